@@ -4,10 +4,13 @@ package pulumiyaml
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/blang/semver"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/pulumi/pulumi/pkg/v3/codegen"
 	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
@@ -62,6 +65,64 @@ func (tc *typeCache) TypeExpr(expr ast.Expr) schema.Type {
 	return tc.exprs[expr]
 }
 
+// An ExprType is the inferred type of the expression found at Range.
+type ExprType struct {
+	Range hcl.Range
+	// Type is the display name of the inferred type, such as "List<string>".
+	Type string
+}
+
+// ExplainTypes returns the inferred type of every expression in a type checked program that has a
+// source location. The result is ordered by position, with enclosing expressions before the
+// expressions they contain, so it is stable across runs.
+func ExplainTypes(t Typing) []ExprType {
+	tc, ok := t.(*typeCache)
+	if !ok {
+		return nil
+	}
+
+	seen := map[ExprType]struct{}{}
+	var types []ExprType
+	for expr, typ := range tc.exprs {
+		if expr == nil || typ == nil || expr.Syntax() == nil || expr.Syntax().Syntax() == nil {
+			continue
+		}
+		rng := expr.Syntax().Syntax().Range()
+		if rng == nil {
+			continue
+		}
+		et := ExprType{Range: *rng, Type: displayType(typ)}
+		if _, ok := seen[et]; ok {
+			continue
+		}
+		seen[et] = struct{}{}
+		types = append(types, et)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		a, b := types[i], types[j]
+		if a.Range.Filename != b.Range.Filename {
+			return a.Range.Filename < b.Range.Filename
+		}
+		if a.Range.Start != b.Range.Start {
+			return posBefore(a.Range.Start, b.Range.Start)
+		}
+		if a.Range.End != b.Range.End {
+			return posBefore(b.Range.End, a.Range.End)
+		}
+		return a.Type < b.Type
+	})
+	return types
+}
+
+// posBefore reports whether a comes before b. Positions from the YAML parser don't carry byte
+// offsets, so they are compared by line and column.
+func posBefore(a, b hcl.Pos) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
+
 type typeCache struct {
 	resources     map[*ast.ResourceDecl]schema.Type
 	configuration map[string]schema.Type
@@ -69,6 +130,8 @@ type typeCache struct {
 	exprs         map[ast.Expr]schema.Type
 	resourceNames map[string]*ast.ResourceDecl
 	variableNames map[string]ast.Expr
+	// The package versions pinned so far, so that conflicting pins can be reported.
+	plugins pluginSet
 }
 
 func (tc *typeCache) registerResource(name string, resource *ast.ResourceDecl, typ schema.Type) {
@@ -269,6 +332,24 @@ func (tc *typeCache) isAssignable(fromExpr ast.Expr, to schema.Type) *notAssigna
 		// Every assignment type must be assignable.
 		return okIf(len(reasons) == 0).Because(reasons...)
 
+	case *schema.EnumType:
+		// An enum can be assigned wherever all of its values can.
+		if to, ok := to.(*schema.EnumType); ok {
+			for _, e := range from.Elements {
+				found := false
+				for _, allowed := range to.Elements {
+					if e.Value == allowed.Value {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return fail.WithReason(": %v is not one of the allowed values", e.Value)
+				}
+			}
+			return isAssignable(from.ElementType, to.ElementType)
+		}
+		return isAssignable(from.ElementType, to)
 	case *schema.TokenType:
 		underlying := schema.AnyType
 		if from.UnderlyingType != nil {
@@ -452,9 +533,7 @@ func (tc *typeCache) isAssignable(fromExpr ast.Expr, to schema.Type) *notAssigna
 // Currently, the only ast.Expr types that support enum member checks are
 // - ast.StringExpr
 // - ast.NumberExpr
-//
-// If a type cast fails, it means that the schema is invalid. In that case an internal
-// error is returned.
+// - ast.BooleanExpr
 func hasValidEnumValue(from ast.Expr, to []*schema.Enum) *notAssignable {
 	var errRange *hcl.Range
 	if node := from.Syntax(); node != nil {
@@ -462,55 +541,31 @@ func hasValidEnumValue(from ast.Expr, to []*schema.Enum) *notAssignable {
 			errRange = syntax.Range()
 		}
 	}
-	convertError := func(expectedType string) *notAssignable {
-		return &notAssignable{
-			internal: true,
-			reason:   fmt.Sprintf("schema enum value was type %T but a %s was expected", to, expectedType),
-			errRange: errRange,
-		}
+
+	var literal interface{}
+	switch from := from.(type) {
+	case *ast.StringExpr:
+		literal = from.GetValue()
+	case *ast.NumberExpr:
+		literal = from.Value
+	case *ast.BooleanExpr:
+		literal = from.Value
+	default:
+		return nil
 	}
 	for _, to := range to {
-		switch from := from.(type) {
-		case *ast.StringExpr:
-			if value, ok := to.Value.(string); ok {
-				if from.GetValue() == value {
-					return nil
-				}
-			} else {
-				return convertError("string")
-			}
-		case *ast.NumberExpr:
-			if to, ok := to.Value.(float64); ok {
-				if from.Value == to {
-					return nil
-				}
-			} else {
-				return convertError("float64")
-			}
-		default:
+		if enumValueEquals(literal, to.Value) {
 			return nil
 		}
 	}
+
 	// We didn't find the value we expected. We should return an error.
 	var valueList []string
 	for _, value := range to {
-
 		// We want to display just the value in 2 conditions:
 		// 1. We have a string based enum, and the name matches the value.
 		// 2. When the name is empty.
-
-		// This cast is safe because we performed the same cast in the above for loop. If
-		// the cast failed then we would have already returned with a
-		// `convertError(string)`.
-		var s string
-		switch v := value.Value.(type) {
-		case string:
-			s = `"` + v + `"`
-		case float64:
-			s = strconv.FormatFloat(v, 'f', -1, 64)
-		default:
-			panic("If we have a non-conformant value we should have panicked in the first for loop.")
-		}
+		s := displayEnumValue(value.Value)
 		// Add the the value to the list of possible values
 		if value.Name == "" || value.Name == s {
 			valueList = append(valueList, s)
@@ -526,6 +581,29 @@ func hasValidEnumValue(from ast.Expr, to []*schema.Enum) *notAssignable {
 	}
 }
 
+// enumValueEquals compares a literal from the program with an enum value from a schema. Integer enums hold their
+// values as integers, so numbers are compared by value rather than by type.
+func enumValueEquals(literal, value interface{}) bool {
+	switch v := value.(type) {
+	case int32:
+		value = float64(v)
+	case int:
+		value = float64(v)
+	}
+	return literal == value
+}
+
+func displayEnumValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return `"` + v + `"`
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 // Provides an appropriate diagnostic message if it is illegal to assign `from`
 // to `to`.
 func (tc *typeCache) assertTypeAssignable(ctx *evalContext, from ast.Expr, to schema.Type) {
@@ -556,9 +634,35 @@ func (tc *typeCache) assertTypeAssignable(ctx *evalContext, from ast.Expr, to sc
 	if s := result.Summary(); s != "" {
 		summary = s
 	}
+	if _, ok := from.(*ast.SelectExpr); ok {
+		// The generic message doesn't make clear that the union comes from the list's elements.
+		if union, ok := codegen.UnwrapType(typ).(*schema.UnionType); ok {
+			elements := make([]string, len(union.ElementTypes))
+			for i, t := range union.ElementTypes {
+				elements[i] = displayType(t)
+			}
+			summary = fmt.Sprintf("The element selected by fn::select could be any of %s, and not all of them are assignable to %s",
+				strings.Join(elements, ", "), displayType(to))
+		}
+	}
 	ctx.addErrDiag(rng, summary, result.String())
 }
 
+// suggestResourceType returns the spelling of a resource type defined by the package of typeName that is closest to
+// typeName, if there is one close enough to be a likely typo.
+func suggestResourceType(loader PackageLoader, typeName string, version *semver.Version) (string, bool) {
+	pkg, err := loadPackage(loader, typeName, version)
+	if err != nil {
+		return "", false
+	}
+	var spellings []string
+	for _, token := range pkg.ListResources() {
+		spellings = append(spellings, tokenSpellings(token)...)
+	}
+	suggestion, ok := yamldiags.ClosestMatch(spellings, typeName, 3)
+	return suggestion, ok && suggestion != typeName
+}
+
 func (tc *typeCache) typeResource(r *Runner, node resourceNode) bool {
 	k, v := node.Key.Value, node.Value
 	ctx := r.newContext(node)
@@ -569,9 +673,17 @@ func (tc *typeCache) typeResource(r *Runner, node resourceNode) bool {
 	}
 	pkg, typ, err := ResolveResource(ctx.pkgLoader, v.Type.Value, version)
 	if err != nil {
-		ctx.error(v.Type, fmt.Sprintf("error resolving type of resource %v: %v", k, err))
+		summary := fmt.Sprintf("error resolving type of resource %v: %v", k, err)
+		if suggestion, ok := suggestResourceType(ctx.pkgLoader, v.Type.Value, version); ok {
+			ctx.addErrDiag(v.Type.Syntax().Syntax().Range(), summary, fmt.Sprintf("Did you mean %q?", suggestion))
+		} else {
+			ctx.error(v.Type, summary)
+		}
 		return true
 	}
+	tc.plugins.accept(v.Type.Value, v.Options.Version, v.Options.PluginDownloadURL, func(expr ast.Expr, summary string) {
+		ctx.error(expr, summary)
+	})
 	hint := pkg.ResourceTypeHint(typ)
 	var allProperties []string
 	for _, prop := range hint.Resource.InputProperties {
@@ -596,11 +708,26 @@ func (tc *typeCache) typeResource(r *Runner, node resourceNode) bool {
 		)
 	}
 
+	if resourceIsGet {
+		if isComponent, err := pkg.IsComponent(typ); err == nil && isComponent {
+			subject := node.Key.Syntax().Syntax().Range()
+			if s := v.Get.Syntax(); s != nil {
+				subject = s.Syntax().Range()
+			}
+			ctx.addWarnDiag(subject,
+				fmt.Sprintf("Resource %s is a component and cannot be read with get", typ),
+				"Components are not managed by a provider, so there is no existing state to read.",
+			)
+		}
+	}
+
 	// We type check properties if
 	// 1. They exist, or
 	// 2. The resource doesn't have a `Get` field (catching missing properties)
 	if resourceHasProperties || !resourceIsGet {
-		tc.typePropertyEntries(ctx, k, typ.String(), fmtr, v.Properties.Entries, hint.Resource.InputProperties)
+		entries := tc.checkReadOnlyProperties(ctx, v.Properties.Entries, hint.Resource)
+		inputs := tc.checkRequiredProperties(ctx, node, v.Properties.Entries, hint.Resource)
+		tc.typePropertyEntries(ctx, k, typ.String(), fmtr, entries, inputs)
 	}
 
 	tc.registerResource(k, node.Value, hint)
@@ -676,6 +803,31 @@ func (tc *typeCache) typeResource(r *Runner, node resourceNode) bool {
 		}
 	}
 
+	for _, opt := range []ast.Expr{v.Options.DeleteBeforeReplace, v.Options.Protect, v.Options.RetainOnDelete} {
+		if opt != nil {
+			tc.assertTypeAssignable(ctx, opt, schema.BoolType)
+		}
+	}
+
+	if u := v.Options.PluginDownloadURL; u != nil && u.Value != "" {
+		if parsed, err := url.Parse(u.Value); err != nil || parsed.Scheme == "" {
+			ctx.error(u, fmt.Sprintf("pluginDownloadURL must be a URL with a scheme, such as https://, not %q", u.Value))
+		}
+	}
+	if v.Options.DependsOn != nil {
+		tc.assertResources(ctx, "dependsOn", v.Options.DependsOn)
+	}
+
+	tc.checkPropertyPathOptions(ctx, "ignoreChanges", v.Options.IgnoreChanges, hint.Resource)
+	tc.checkPropertyPathOptions(ctx, "replaceOnChanges", v.Options.ReplaceOnChanges, hint.Resource)
+	if v.Options.Providers != nil {
+		// A component passes its providers on to the resources it creates, which can be of any package.
+		if isComponent, err := pkg.IsComponent(typ); err == nil && !isComponent {
+			tc.checkProviders(ctx, r.t, k, ResolvePkgName(typ.String()), v.Options.Providers)
+		}
+	}
+	tc.checkVolatileReplacements(ctx, k, v.Properties.Entries, hint.Resource)
+
 	if s := v.Options.Syntax(); s != nil {
 		if o, ok := s.(*syntax.ObjectNode); ok {
 			fmtr := yamldiags.NonExistentFieldFormatter{
@@ -705,6 +857,301 @@ func (tc *typeCache) typeResource(r *Runner, node resourceNode) bool {
 	return true
 }
 
+// checkVolatileReplacements warns about inputs that replace their resource when they change, but are bound to a
+// value that changes from one deployment to the next, since the resource would then be replaced on every deployment.
+func (tc *typeCache) checkVolatileReplacements(ctx *evalContext, name string, entries []ast.PropertyMapEntry, res *schema.Resource) {
+	replaces := map[string]struct{}{}
+	for _, prop := range res.InputProperties {
+		if prop.ReplaceOnChanges || prop.WillReplaceOnChanges {
+			replaces[prop.Name] = struct{}{}
+		}
+	}
+	if len(replaces) == 0 {
+		return
+	}
+	for _, entry := range entries {
+		if _, ok := replaces[entry.Key.Value]; !ok {
+			continue
+		}
+		source, ok := volatileSource(entry.Value)
+		if !ok {
+			continue
+		}
+		ctx.addWarnDiag(entry.Value.Syntax().Syntax().Range(),
+			fmt.Sprintf("Resource %v may be replaced on every deployment", name),
+			fmt.Sprintf("Changing %v replaces %v, and it is bound to %v, which can change between deployments",
+				entry.Key.Value, name, source))
+	}
+}
+
+// volatileSource returns a description of the first value referenced by x that can change from one deployment of a
+// stack to the next, if any.
+func volatileSource(x ast.Expr) (string, bool) {
+	isVolatile := func(access *ast.PropertyAccess) bool {
+		if access == nil || len(access.Accessors) < 2 || access.RootName() != PulumiVarName {
+			return false
+		}
+		name, ok := access.Accessors[1].(*ast.PropertyName)
+		return ok && name.Name == "stack"
+	}
+
+	switch x := x.(type) {
+	case *ast.SymbolExpr:
+		if isVolatile(x.Property) {
+			return "${" + x.Property.String() + "}", true
+		}
+	case *ast.InterpolateExpr:
+		for _, part := range x.Parts {
+			if isVolatile(part.Value) {
+				return "${" + part.Value.String() + "}", true
+			}
+		}
+	case *ast.ListExpr:
+		for _, elem := range x.Elements {
+			if source, ok := volatileSource(elem); ok {
+				return source, true
+			}
+		}
+	case *ast.ObjectExpr:
+		for _, entry := range x.Entries {
+			if source, ok := volatileSource(entry.Value); ok {
+				return source, true
+			}
+		}
+	case *ast.GetEnvExpr:
+		if name, ok := x.Variable.(*ast.StringExpr); ok {
+			return fmt.Sprintf("the environment variable %v", name.Value), true
+		}
+		return "an environment variable", true
+	case *ast.UUIDExpr:
+		return "fn::uuid", true
+	case *ast.TimestampExpr:
+		return "fn::timestamp", true
+	case ast.BuiltinExpr:
+		return volatileSource(x.Args())
+	}
+	return "", false
+}
+
+// assertResources reports an error unless the value of a resource option is a resource or a list of resources. The
+// elements of a literal list are checked individually, so that the error points at the offending element. A bare
+// reference to a variable or config value that isn't a resource is reported as a resource that could not be found.
+func (tc *typeCache) assertResources(ctx *evalContext, option string, expr ast.Expr) {
+	var isResource func(typ schema.Type) bool
+	isResource = func(typ schema.Type) bool {
+		switch typ := codegen.UnwrapType(typ).(type) {
+		case *schema.ResourceType:
+			return true
+		case *schema.UnionType:
+			for _, t := range typ.ElementTypes {
+				if !isResource(t) {
+					return false
+				}
+			}
+			return true
+		default:
+			// The type of a value that isn't known until the program runs can't be checked.
+			return typ == schema.AnyType
+		}
+	}
+	check := func(expr ast.Expr, typ schema.Type, allowList bool) {
+		if typ == nil || isResource(typ) {
+			return
+		}
+		if arr, ok := codegen.UnwrapType(typ).(*schema.ArrayType); ok && allowList && isResource(arr.ElementType) {
+			return
+		}
+		if _, ok := typ.(*schema.InvalidType); ok {
+			return
+		}
+		if sym, ok := expr.(*ast.SymbolExpr); ok && len(sym.Property.Accessors) == 1 {
+			if name := sym.Property.RootName(); tc.resourceNames[name] == nil {
+				kind := "variable"
+				if _, ok := tc.configuration[name]; ok {
+					kind = "config value"
+				}
+				ctx.addErrDiag(expr.Syntax().Syntax().Range(), fmt.Sprintf("resource %q could not be found", name),
+					fmt.Sprintf("%s refers to the %s %q, which is not a resource", option, kind, name))
+				return
+			}
+		}
+		ctx.error(expr, fmt.Sprintf("%s must be a resource or a list of resources, not %s", option, displayType(typ)))
+	}
+
+	if list, ok := expr.(*ast.ListExpr); ok {
+		for _, elem := range list.Elements {
+			check(elem, tc.exprs[elem], false)
+		}
+		return
+	}
+	check(expr, tc.exprs[expr], true)
+}
+
+// checkProviders warns about the providers listed in a custom resource's providers option whose package is not used
+// by the resource or any of its children, since they will never be used.
+func (tc *typeCache) checkProviders(ctx *evalContext, t *ast.TemplateDecl, name, pkgName string, providers ast.Expr) {
+	list, ok := providers.(*ast.ListExpr)
+	if !ok {
+		return
+	}
+
+	types := map[string]string{}
+	children := map[string][]string{}
+	for _, entry := range t.Resources.Entries {
+		if entry.Value == nil || entry.Value.Type == nil {
+			continue
+		}
+		types[entry.Key.Value] = entry.Value.Type.Value
+		if parent, ok := entry.Value.Options.Parent.(*ast.SymbolExpr); ok {
+			root := parent.Property.RootName()
+			children[root] = append(children[root], entry.Key.Value)
+		}
+	}
+
+	used := map[string]struct{}{pkgName: {}}
+	visited := map[string]struct{}{name: {}}
+	queue := children[name]
+	for len(queue) > 0 {
+		child := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[child]; ok {
+			continue
+		}
+		visited[child] = struct{}{}
+		used[ResolvePkgName(types[child])] = struct{}{}
+		queue = append(queue, children[child]...)
+	}
+
+	for _, elem := range list.Elements {
+		symbol, ok := elem.(*ast.SymbolExpr)
+		if !ok || len(symbol.Property.Accessors) != 1 {
+			continue
+		}
+		provider := symbol.Property.RootName()
+		typ, ok := types[provider]
+		if !ok || !strings.HasPrefix(typ, "pulumi:providers:") {
+			continue
+		}
+		if _, ok := used[ResolvePkgName(typ)]; ok {
+			continue
+		}
+		ctx.addWarnDiag(elem.Syntax().Syntax().Range(),
+			fmt.Sprintf("Provider %v will never be used by resource %v", provider, name),
+			fmt.Sprintf("%v is a provider for the %v package, which is not used by %v or any of its children",
+				provider, ResolvePkgName(typ), name))
+	}
+}
+
+// checkPropertyPathOptions warns about the paths in a resource option such as ignoreChanges whose root property is
+// not an input of res, since the option has no effect on them. Paths containing a wildcard are not checked.
+func (tc *typeCache) checkPropertyPathOptions(ctx *evalContext, option string, paths *ast.StringListDecl, res *schema.Resource) {
+	if paths == nil {
+		return
+	}
+	inputs := map[string]struct{}{}
+	for _, prop := range res.InputProperties {
+		inputs[prop.Name] = struct{}{}
+	}
+	outputs := map[string]struct{}{}
+	for _, prop := range res.Properties {
+		outputs[prop.Name] = struct{}{}
+	}
+
+	for _, path := range paths.Elements {
+		if path == nil || strings.Contains(path.Value, "*") {
+			continue
+		}
+		root := path.Value
+		if i := strings.IndexAny(root, ".["); i != -1 {
+			root = root[:i]
+		}
+		if _, ok := inputs[root]; ok {
+			continue
+		}
+		if _, ok := outputs[root]; ok {
+			ctx.addWarnDiag(path.Syntax().Syntax().Range(),
+				fmt.Sprintf("%s references %s, which is an output-only property of %s", option, root, res.Token),
+				fmt.Sprintf("%s only applies to input properties, so this entry has no effect", option))
+			continue
+		}
+		ctx.addWarnDiag(path.Syntax().Syntax().Range(),
+			fmt.Sprintf("%s references %s, which is not a property of %s", option, root, res.Token),
+			"")
+	}
+}
+
+// checkReadOnlyProperties reports entries that set output-only properties of res, returning the remaining entries.
+func (tc *typeCache) checkReadOnlyProperties(ctx *evalContext, entries []ast.PropertyMapEntry, res *schema.Resource) []ast.PropertyMapEntry {
+	inputs := map[string]struct{}{}
+	for _, prop := range res.InputProperties {
+		inputs[prop.Name] = struct{}{}
+	}
+	outputs := map[string]struct{}{}
+	for _, prop := range res.Properties {
+		outputs[prop.Name] = struct{}{}
+	}
+
+	filtered := make([]ast.PropertyMapEntry, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Key.GetValue()
+		_, isInput := inputs[name]
+		_, isOutput := outputs[name]
+		if !isInput && isOutput {
+			ctx.addErrDiag(entry.Key.Syntax().Syntax().Range(),
+				fmt.Sprintf("Property %s is read-only and cannot be set as an input", name),
+				fmt.Sprintf("%s is an output of %s", name, res.Token))
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// checkRequiredProperties reports each required input of res that is not set by entries, returning the inputs of res
+// with the missing ones made optional so that they aren't reported again when the entries are type checked.
+func (tc *typeCache) checkRequiredProperties(ctx *evalContext, node resourceNode, entries []ast.PropertyMapEntry, res *schema.Resource) []*schema.Property {
+	// Properties given as a single expression rather than a literal map can't be checked until they are evaluated.
+	if s := node.Value.Properties.Syntax(); s != nil {
+		if _, ok := s.(*syntax.ObjectNode); !ok {
+			return res.InputProperties
+		}
+	}
+
+	set := map[string]struct{}{}
+	for _, entry := range entries {
+		set[entry.Key.GetValue()] = struct{}{}
+	}
+
+	var missing []string
+	inputs := make([]*schema.Property, 0, len(res.InputProperties))
+	for _, prop := range res.InputProperties {
+		_, ok := set[prop.Name]
+		// Constant properties are filled in when the resource is registered.
+		if ok || !prop.IsRequired() || prop.ConstValue != nil {
+			inputs = append(inputs, prop)
+			continue
+		}
+		missing = append(missing, prop.Name)
+		optional := *prop
+		optional.Type = &schema.OptionalType{ElementType: prop.Type}
+		inputs = append(inputs, &optional)
+	}
+
+	subject := node.Key.Syntax().Syntax().Range()
+	if len(entries) == 0 && len(missing) > 0 {
+		// The properties may have been left out by mistake, so point out everything that's required in one place.
+		ctx.addWarnDiag(subject,
+			fmt.Sprintf("Resource %s was declared without any properties", node.Key.Value),
+			fmt.Sprintf("%s requires %s", res.Token, strings.Join(missing, ", ")))
+	}
+	for _, name := range missing {
+		ctx.addErrDiag(subject,
+			fmt.Sprintf("Resource %s is missing required property %s", node.Key.Value, name),
+			fmt.Sprintf("%s is a required input of %s", name, res.Token))
+	}
+	return inputs
+}
+
 func (tc *typeCache) typePropertyEntries(ctx *evalContext, resourceName, resourceType string, fmtr yamldiags.NonExistentFieldFormatter, entries []ast.PropertyMapEntry, props []*schema.Property) {
 	to := &schema.ObjectType{
 		Token:      resourceType,
@@ -738,6 +1185,134 @@ func (tc *typeCache) typePropertyEntries(ctx *evalContext, resourceName, resourc
 	tc.assertTypeAssignable(ctx, from, to)
 }
 
+// typeCidr checks the arguments of fn::cidrSubnet and fn::cidrHost: a CIDR prefix followed by integers. A literal
+// prefix is parsed, so that a malformed one is reported before the program runs.
+func (tc *typeCache) typeCidr(ctx *evalContext, prefix ast.Expr, numbers ...ast.Expr) {
+	tc.assertTypeAssignable(ctx, prefix, schema.StringType)
+	if str, ok := prefix.(*ast.StringExpr); ok {
+		if _, _, _, err := parseCIDRPrefix(str.Value); err != nil {
+			ctx.error(prefix, err.Error())
+		}
+	}
+	for _, n := range numbers {
+		tc.assertTypeAssignable(ctx, n, schema.IntType)
+	}
+}
+
+// typeRange checks the arguments of fn::range. When they are all literals, the range is computed, so that a bad step
+// is reported before the program runs.
+func (tc *typeCache) typeRange(ctx *evalContext, t *ast.RangeExpr) {
+	exprs := []ast.Expr{t.Start, t.End}
+	if t.Step != nil {
+		exprs = append(exprs, t.Step)
+	}
+	numbers := make([]float64, 0, len(exprs))
+	for _, expr := range exprs {
+		tc.assertTypeAssignable(ctx, expr, schema.NumberType)
+		if n, ok := expr.(*ast.NumberExpr); ok {
+			numbers = append(numbers, n.Value)
+		}
+	}
+	if len(numbers) == len(exprs) {
+		var step *float64
+		if len(numbers) == 3 {
+			step = &numbers[2]
+		}
+		if _, err := numberRange(numbers[0], numbers[1], step); err != nil {
+			ctx.error(t, err.Error())
+		}
+	}
+	tc.exprs[t] = &schema.ArrayType{ElementType: schema.NumberType}
+}
+
+// typeSort checks that fn::sort is given a list of strings or a list of numbers.
+func (tc *typeCache) typeSort(ctx *evalContext, t *ast.SortExpr) {
+	tc.assertTypeAssignable(ctx, t.Values, &schema.ArrayType{ElementType: schema.AnyType})
+	if t.Descending != nil {
+		tc.assertTypeAssignable(ctx, t.Descending, schema.BoolType)
+	}
+
+	arr, ok := codegen.UnwrapType(tc.exprs[t.Values]).(*schema.ArrayType)
+	if !ok {
+		tc.exprs[t] = &schema.ArrayType{ElementType: schema.AnyType}
+		return
+	}
+	tc.exprs[t] = arr
+	switch elem := codegen.UnwrapType(arr.ElementType); elem {
+	case schema.StringType, schema.NumberType, schema.IntType, schema.AnyType:
+	default:
+		if _, ok := elem.(*schema.InvalidType); !ok && !isNumericUnion(elem) {
+			ctx.errorf(t.Values, "fn::sort can only sort lists of strings or of numbers, not %s", displayType(arr))
+		}
+	}
+}
+
+// isNumericUnion reports whether typ is a union of numbers and integers, such as the elements of a list that mixes
+// number literals with Integer config values.
+func isNumericUnion(typ schema.Type) bool {
+	union, ok := typ.(*schema.UnionType)
+	if !ok {
+		return false
+	}
+	for _, elem := range union.ElementTypes {
+		if elem := codegen.UnwrapType(elem); elem != schema.NumberType && elem != schema.IntType {
+			return false
+		}
+	}
+	return true
+}
+
+// typeAssetArchive checks that the entries of an fn::assetArchive that reference other values refer to assets or
+// archives.
+func (tc *typeCache) typeAssetArchive(ctx *evalContext, t *ast.AssetArchiveExpr) {
+	tc.exprs[t] = schema.ArchiveType
+
+	keys := make([]string, 0, len(t.AssetOrArchives))
+	for k := range t.AssetOrArchives {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		ref, ok := t.AssetOrArchives[k].(*ast.SymbolExpr)
+		if !ok {
+			continue
+		}
+		typ := codegen.UnwrapType(tc.exprs[ref])
+		switch typ.(type) {
+		case nil, *schema.InvalidType:
+			continue
+		}
+		switch typ {
+		case schema.ArchiveType, schema.AssetType, schema.AnyType:
+		default:
+			ctx.errorf(ref, errAssetArchiveReference, k, typeKind(typ))
+		}
+	}
+}
+
+// typeKind describes a type the way typeString describes a value of that type, so that the type checker and the
+// evaluator can report the same error.
+func typeKind(typ schema.Type) string {
+	switch typ := codegen.UnwrapType(typ); typ {
+	case schema.StringType:
+		return "a string"
+	case schema.NumberType:
+		return "a number"
+	case schema.IntType:
+		return "an integer"
+	case schema.BoolType:
+		return "a boolean"
+	default:
+		switch typ.(type) {
+		case *schema.ArrayType:
+			return "a list"
+		case *schema.MapType, *schema.ObjectType:
+			return "an object"
+		}
+		return displayType(typ)
+	}
+}
+
 func (tc *typeCache) typeInvoke(ctx *evalContext, t *ast.InvokeExpr) bool {
 	version, err := ParseVersion(t.CallOpts.Version)
 	if err != nil {
@@ -763,7 +1338,23 @@ func (tc *typeCache) typeInvoke(ctx *evalContext, t *ast.InvokeExpr) bool {
 		Fields:      existing,
 		MaxElements: 5,
 	}
-	if t.CallArgs != nil {
+	if t.PositionalArgs != nil {
+		args, diags := InvokeArguments(t, hint)
+		ctx.sdiags.Extend(diags...)
+		ctx.Runner.sdiags.Extend(diags...)
+		if args != nil {
+			for _, prop := range args.Entries {
+				tc.assertTypeAssignable(ctx, prop.Value, inputs[prop.Key.(*ast.StringExpr).Value])
+			}
+		}
+	} else if t.ArgumentsExpr != nil {
+		// The fields of a referenced object aren't known until it resolves, so only check that it is an object.
+		typ := codegen.UnwrapType(tc.exprs[t.ArgumentsExpr])
+		if _, isList := typ.(*schema.ArrayType); isList ||
+			typ == schema.StringType || typ == schema.NumberType || typ == schema.IntType || typ == schema.BoolType {
+			ctx.error(t.ArgumentsExpr, fmt.Sprintf("function arguments ('arguments') must be an object, not %s", displayType(typ)))
+		}
+	} else if t.CallArgs != nil {
 		for _, prop := range t.CallArgs.Entries {
 			k := prop.Key.(*ast.StringExpr).Value
 			if typ, ok := inputs[k]; !ok {
@@ -787,7 +1378,10 @@ func (tc *typeCache) typeInvoke(ctx *evalContext, t *ast.InvokeExpr) bool {
 	if t.CallOpts.PluginDownloadURL != nil {
 		tc.typeExpr(ctx, t.CallOpts.PluginDownloadURL)
 	}
-	if t.Return != nil {
+	if t.Collect != nil {
+		tc.typeInvokeCollect(ctx, t, hint, inputs)
+	} else if t.Return != nil {
+		name, accessors := t.ReturnPath()
 		fields := []string{}
 		var (
 			returnType  schema.Type
@@ -796,7 +1390,7 @@ func (tc *typeCache) typeInvoke(ctx *evalContext, t *ast.InvokeExpr) bool {
 		if o := hint.Outputs; o != nil {
 			for _, output := range o.Properties {
 				fields = append(fields, output.Name)
-				if strings.EqualFold(t.Return.Value, output.Name) {
+				if strings.EqualFold(name, output.Name) {
 					returnType = output.Type
 					validReturn = true
 				}
@@ -809,10 +1403,27 @@ func (tc *typeCache) typeInvoke(ctx *evalContext, t *ast.InvokeExpr) bool {
 			FieldsAreProperties: true,
 		}
 		if hint.Outputs == nil || !validReturn {
-			summary, detail := fmtr.MessageWithDetail(t.Return.Value, t.Return.Value)
-			ctx.addErrDiag(t.Return.Syntax().Syntax().Range(), summary, detail)
+			summary, detail := fmtr.MessageWithDetail(name, name)
+			ctx.addErrDiag(t.ReturnPathRange(0), summary, detail)
 		} else {
-			tc.exprs[t] = returnType
+			// A nested return path is typed like a property access into the returned output. Errors are reported at
+			// the first segment of the path that can't be accessed.
+			failed := len(accessors)
+			for i := 1; i < len(accessors); i++ {
+				typ := typePropertyAccess(ctx, returnType, name, accessors[:i], func(string, string) *schema.InvalidType {
+					return &schema.InvalidType{}
+				})
+				if _, ok := typ.(*schema.InvalidType); ok {
+					failed = i
+					break
+				}
+			}
+			rng := t.ReturnPathRange(failed)
+			tc.exprs[t] = typePropertyAccess(ctx, returnType, name, accessors, func(summary, detail string) *schema.InvalidType {
+				diag := syntax.Error(rng, summary, detail)
+				ctx.addErrDiag(rng, summary, detail)
+				return &schema.InvalidType{Diagnostics: []*hcl.Diagnostic{diag.HCL()}}
+			})
 		}
 	} else {
 		tc.exprs[t] = hint.Outputs
@@ -820,6 +1431,52 @@ func (tc *typeCache) typeInvoke(ctx *evalContext, t *ast.InvokeExpr) bool {
 	return true
 }
 
+// typeInvokeCollect types a collecting invoke as a list of the items returned by each call.
+func (tc *typeCache) typeInvokeCollect(ctx *evalContext, t *ast.InvokeExpr, hint *schema.Function, inputs map[string]schema.Type) {
+	outputs := map[string]schema.Type{}
+	var fields []string
+	if hint.Outputs != nil {
+		for _, output := range hint.Outputs.Properties {
+			fields = append(fields, output.Name)
+			outputs[output.Name] = output.Type
+		}
+	}
+	fmtr := yamldiags.NonExistentFieldFormatter{
+		ParentLabel:         t.Token.Value,
+		Fields:              fields,
+		MaxElements:         5,
+		FieldsAreProperties: true,
+	}
+
+	ok := true
+	for _, field := range []*ast.StringExpr{t.Collect.Items, t.Collect.Token} {
+		if _, has := outputs[field.Value]; !has {
+			summary, detail := fmtr.MessageWithDetail(field.Value, field.Value)
+			ctx.addErrDiag(field.Syntax().Syntax().Range(), summary, detail)
+			ok = false
+		}
+	}
+	if !ok {
+		return
+	}
+	if _, has := inputs[t.Collect.ArgumentName()]; !has && hint.Inputs != nil {
+		subject := t.Collect.Token
+		if t.Collect.Argument != nil {
+			subject = t.Collect.Argument
+		}
+		ctx.addWarnDiag(subject.Syntax().Syntax().Range(),
+			fmt.Sprintf("%s does not take an argument named '%s'", t.Token.Value, t.Collect.ArgumentName()),
+			"The continuation token is passed back to the function as this argument; "+
+				"use 'argument' to name a different one")
+	}
+
+	items := outputs[t.Collect.Items.Value]
+	if arr, ok := codegen.UnwrapType(items).(*schema.ArrayType); ok {
+		items = arr.ElementType
+	}
+	tc.exprs[t] = &schema.ArrayType{ElementType: items}
+}
+
 func (tc *typeCache) typeSymbol(ctx *evalContext, t *ast.SymbolExpr) bool {
 	var typ schema.Type = &schema.InvalidType{}
 	if root, ok := tc.resourceNames[t.Property.RootName()]; ok {
@@ -886,6 +1543,8 @@ func typePropertyAccess(ctx *evalContext, root schema.Type,
 	switch accessor := accessors[0].(type) {
 	case *ast.PropertyName:
 		properties := map[string]schema.Type{}
+		// Input properties of a resource that are not also outputs.
+		inputOnly := map[string]struct{}{}
 		switch root := codegen.UnwrapType(root).(type) {
 		case *schema.ObjectType:
 			for _, prop := range root.Properties {
@@ -899,6 +1558,11 @@ func typePropertyAccess(ctx *evalContext, root schema.Type,
 				properties["id"] = schema.StringType
 			}
 			properties["urn"] = schema.StringType
+			for _, prop := range root.Resource.InputProperties {
+				if _, ok := properties[prop.Name]; !ok {
+					inputOnly[prop.Name] = struct{}{}
+				}
+			}
 		case *schema.InvalidType:
 			return root
 		default:
@@ -922,6 +1586,9 @@ func typePropertyAccess(ctx *evalContext, root schema.Type,
 				FieldsAreProperties: true,
 			}
 			summary, detail := fmtr.MessageWithDetail(accessor.Name, accessor.Name)
+			if _, ok := inputOnly[accessor.Name]; ok {
+				summary = fmt.Sprintf("%s is an input of %s and is not available as an output", accessor.Name, runningName)
+			}
 			return setError(summary, detail)
 		}
 		return typePropertyAccess(ctx, newType, runningName+"."+accessor.Name, accessors[1:], setError)
@@ -958,6 +1625,76 @@ func typePropertyAccess(ctx *evalContext, root schema.Type,
 	}
 }
 
+// defaultsType computes the type of fn::defaults. When both arguments are objects, the result has the properties of
+// both, with the type of target's properties taking precedence.
+func defaultsType(target, defaults schema.Type) schema.Type {
+	targetObj, ok := codegen.UnwrapType(target).(*schema.ObjectType)
+	if !ok {
+		return &schema.MapType{ElementType: schema.AnyType}
+	}
+	defaultsObj, ok := codegen.UnwrapType(defaults).(*schema.ObjectType)
+	if !ok {
+		return &schema.MapType{ElementType: schema.AnyType}
+	}
+
+	properties := make([]*schema.Property, 0, len(targetObj.Properties)+len(defaultsObj.Properties))
+	propNames := make([]string, 0, cap(properties))
+	seen := map[string]struct{}{}
+	for _, props := range [][]*schema.Property{targetObj.Properties, defaultsObj.Properties} {
+		for _, prop := range props {
+			if _, ok := seen[prop.Name]; ok {
+				continue
+			}
+			seen[prop.Name] = struct{}{}
+			properties = append(properties, prop)
+			propNames = append(propNames, prop.Name)
+		}
+	}
+	return &schema.ObjectType{
+		Token:      adhockObjectToken + strings.Join(propNames, "•"),
+		Properties: properties,
+	}
+}
+
+// mergeType computes the type of fn::merge. When every argument is an object, the result has the properties of all
+// of them. The type of a property comes from the last object that declares it, except that properties that are
+// objects in several arguments are merged in turn.
+func mergeType(types []schema.Type) schema.Type {
+	var properties []*schema.Property
+	index := map[string]int{}
+	for _, typ := range types {
+		obj, ok := codegen.UnwrapType(typ).(*schema.ObjectType)
+		if !ok {
+			return &schema.MapType{ElementType: schema.AnyType}
+		}
+		for _, prop := range obj.Properties {
+			i, seen := index[prop.Name]
+			if !seen {
+				index[prop.Name] = len(properties)
+				properties = append(properties, prop)
+				continue
+			}
+			prev := properties[i]
+			_, prevObj := codegen.UnwrapType(prev.Type).(*schema.ObjectType)
+			_, propObj := codegen.UnwrapType(prop.Type).(*schema.ObjectType)
+			if prevObj && propObj {
+				properties[i] = &schema.Property{Name: prop.Name, Type: mergeType([]schema.Type{prev.Type, prop.Type})}
+			} else {
+				properties[i] = prop
+			}
+		}
+	}
+
+	propNames := make([]string, len(properties))
+	for i, prop := range properties {
+		propNames[i] = prop.Name
+	}
+	return &schema.ObjectType{
+		Token:      adhockObjectToken + strings.Join(propNames, "•"),
+		Properties: properties,
+	}
+}
+
 func (tc *typeCache) typeExpr(ctx *evalContext, t ast.Expr) bool {
 	switch t := t.(type) {
 	case *ast.InvokeExpr:
@@ -970,7 +1707,9 @@ func (tc *typeCache) typeExpr(ctx *evalContext, t ast.Expr) bool {
 		tc.exprs[t] = schema.NumberType
 	case *ast.BooleanExpr:
 		tc.exprs[t] = schema.BoolType
-	case *ast.AssetArchiveExpr, *ast.FileArchiveExpr, *ast.RemoteArchiveExpr:
+	case *ast.AssetArchiveExpr:
+		tc.typeAssetArchive(ctx, t)
+	case *ast.FileArchiveExpr, *ast.RemoteArchiveExpr:
 		tc.exprs[t] = schema.ArchiveType
 	case *ast.FileAssetExpr, *ast.RemoteAssetExpr, *ast.StringAssetExpr:
 		tc.exprs[t] = schema.AssetType
@@ -979,6 +1718,216 @@ func (tc *typeCache) typeExpr(ctx *evalContext, t ast.Expr) bool {
 		tc.exprs[t] = schema.StringType
 	case *ast.ToJSONExpr:
 		tc.exprs[t] = schema.StringType
+	case *ast.ToNumberExpr:
+		if str, ok := t.Value.(*ast.StringExpr); ok {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(str.Value), 64); err != nil {
+				ctx.error(t.Value, fmt.Sprintf("fn::toNumber could not parse %q as a number", str.Value))
+			}
+		}
+		tc.exprs[t] = schema.NumberType
+	case *ast.ToStringExpr:
+		switch codegen.UnwrapType(tc.exprs[t.Value]).(type) {
+		case *schema.ArrayType, *schema.MapType, *schema.ObjectType:
+			ctx.error(t.Value, fmt.Sprintf("fn::toString cannot convert %v to a string; use fn::toJSON instead",
+				displayType(tc.exprs[t.Value])))
+		}
+		tc.exprs[t] = schema.StringType
+	case *ast.ToYAMLExpr:
+		tc.exprs[t] = schema.StringType
+	case *ast.FromJSONExpr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.StringType)
+		// The shape of the decoded value isn't known until the program runs.
+		tc.exprs[t] = schema.AnyType
+	case *ast.BasenameExpr:
+		tc.assertTypeAssignable(ctx, t.Path, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.DirnameExpr:
+		tc.assertTypeAssignable(ctx, t.Path, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.FileExtensionExpr:
+		tc.assertTypeAssignable(ctx, t.Path, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.DefaultsExpr:
+		anyMap := &schema.MapType{ElementType: schema.AnyType}
+		tc.assertTypeAssignable(ctx, t.Target, anyMap)
+		tc.assertTypeAssignable(ctx, t.Defaults, anyMap)
+		tc.exprs[t] = defaultsType(tc.exprs[t.Target], tc.exprs[t.Defaults])
+	case *ast.CoalesceListExpr:
+		var types OrderedTypeSet
+		for _, elem := range t.Values.Elements {
+			tc.assertTypeAssignable(ctx, elem, &schema.ArrayType{ElementType: schema.AnyType})
+			if arr, ok := codegen.UnwrapType(tc.exprs[elem]).(*schema.ArrayType); ok {
+				if _, invalid := arr.ElementType.(*schema.InvalidType); !invalid {
+					types.Add(arr.ElementType)
+				}
+			}
+		}
+		var elementType schema.Type
+		switch types.Len() {
+		case 0:
+			elementType = schema.AnyType
+		case 1:
+			elementType = types.First()
+		default:
+			elementType = &schema.UnionType{ElementTypes: types.Values()}
+		}
+		tc.exprs[t] = &schema.ArrayType{ElementType: elementType}
+	case *ast.CoalesceEmptyStringExpr:
+		for _, elem := range t.Values.Elements {
+			tc.assertTypeAssignable(ctx, elem, &schema.OptionalType{ElementType: schema.StringType})
+		}
+		tc.exprs[t] = schema.StringType
+	case *ast.CidrSubnetExpr:
+		tc.typeCidr(ctx, t.Prefix, t.Newbits, t.Netnum)
+		tc.exprs[t] = schema.StringType
+	case *ast.CidrHostExpr:
+		tc.typeCidr(ctx, t.Prefix, t.Hostnum)
+		tc.exprs[t] = schema.StringType
+	case *ast.RangeExpr:
+		tc.typeRange(ctx, t)
+	case *ast.ReplaceExpr:
+		tc.assertTypeAssignable(ctx, t.Source, schema.StringType)
+		tc.assertTypeAssignable(ctx, t.Old, schema.StringType)
+		tc.assertTypeAssignable(ctx, t.New, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.TrimExpr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.TrimPrefixExpr:
+		tc.assertTypeAssignable(ctx, t.Source, schema.StringType)
+		tc.assertTypeAssignable(ctx, t.Prefix, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.TrimSuffixExpr:
+		tc.assertTypeAssignable(ctx, t.Source, schema.StringType)
+		tc.assertTypeAssignable(ctx, t.Suffix, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.FormatExpr:
+		tc.assertTypeAssignable(ctx, t.Format, schema.StringType)
+		if format, ok := t.Format.(*ast.StringExpr); ok {
+			if verbs, ok := formatVerbs(format.Value); ok && len(verbs) != len(t.Values) {
+				ctx.addWarnDiag(t.Syntax().Syntax().Range(),
+					fmt.Sprintf("fn::format expects %d arguments for its format string, got %d", len(verbs), len(t.Values)),
+					"The output will include a placeholder such as %!d(MISSING) or %!(EXTRA ...) where the arguments do not match.")
+			}
+		}
+		tc.exprs[t] = schema.StringType
+	case *ast.FormatNumberExpr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.NumberType)
+		tc.assertTypeAssignable(ctx, t.Format, schema.StringType)
+		if format, ok := t.Format.(*ast.StringExpr); ok {
+			if _, err := parseNumberFormat(format.Value); err != nil {
+				ctx.addErrDiag(format.Syntax().Syntax().Range(), fmt.Sprintf("invalid number format %q: %v", format.Value, err), "")
+			}
+		}
+		tc.exprs[t] = schema.StringType
+	case *ast.JSONPathExpr:
+		tc.assertTypeAssignable(ctx, t.Path, schema.StringType)
+		if path, ok := t.Path.(*ast.StringExpr); ok {
+			if _, err := parseJSONPath(path.Value); err != nil {
+				ctx.addErrDiag(path.Syntax().Syntax().Range(), fmt.Sprintf("invalid JSONPath %q: %v", path.Value, err), "")
+			}
+		}
+		tc.exprs[t] = schema.AnyType
+	case *ast.IfExpr:
+		tc.assertTypeAssignable(ctx, t.Condition, schema.BoolType)
+		// Both branches have already been checked, so errors in an unreachable branch are still reported.
+		if cond, ok := t.Condition.(*ast.BooleanExpr); ok {
+			taken, unreachable := "then", t.Else
+			if !cond.Value {
+				taken, unreachable = "else", t.Then
+			}
+			ctx.addWarnDiag(unreachable.Syntax().Syntax().Range(), "this branch of fn::if is unreachable",
+				fmt.Sprintf("The condition is always %t, so the %s branch is always taken", cond.Value, taken))
+		}
+		var types OrderedTypeSet
+		for _, branch := range []ast.Expr{t.Then, t.Else} {
+			if typ, ok := tc.exprs[branch]; ok {
+				types.Add(typ)
+			} else {
+				types.Add(schema.AnyType)
+			}
+		}
+		if types.Len() == 1 {
+			tc.exprs[t] = types.First()
+		} else {
+			tc.exprs[t] = &schema.UnionType{ElementTypes: types.Values()}
+		}
+	case *ast.EqualsExpr:
+		tc.exprs[t] = schema.BoolType
+	case *ast.ContainsExpr:
+		switch typ := codegen.UnwrapType(tc.exprs[t.Collection]).(type) {
+		case *schema.MapType, *schema.ObjectType:
+			tc.assertTypeAssignable(ctx, t.Value, schema.StringType)
+		case nil, *schema.ArrayType, *schema.UnionType, *schema.InvalidType:
+		default:
+			if typ != schema.AnyType {
+				ctx.errorf(t.Collection, "the collection given to fn::contains must be a list or an object, not %s", displayType(typ))
+			}
+		}
+		tc.exprs[t] = schema.BoolType
+	case *ast.NotExpr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.BoolType)
+		tc.exprs[t] = schema.BoolType
+	case *ast.AndExpr:
+		for _, elem := range t.Values.Elements {
+			tc.assertTypeAssignable(ctx, elem, schema.BoolType)
+		}
+		tc.exprs[t] = schema.BoolType
+	case *ast.OrExpr:
+		for _, elem := range t.Values.Elements {
+			tc.assertTypeAssignable(ctx, elem, schema.BoolType)
+		}
+		tc.exprs[t] = schema.BoolType
+	case *ast.OutputExpr:
+		if entry, ok := ctx.root.(ast.PropertyMapEntry); !ok || entry.Value != t {
+			ctx.error(t, "fn::output may only be used as the value of an output")
+		}
+		tc.assertTypeAssignable(ctx, t.Condition, schema.BoolType)
+		tc.exprs[t] = tc.exprs[t.Value]
+	case *ast.Sha256Expr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.Sha1Expr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.ReadFileExpr:
+		tc.assertTypeAssignable(ctx, t.Path, schema.StringType)
+		if t.MaxBytes != nil {
+			tc.assertTypeAssignable(ctx, t.MaxBytes, schema.NumberType)
+		}
+		tc.exprs[t] = schema.StringType
+	case *ast.ReadDirExpr:
+		tc.assertTypeAssignable(ctx, t.Path, schema.StringType)
+		tc.exprs[t] = &schema.ArrayType{ElementType: schema.StringType}
+	case *ast.UUIDExpr, *ast.TimestampExpr:
+		tc.exprs[t] = schema.StringType
+	case *ast.GetEnvExpr:
+		tc.assertTypeAssignable(ctx, t.Variable, schema.StringType)
+		if t.Default != nil {
+			tc.assertTypeAssignable(ctx, t.Default, schema.StringType)
+		}
+		ctx.addWarnDiag(t.Syntax().Syntax().Range(),
+			"fn::getEnv makes the program depend on the environment it runs in",
+			"A preview may not match the update that follows it, if the environment variable differs between them")
+		tc.exprs[t] = schema.StringType
+	case *ast.UpperExpr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.LowerExpr:
+		tc.assertTypeAssignable(ctx, t.Value, schema.StringType)
+		tc.exprs[t] = schema.StringType
+	case *ast.TextTemplateExpr:
+		tc.assertTypeAssignable(ctx, t.Template, schema.StringType)
+		tc.assertTypeAssignable(ctx, t.Data, &schema.MapType{ElementType: schema.AnyType})
+		tc.exprs[t] = schema.StringType
+	case *ast.MergeExpr:
+		anyMap := &schema.MapType{ElementType: schema.AnyType}
+		types := make([]schema.Type, len(t.Values.Elements))
+		for i, elem := range t.Values.Elements {
+			tc.assertTypeAssignable(ctx, elem, anyMap)
+			types[i] = tc.exprs[elem]
+		}
+		tc.exprs[t] = mergeType(types)
 	case *ast.JoinExpr:
 		tc.assertTypeAssignable(ctx, t.Delimiter, schema.StringType)
 		tc.exprs[t] = schema.StringType
@@ -1007,14 +1956,26 @@ func (tc *typeCache) typeExpr(ctx *evalContext, t ast.Expr) bool {
 		// This is an add hock object
 		properties := make([]*schema.Property, 0, len(t.Entries))
 		propNames := make([]string, 0, len(t.Entries))
+		computedKeys := false
 		for _, entry := range t.Entries {
-			k, v := entry.Key.(*ast.StringExpr), entry.Value
+			k, ok := entry.Key.(*ast.StringExpr)
+			if !ok {
+				// Keys computed by an expression must evaluate to strings, but their names aren't known
+				// until the program runs.
+				tc.assertTypeAssignable(ctx, entry.Key, schema.StringType)
+				computedKeys = true
+				continue
+			}
 			properties = append(properties, &schema.Property{
 				Name: k.Value,
-				Type: tc.exprs[v],
+				Type: tc.exprs[entry.Value],
 			})
 			propNames = append(propNames, k.Value)
 		}
+		if computedKeys {
+			tc.exprs[t] = &schema.MapType{ElementType: schema.AnyType}
+			break
+		}
 		tc.exprs[t] = &schema.ObjectType{
 			Token:      adhockObjectToken + strings.Join(propNames, "•"),
 			Properties: properties,
@@ -1024,17 +1985,88 @@ func (tc *typeCache) typeExpr(ctx *evalContext, t ast.Expr) bool {
 	case *ast.SecretExpr:
 		// The type of a secret is the type of its argument
 		tc.exprs[t] = tc.exprs[t.Value]
+	case *ast.UnsecretExpr:
+		tc.exprs[t] = tc.exprs[t.Value]
 	case *ast.SplitExpr:
 		tc.assertTypeAssignable(ctx, t.Delimiter, schema.StringType)
 		tc.assertTypeAssignable(ctx, t.Source, schema.StringType)
+		if d, ok := t.Delimiter.(*ast.StringExpr); ok && d.Value == "" {
+			ctx.error(t.Delimiter, errEmptySplitDelimiter)
+		}
+		tc.exprs[t] = &schema.ArrayType{ElementType: schema.StringType}
+	case *ast.SplitRegexExpr:
+		tc.assertTypeAssignable(ctx, t.Pattern, schema.StringType)
+		tc.assertTypeAssignable(ctx, t.Source, schema.StringType)
 		tc.exprs[t] = &schema.ArrayType{ElementType: schema.StringType}
+	case *ast.KeysExpr:
+		tc.assertTypeAssignable(ctx, t.Object, &schema.MapType{ElementType: schema.AnyType})
+		tc.exprs[t] = &schema.ArrayType{ElementType: schema.StringType}
+	case *ast.ValuesExpr:
+		tc.assertTypeAssignable(ctx, t.Object, &schema.MapType{ElementType: schema.AnyType})
+		var elementType schema.Type = schema.AnyType
+		switch typ := codegen.UnwrapType(tc.exprs[t.Object]).(type) {
+		case *schema.MapType:
+			elementType = typ.ElementType
+		case *schema.ObjectType:
+			var types OrderedTypeSet
+			for _, prop := range typ.Properties {
+				types.Add(prop.Type)
+			}
+			switch types.Len() {
+			case 0:
+			case 1:
+				elementType = types.First()
+			default:
+				elementType = &schema.UnionType{ElementTypes: types.Values()}
+			}
+		}
+		tc.exprs[t] = &schema.ArrayType{ElementType: elementType}
+	case *ast.SortExpr:
+		tc.typeSort(ctx, t)
+	case *ast.UniqueExpr:
+		tc.assertTypeAssignable(ctx, t.Values, &schema.ArrayType{ElementType: schema.AnyType})
+		if arr, ok := codegen.UnwrapType(tc.exprs[t.Values]).(*schema.ArrayType); ok {
+			tc.exprs[t] = arr
+		} else {
+			tc.exprs[t] = &schema.ArrayType{ElementType: schema.AnyType}
+		}
+	case *ast.SliceExpr:
+		tc.assertTypeAssignable(ctx, t.Start, schema.IntType)
+		if t.End != nil {
+			tc.assertTypeAssignable(ctx, t.End, schema.IntType)
+			start, startOk := t.Start.(*ast.NumberExpr)
+			end, endOk := t.End.(*ast.NumberExpr)
+			if startOk && endOk && start.Value > end.Value {
+				ctx.addErrDiag(t.Syntax().Syntax().Range(),
+					fmt.Sprintf("the start index of fn::slice (%v) must not be greater than its end index (%v)", start.Value, end.Value), "")
+			}
+		}
+		tc.assertTypeAssignable(ctx, t.Source, &schema.ArrayType{ElementType: schema.AnyType})
+		if arr, ok := codegen.UnwrapType(tc.exprs[t.Source]).(*schema.ArrayType); ok {
+			tc.exprs[t] = &schema.ArrayType{ElementType: arr.ElementType}
+		} else {
+			tc.exprs[t] = &schema.ArrayType{ElementType: schema.AnyType}
+		}
 	case *ast.SelectExpr:
 		tc.assertTypeAssignable(ctx, t.Index, schema.IntType)
 		tc.assertTypeAssignable(ctx, t.Values,
 			&schema.ArrayType{ElementType: schema.AnyType}) // We accept an array of any type
 		if valuesType, ok := tc.exprs[t.Values]; ok {
 			arr, ok := codegen.UnwrapType(valuesType).(*schema.ArrayType)
-			if ok {
+			if ok && t.Default != nil {
+				var types OrderedTypeSet
+				types.Add(arr.ElementType)
+				if typ, ok := tc.exprs[t.Default]; ok {
+					types.Add(typ)
+				} else {
+					types.Add(schema.AnyType)
+				}
+				if types.Len() == 1 {
+					tc.exprs[t] = types.First()
+				} else {
+					tc.exprs[t] = &schema.UnionType{ElementTypes: types.Values()}
+				}
+			} else if ok {
 				tc.exprs[t] = arr.ElementType
 			} else {
 				tc.exprs[t] = &schema.InvalidType{
@@ -1064,6 +2096,36 @@ func (tc *typeCache) typeVariable(r *Runner, node variableNode) bool {
 	return true
 }
 
+// typeAllowedValues returns the enum type of a config value declared with allowedValues, checking that each allowed
+// value, and the default, has the declared type.
+func (tc *typeCache) typeAllowedValues(ctx *evalContext, k string, c *ast.ConfigParamDecl, typ schema.Type) schema.Type {
+	if _, invalid := typ.(*schema.InvalidType); invalid {
+		return typ
+	}
+	if typ != schema.StringType && typ != schema.NumberType && typ != schema.IntType {
+		ctx.error(c.AllowedValues, fmt.Sprintf("allowedValues can only be used with String, Number or Integer config, not %v", displayType(typ)))
+		return typ
+	}
+
+	enum := &schema.EnumType{Token: "config:" + k, ElementType: typ}
+	for _, elem := range c.AllowedValues.Elements {
+		switch elem := elem.(type) {
+		case *ast.StringExpr:
+			enum.Elements = append(enum.Elements, &schema.Enum{Value: elem.Value})
+		case *ast.NumberExpr:
+			enum.Elements = append(enum.Elements, &schema.Enum{Value: elem.Value})
+		default:
+			ctx.error(elem, "allowedValues must be a list of string or number constants")
+			return typ
+		}
+		tc.assertTypeAssignable(ctx, elem, typ)
+	}
+	if c.Default != nil {
+		tc.assertTypeAssignable(ctx, c.Default, enum)
+	}
+	return enum
+}
+
 func (tc *typeCache) typeConfig(r *Runner, node configNode) bool {
 	k, v := node.key().Value, node.value()
 	var typCurrent schema.Type = &schema.InvalidType{}
@@ -1072,17 +2134,22 @@ func (tc *typeCache) typeConfig(r *Runner, node configNode) bool {
 	switch n := node.(type) {
 	case configNodeYaml:
 		v := n.Value
-		switch {
-		case v.Default != nil:
+		if v.Default != nil {
 			// We have a default, so the type is optional
 			typCurrent = tc.exprs[v.Default]
 			optional = true
-		case v.Type != nil:
+		}
+		if v.Type != nil {
+			// An explicit type takes precedence over the type of the default, which is checked against it when the
+			// config is evaluated.
 			ctype, ok := ctypes.Parse(v.Type.Value)
 			if ok {
 				typCurrent = ctype.Schema()
 			}
 		}
+		if v.AllowedValues != nil {
+			typCurrent = tc.typeAllowedValues(r.newContext(node), k, v, typCurrent)
+		}
 	case configNodeProp:
 		ctype, ok := ctypes.Parse(n.v.TypeString())
 		if ok {
@@ -1117,6 +2184,13 @@ func (tc *typeCache) typeConfig(r *Runner, node configNode) bool {
 // TODO: remove the last case once `configuration` is deprecated.
 func isTypeCompatible(typeA, typeB schema.Type, valB interface{}) bool {
 	typeA, typeB = codegen.UnwrapType(typeA), codegen.UnwrapType(typeB)
+	// Config declared with allowedValues is compatible with values of its underlying type.
+	if enum, ok := typeA.(*schema.EnumType); ok {
+		typeA = enum.ElementType
+	}
+	if enum, ok := typeB.(*schema.EnumType); ok {
+		typeB = enum.ElementType
+	}
 	if typeA.String() == typeB.String() {
 		return true
 	} else if typeA == schema.NumberType && typeB == schema.IntType {
@@ -1137,6 +2211,11 @@ func isTypeCompatible(typeA, typeB schema.Type, valB interface{}) bool {
 	return false
 }
 
+func (tc *typeCache) typeNamePrefix(ctx *evalContext, expr ast.Expr) bool {
+	tc.assertTypeAssignable(ctx, expr, schema.StringType)
+	return !ctx.sdiags.HasErrors()
+}
+
 func (tc *typeCache) typeOutput(r *Runner, node ast.PropertyMapEntry) bool {
 	tc.outputs[node.Key.Value] = tc.exprs[node.Value]
 	return true
@@ -1166,22 +2245,109 @@ func newTypeCache() *typeCache {
 			PulumiVarName: pulumiExpr,
 		},
 		outputs: map[string]schema.Type{},
+		plugins: pluginSet{},
 	}
 }
 
+// checkUnused warns about the variables and configuration declared by the template that are never referenced by a
+// resource, variable, config default or output. Declarations whose name starts with an underscore are exempt, so
+// that an unused entry can be kept deliberately.
+func (r *Runner) checkUnused() syntax.Diagnostics {
+	used := map[string]struct{}{}
+	markUsed := func(node interface{}) {
+		for name := range r.references(node) {
+			used[name] = struct{}{}
+			used[stripConfigNamespace(r.t.Name.GetValue(), name)] = struct{}{}
+		}
+	}
+	for _, node := range r.intermediates {
+		markUsed(node)
+	}
+	for _, node := range r.t.Outputs.Entries {
+		markUsed(node)
+	}
+
+	var diags syntax.Diagnostics
+	warned := map[string]struct{}{}
+	warn := func(kind string, key *ast.StringExpr, advice string) {
+		if _, ok := used[key.Value]; ok || strings.HasPrefix(key.Value, "_") {
+			return
+		}
+		if _, ok := warned[key.Value]; ok {
+			return
+		}
+		warned[key.Value] = struct{}{}
+		var rng *hcl.Range
+		if s := key.Syntax(); s != nil {
+			rng = s.Syntax().Range()
+		}
+		diags = append(diags, syntax.Warning(rng, fmt.Sprintf("%s %s is never used", kind, key.Value), advice))
+	}
+	renameAdvice := func(key *ast.StringExpr) string {
+		return fmt.Sprintf("remove it, or rename it to _%s to keep it without this warning", key.Value)
+	}
+	// The key of a config value is the name it is read by, unless the value sets 'name', so renaming the key
+	// alone would change what is read.
+	warnConfig := func(kvp ast.ConfigMapEntry) {
+		name := kvp.Key.Value
+		if kvp.Value != nil && kvp.Value.Name != nil && kvp.Value.Name.Value != "" {
+			name = kvp.Value.Name.Value
+		}
+		// Config in another package's namespace, such as aws:region, is read by that package's provider.
+		if ns, _, ok := strings.Cut(name, ":"); ok && ns != r.t.Name.GetValue() {
+			return
+		}
+		advice := renameAdvice(kvp.Key)
+		if kvp.Value == nil || kvp.Value.Name == nil || kvp.Value.Name.Value == "" {
+			advice = fmt.Sprintf("remove it, or rename it to _%[1]s and set 'name: %[1]s' to keep reading it without this warning",
+				kvp.Key.Value)
+		}
+		warn("Config value", kvp.Key, advice)
+	}
+	for _, kvp := range r.t.Configuration.Entries {
+		warnConfig(kvp)
+	}
+	for _, kvp := range r.t.Config.Entries {
+		warnConfig(kvp)
+	}
+	for _, kvp := range r.t.Variables.Entries {
+		warn("Variable", kvp.Key, renameAdvice(kvp.Key))
+	}
+	return diags
+}
+
 func TypeCheck(r *Runner) (Typing, syntax.Diagnostics) {
+	return typeCheck(r, false)
+}
+
+// TypeCheckAll is like TypeCheck, but keeps checking past errors so that every diagnostic is reported in a single
+// pass. Nodes that reference a node that failed to check are skipped, so errors don't cascade.
+func TypeCheckAll(r *Runner) (Typing, syntax.Diagnostics) {
+	return typeCheck(r, true)
+}
+
+func typeCheck(r *Runner, continueOnError bool) (Typing, syntax.Diagnostics) {
 	types := newTypeCache()
+	// Conflicts between the template's plugins are reported by GetReferencedPlugins; they only seed the versions
+	// that resources are checked against here.
+	for _, plugin := range r.t.Plugins.Elements {
+		if plugin.Package != nil {
+			types.plugins.accept(plugin.Package.Value, plugin.Version, plugin.DownloadURL, func(ast.Expr, string) {})
+		}
+	}
 
 	// Set roots
 	diags := r.Run(walker{
-		VisitResource: types.typeResource,
-		VisitExpr:     types.typeExpr,
-		VisitVariable: types.typeVariable,
-		VisitConfig:   types.typeConfig,
-		VisitOutput:   types.typeOutput,
+		VisitResource:   types.typeResource,
+		VisitExpr:       types.typeExpr,
+		VisitVariable:   types.typeVariable,
+		VisitConfig:     types.typeConfig,
+		VisitOutput:     types.typeOutput,
+		VisitNamePrefix: types.typeNamePrefix,
+		ContinueOnError: continueOnError,
 	})
 
-	return types, diags
+	return types, diags.Attribute(syntax.SourceTypeCheck)
 }
 
 type walker struct {
@@ -1190,6 +2356,42 @@ type walker struct {
 	VisitOutput   func(r *Runner, node ast.PropertyMapEntry) bool
 	VisitResource func(r *Runner, node resourceNode) bool
 	VisitExpr     func(*evalContext, ast.Expr) bool
+	// VisitNamePrefix is called with the template's namePrefix, before any other node is visited.
+	VisitNamePrefix func(ctx *evalContext, expr ast.Expr) bool
+
+	// When set, a failed visit doesn't stop the walk. The siblings of a failed expression are still walked, but its
+	// parents are not visited, and Run moves on to the next node that doesn't depend on a failed one.
+	ContinueOnError bool
+}
+
+// visitAll calls each visit in order, and returns whether all of them succeeded. Unless the walker continues on
+// error, it stops at the first visit that fails.
+func (e walker) visitAll(visits ...func() bool) bool {
+	ok := true
+	for _, visit := range visits {
+		if !visit() {
+			if !e.ContinueOnError {
+				return false
+			}
+			ok = false
+		}
+	}
+	return ok
+}
+
+// walkAll walks each of exprs in order, and returns whether all of them succeeded. Unless the walker continues on
+// error, it stops at the first expression that fails.
+func (e walker) walkAll(ctx *evalContext, exprs ...ast.Expr) bool {
+	ok := true
+	for _, x := range exprs {
+		if !e.walk(ctx, x) {
+			if !e.ContinueOnError {
+				return false
+			}
+			ok = false
+		}
+	}
+	return ok
 }
 
 func (e walker) walk(ctx *evalContext, x ast.Expr) bool {
@@ -1199,26 +2401,20 @@ func (e walker) walk(ctx *evalContext, x ast.Expr) bool {
 	switch x := x.(type) {
 	case *ast.NullExpr, *ast.BooleanExpr, *ast.NumberExpr, *ast.StringExpr:
 	case *ast.ListExpr:
-		for _, el := range x.Elements {
-			if !e.walk(ctx, el) {
-				return false
-			}
+		if !e.walkAll(ctx, x.Elements...) {
+			return false
 		}
 	case *ast.ObjectExpr:
+		exprs := make([]ast.Expr, 0, 2*len(x.Entries))
 		for _, prop := range x.Entries {
-			if !e.walk(ctx, prop.Key) {
-				return false
-			}
-			if !e.walk(ctx, prop.Value) {
-				return false
-			}
+			exprs = append(exprs, prop.Key, prop.Value)
 		}
-	case *ast.InterpolateExpr, *ast.SymbolExpr:
-	case ast.BuiltinExpr:
-		if !e.walk(ctx, x.Name()) {
+		if !e.walkAll(ctx, exprs...) {
 			return false
 		}
-		if !e.walk(ctx, x.Args()) {
+	case *ast.InterpolateExpr, *ast.SymbolExpr:
+	case ast.BuiltinExpr:
+		if !e.walkAll(ctx, x.Name(), x.Args()) {
 			return false
 		}
 	default:
@@ -1227,20 +2423,37 @@ func (e walker) walk(ctx *evalContext, x ast.Expr) bool {
 	return e.VisitExpr(ctx, x)
 }
 
-func (e walker) EvalConfig(r *Runner, node configNode) bool {
+func (e walker) EvalNamePrefix(r *Runner, expr ast.Expr) bool {
+	ctx := r.newContext(expr)
+	if !ctx.checkNamePrefixReferences(expr) {
+		return false
+	}
 	if e.VisitExpr != nil {
-		ctx := r.newContext(node)
-		if !e.walk(ctx, node.key()) {
+		if !e.walk(ctx, expr) {
 			return false
 		}
+	}
+	if e.VisitNamePrefix != nil {
+		if !e.VisitNamePrefix(ctx, expr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e walker) EvalConfig(r *Runner, node configNode) bool {
+	if e.VisitExpr != nil {
+		ctx := r.newContext(node)
+		exprs := []ast.Expr{node.key()}
 		if nodeYaml, ok := node.(configNodeYaml); ok {
-			if !e.walk(ctx, nodeYaml.Value.Default) {
-				return false
-			}
-			if !e.walk(ctx, nodeYaml.Value.Secret) {
-				return false
+			exprs = append(exprs, nodeYaml.Value.Default, nodeYaml.Value.Secret)
+			if nodeYaml.Value.AllowedValues != nil {
+				exprs = append(exprs, nodeYaml.Value.AllowedValues)
 			}
 		}
+		if !e.walkAll(ctx, exprs...) {
+			return false
+		}
 	}
 	if e.VisitConfig != nil {
 		if !e.VisitConfig(r, node) {
@@ -1253,10 +2466,7 @@ func (e walker) EvalConfig(r *Runner, node configNode) bool {
 func (e walker) EvalVariable(r *Runner, node variableNode) bool {
 	if e.VisitExpr != nil {
 		ctx := r.newContext(node)
-		if !e.walk(ctx, node.Key) {
-			return false
-		}
-		if !e.walk(ctx, node.Value) {
+		if !e.walkAll(ctx, node.Key, node.Value) {
 			return false
 		}
 	}
@@ -1271,10 +2481,7 @@ func (e walker) EvalVariable(r *Runner, node variableNode) bool {
 func (e walker) EvalOutput(r *Runner, node ast.PropertyMapEntry) bool {
 	if e.VisitExpr != nil {
 		ctx := r.newContext(node)
-		if !e.walk(ctx, node.Key) {
-			return false
-		}
-		if !e.walk(ctx, node.Value) {
+		if !e.walkAll(ctx, node.Key, node.Value) {
 			return false
 		}
 	}
@@ -1290,20 +2497,13 @@ func (e walker) EvalOutput(r *Runner, node ast.PropertyMapEntry) bool {
 func (e walker) EvalResource(r *Runner, node resourceNode) bool {
 	if e.VisitExpr != nil {
 		ctx := r.newContext(node)
-		if !e.walk(ctx, node.Key) {
-			return false
-		}
 		v := node.Value
-		if !e.walk(ctx, v.Type) {
-			return false
-		}
-		if !e.walkPropertyMap(ctx, v.Properties) {
-			return false
-		}
-		if !e.walkResourceOptions(ctx, v.Options) {
-			return false
-		}
-		if !e.walkGetResoure(ctx, v.Get) {
+		if !e.visitAll(
+			func() bool { return e.walkAll(ctx, node.Key, v.Type) },
+			func() bool { return e.walkPropertyMap(ctx, v.Properties) },
+			func() bool { return e.walkResourceOptions(ctx, v.Options) },
+			func() bool { return e.walkGetResoure(ctx, v.Get) },
+		) {
 			return false
 		}
 	}
@@ -1317,94 +2517,53 @@ func (e walker) EvalResource(r *Runner, node resourceNode) bool {
 }
 
 func (e walker) walkPropertyMap(ctx *evalContext, m ast.PropertyMapDecl) bool {
+	exprs := make([]ast.Expr, 0, 2*len(m.Entries))
 	for _, prop := range m.Entries {
-		if !e.walk(ctx, prop.Key) {
-			return false
-		}
-		if !e.walk(ctx, prop.Value) {
-			return false
-		}
+		exprs = append(exprs, prop.Key, prop.Value)
 	}
-	return true
+	return e.walkAll(ctx, exprs...)
 }
 
 func (e walker) walkGetResoure(ctx *evalContext, get ast.GetResourceDecl) bool {
-	if !e.walk(ctx, get.Id) {
-		return false
-	}
-	return e.walkPropertyMap(ctx, get.State)
+	return e.visitAll(
+		func() bool { return e.walk(ctx, get.Id) },
+		func() bool { return e.walkPropertyMap(ctx, get.State) },
+	)
 }
 
 func (e walker) walkResourceOptions(ctx *evalContext, opts ast.ResourceOptionsDecl) bool {
-	if !e.walkStringList(ctx, opts.AdditionalSecretOutputs) {
-		return false
-	}
-	if !e.walkStringList(ctx, opts.Aliases) {
-		return false
-	}
-	if !e.walk(ctx, opts.DeleteBeforeReplace) {
-		return false
-	}
-	if !e.walk(ctx, opts.DependsOn) {
-		return false
+	visits := []func() bool{
+		func() bool { return e.walkStringList(ctx, opts.AdditionalSecretOutputs) },
+		func() bool { return e.walkStringList(ctx, opts.Aliases) },
+		func() bool { return e.walk(ctx, opts.DeleteBeforeReplace) },
+		func() bool { return e.walk(ctx, opts.DependsOn) },
+		func() bool { return e.walkStringList(ctx, opts.IgnoreChanges) },
+		func() bool { return e.walk(ctx, opts.Import) },
+		func() bool { return e.walk(ctx, opts.Parent) },
+		func() bool { return e.walk(ctx, opts.Protect) },
+		func() bool { return e.walk(ctx, opts.Provider) },
+		func() bool { return e.walk(ctx, opts.Providers) },
+		func() bool { return e.walk(ctx, opts.Version) },
+		func() bool { return e.walk(ctx, opts.PluginDownloadURL) },
+		func() bool { return e.walkStringList(ctx, opts.ReplaceOnChanges) },
+		func() bool { return e.walk(ctx, opts.RetainOnDelete) },
+		func() bool { return e.walk(ctx, opts.DeletedWith) },
 	}
-	if !e.walkStringList(ctx, opts.IgnoreChanges) {
-		return false
-	}
-	if !e.walk(ctx, opts.Import) {
-		return false
-	}
-	if !e.walk(ctx, opts.Parent) {
-		return false
-	}
-	if !e.walk(ctx, opts.Protect) {
-		return false
-	}
-	if !e.walk(ctx, opts.Provider) {
-		return false
-	}
-	if !e.walk(ctx, opts.Providers) {
-		return false
-	}
-	if !e.walk(ctx, opts.Version) {
-		return false
-	}
-	if !e.walk(ctx, opts.PluginDownloadURL) {
-		return false
-	}
-	if !e.walkStringList(ctx, opts.ReplaceOnChanges) {
-		return false
-	}
-	if !e.walk(ctx, opts.RetainOnDelete) {
-		return false
-	}
-	if !e.walk(ctx, opts.DeletedWith) {
-		return false
-	}
-
 	if ct := opts.CustomTimeouts; ct != nil {
-		if !e.walk(ctx, ct.Create) {
-			return false
-		}
-		if !e.walk(ctx, ct.Delete) {
-			return false
-		}
-		if !e.walk(ctx, ct.Update) {
-			return false
-		}
+		visits = append(visits, func() bool { return e.walkAll(ctx, ct.Create, ct.Delete, ct.Update) })
 	}
-	return true
+	return e.visitAll(visits...)
 }
 
 func (e walker) walkStringList(ctx *evalContext, l *ast.StringListDecl) bool {
-	if l != nil {
-		for _, el := range l.Elements {
-			if !e.walk(ctx, el) {
-				return false
-			}
-		}
+	if l == nil {
+		return true
 	}
-	return true
+	exprs := make([]ast.Expr, len(l.Elements))
+	for i, el := range l.Elements {
+		exprs[i] = el
+	}
+	return e.walkAll(ctx, exprs...)
 }
 
 // Compute the set of fields valid for the resource options.
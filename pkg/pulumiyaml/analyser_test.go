@@ -14,6 +14,14 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func mustSymbol(value string) *ast.SymbolExpr {
+	symbol, diags := ast.VariableSubstitution(value)
+	if diags.HasErrors() {
+		panic(diags.Error())
+	}
+	return symbol
+}
+
 func TestTypeError(t *testing.T) {
 	t.Parallel()
 	cases := []struct {
@@ -185,6 +193,72 @@ func TestTypeError(t *testing.T) {
 			message: `Cannot assign type 'number' to type 'tk:index:Enum':
   Allowed values are fizz (0), 0.5, 1`,
 		},
+		{
+			// Integer enums hold their values as integers
+			from:     schema.NumberType,
+			fromExpr: ast.Number(2),
+			to: &schema.EnumType{
+				Token:       "tk:index:Size",
+				Elements:    []*schema.Enum{{Value: int32(1)}, {Value: int32(2)}},
+				ElementType: schema.IntType,
+			},
+		},
+		{
+			from:     schema.NumberType,
+			fromExpr: ast.Number(3),
+			to: &schema.EnumType{
+				Token:       "tk:index:Size",
+				Elements:    []*schema.Enum{{Name: "small", Value: int32(1)}, {Value: int32(2)}},
+				ElementType: schema.IntType,
+			},
+			message: `Cannot assign type 'number' to type 'tk:index:Size':
+  Allowed values are small (1), 2`,
+		},
+		{
+			// Values that aren't known until the program runs can't be checked
+			from:     schema.StringType,
+			fromExpr: mustSymbol("config.env"),
+			to: &schema.EnumType{
+				Token:       "tk:index:Enum",
+				Elements:    []*schema.Enum{{Value: "foo"}},
+				ElementType: schema.StringType,
+			},
+		},
+		{
+			// Enums are assignable to their element type
+			from: &schema.EnumType{
+				Token:       "env",
+				Elements:    []*schema.Enum{{Value: "dev"}, {Value: "prod"}},
+				ElementType: schema.StringType,
+			},
+			to: schema.StringType,
+		},
+		{
+			// Enums are assignable to enums which allow all of their values
+			from: &schema.EnumType{
+				Token:       "env",
+				Elements:    []*schema.Enum{{Value: "dev"}, {Value: "prod"}},
+				ElementType: schema.StringType,
+			},
+			to: &schema.EnumType{
+				Token:       "tk:index:Env",
+				Elements:    []*schema.Enum{{Value: "dev"}, {Value: "staging"}, {Value: "prod"}},
+				ElementType: schema.StringType,
+			},
+		},
+		{
+			from: &schema.EnumType{
+				Token:       "env",
+				Elements:    []*schema.Enum{{Value: "dev"}, {Value: "test"}},
+				ElementType: schema.StringType,
+			},
+			to: &schema.EnumType{
+				Token:       "tk:index:Env",
+				Elements:    []*schema.Enum{{Value: "dev"}, {Value: "prod"}},
+				ElementType: schema.StringType,
+			},
+			message: `Cannot assign 'env' to 'tk:index:Env': test is not one of the allowed values`,
+		},
 	}
 
 	for i, c := range cases { //nolint:paralleltest
@@ -254,6 +328,25 @@ func TestTypePropertyAccess(t *testing.T) {
 			expectedType: "Invalid",
 			errMsg:       `fizzbuzz does not exist on start:Existing properties are: buzz, fizz, id, urn`,
 		},
+		{
+			root: &schema.ResourceType{
+				Token: "pkg:mod:Token",
+				Resource: &schema.Resource{
+					InputProperties: []*schema.Property{
+						{Name: "fizz", Type: schema.StringType},
+						{Name: "password", Type: schema.StringType},
+					},
+					Properties: []*schema.Property{
+						{Name: "fizz", Type: schema.StringType},
+					},
+				},
+			},
+			list: []ast.PropertyAccessor{
+				&ast.PropertyName{Name: "password"},
+			},
+			expectedType: "Invalid",
+			errMsg:       `password is an input of start and is not available as an output:Existing properties are: id, urn, fizz`,
+		},
 		{
 			root: &schema.UnionType{
 				ElementTypes: []schema.Type{
@@ -368,3 +461,296 @@ func TestConfigCompatibility(t *testing.T) {
 		})
 	}
 }
+
+func TestComputedObjectKeys(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  name: foo
+  names: [foo, bar]
+  valid:
+    ${name}: bar
+    static: baz
+  invalid:
+    ${names}: bar
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:10:5: string is not assignable from List<string>; Cannot assign 'List<string>' to 'string'",
+	}, diagStrings)
+	assert.Equal(t, "Map<any>", displayType(tc.TypeVariable("valid")))
+}
+
+func TestSelectFromUnionList(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  picked:
+    fn::split:
+      - ","
+      - fn::select:
+          - 0
+          - [a, [b]]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:7:9: The element selected by fn::select could be any of string, List<string>, " +
+			"and not all of them are assignable to string; " +
+			"Cannot assign 'Union<string, List<string>>' to 'string':\n  Cannot assign 'List<string>' to 'string'",
+	}, diagStrings)
+}
+
+func TestSelectDefaultType(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  same:
+    fn::select: [2, [a, b], c]
+  mixed:
+    fn::select: [2, [a, b], 3]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, "string", displayType(tc.TypeVariable("same")))
+	assert.Equal(t, "Union<string, number>", displayType(tc.TypeVariable("mixed")))
+}
+
+func TestSliceType(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  strings:
+    fn::slice: [[a, b, c], 1]
+  numbers:
+    fn::slice: [[1, 2, 3], 0, 2]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, "List<string>", displayType(tc.TypeVariable("strings")))
+	assert.Equal(t, "List<number>", displayType(tc.TypeVariable("numbers")))
+}
+
+func TestKeysValuesType(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  object:
+    name: web
+    port: 80
+  keys:
+    fn::keys: ${object}
+  values:
+    fn::values: ${object}
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, "List<string>", displayType(tc.TypeVariable("keys")))
+	assert.Equal(t, "List<Union<string, number>>", displayType(tc.TypeVariable("values")))
+}
+
+func TestExplainTypes(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  names: [a, b]
+  joined:
+    fn::join: [",", "${names}"]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+
+	var explained []string
+	for _, et := range ExplainTypes(tc) {
+		explained = append(explained, fmt.Sprintf("%v:%v-%v:%v: %v",
+			et.Range.Start.Line, et.Range.Start.Column, et.Range.End.Line, et.Range.End.Column, et.Type))
+	}
+	assert.Equal(t, []string{
+		"4:3-4:8: string",
+		"4:10-4:15: List<string>",
+		"4:11-4:12: string",
+		"4:14-4:15: string",
+		"5:3-5:9: string",
+		"6:5-6:29: string",
+		"6:5-6:13: string",
+		"6:15-6:29: List<Union<string, List<string>>>",
+		"6:16-6:17: string",
+		"6:21-6:29: List<string>",
+	}, explained)
+	assert.Equal(t, ExplainTypes(tc), ExplainTypes(tc))
+}
+
+func TestConfigAllowedValues(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+configuration:
+  env:
+    type: String
+    allowedValues: [dev, staging, prod]
+    default: dev
+  size:
+    type: Number
+    allowedValues: [1, 2]
+    default: 3
+  mixed:
+    type: Number
+    allowedValues: [1, two]
+  list:
+    type: List<String>
+    allowedValues: [a]
+variables:
+  name: ${env}
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:11:14: config:size is not assignable from number; Cannot assign type 'number' to type 'config:size':\n" +
+			"  Allowed values are 1, 2",
+		"<stdin>:14:24: number is not assignable from string; Cannot assign type 'string' to type 'number'",
+		"<stdin>:17:20: allowedValues can only be used with String, Number or Integer config, not List<string>",
+	}, diagStrings)
+	assert.Equal(t, "config:env", displayType(tc.TypeVariable("name")))
+}
+
+func TestIfBranchTypes(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  same:
+    fn::if: [true, a, b]
+  mixed:
+    fn::if: [true, a, [b]]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, "string", displayType(tc.TypeVariable("same")))
+	assert.Equal(t, "Union<string, List<string>>", displayType(tc.TypeVariable("mixed")))
+}
+
+func TestIfConstantCondition(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  always:
+    fn::if: [true, a, b]
+  never:
+    fn::if:
+      - false
+      - fn::select: [first, [a]]
+      - b
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheckAll(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:23: this branch of fn::if is unreachable; The condition is always true, so the then branch is always taken",
+		"<stdin>:9:22: integer is not assignable from string; Cannot assign type 'string' to type 'integer'",
+		"<stdin>:9:9: this branch of fn::if is unreachable; The condition is always false, so the else branch is always taken",
+	}, diagStrings)
+}
+
+func TestNamePrefixType(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+namePrefix: [dev]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:3:13: string is not assignable from List<string>; Cannot assign 'List<string>' to 'string'",
+	}, diagStrings)
+}
+
+func TestTypeCheckAll(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  first:
+    fn::invoke:
+      function: unknown:index:first
+  dependent: ${first}
+  second:
+    fn::invoke:
+      function: unknown:index:second
+outputs:
+  out: ${dependent}
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+
+	diagStrings := func(diags syntax.Diagnostics) []string {
+		var strs []string
+		for _, v := range diags {
+			strs = append(strs, diagString(v))
+		}
+		return strs
+	}
+
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	assert.Equal(t, []string{
+		`<stdin>:5:5: internal error loading package "unknown": package not found`,
+	}, diagStrings(diags))
+
+	_, diags = TypeCheckAll(newRunner(tmpl, newMockPackageMap()))
+	assert.Equal(t, []string{
+		`<stdin>:5:5: internal error loading package "unknown": package not found`,
+		`<stdin>:9:5: internal error loading package "unknown": package not found`,
+	}, diagStrings(diags))
+}
@@ -150,7 +150,10 @@ func (n *InterpolateExpr) String() string {
 		// this is necessary because the parser will escape the string
 		// so when we print it back out as string, we need to un-escape it
 		str.WriteString(strings.ReplaceAll(p.Text, "$", "$$"))
-		if p.Value != nil {
+		switch {
+		case p.Value != nil && p.Default != nil:
+			fmt.Fprintf(&str, "${%v:-%s}", p.Value, *p.Default)
+		case p.Value != nil:
 			fmt.Fprintf(&str, "${%v}", p.Value)
 		}
 	}
@@ -190,7 +193,8 @@ func VariableSubstitution(value string) (*SymbolExpr, syntax.Diagnostics) {
 		return nil, diags
 	}
 
-	if interpolate != nil && len(interpolate.Parts) == 1 && interpolate.Parts[0].Text == "" {
+	if interpolate != nil && len(interpolate.Parts) == 1 && interpolate.Parts[0].Text == "" &&
+		interpolate.Parts[0].Default == nil {
 		return &SymbolExpr{
 			exprNode: expr(node),
 			Property: interpolate.Parts[0].Value,
@@ -302,7 +306,7 @@ func ParseExpr(node syntax.Node) (Expr, syntax.Diagnostics) {
 				switch {
 				case interpolate.Parts[0].Value == nil:
 					return StringSyntaxValue(node, interpolate.Parts[0].Text), diags
-				case interpolate.Parts[0].Text == "":
+				case interpolate.Parts[0].Text == "" && interpolate.Parts[0].Default == nil:
 					return &SymbolExpr{
 						exprNode: expr(node),
 						Property: interpolate.Parts[0].Value,
@@ -441,6 +445,81 @@ type InvokeExpr struct {
 	CallArgs *ObjectExpr
 	CallOpts InvokeOptionsDecl
 	Return   *StringExpr
+
+	// PositionalArgs holds the arguments when they are given as a list rather than an object. The elements are
+	// matched to the function's inputs in the order its schema declares them.
+	PositionalArgs *ListExpr
+
+	// ArgumentsExpr holds the arguments when they are given as a single reference, e.g. `${settings}`, rather than
+	// an object literal. The referenced value is resolved before the function is invoked and must be an object.
+	ArgumentsExpr *SymbolExpr
+
+	// Collect, if set, calls the function repeatedly to gather every page of its results into a single list.
+	Collect *InvokeCollectDecl
+}
+
+// ReturnPath splits the return directive into the name of the output to return and the accessors of a nested value
+// within it, e.g. `result.items[0]`. A directive that isn't a valid property path names an output as written.
+func (e *InvokeExpr) ReturnPath() (string, []PropertyAccessor) {
+	if e.Return == nil {
+		return "", nil
+	}
+	rest, access, diags := parsePropertyAccess(nil, e.Return.Value+"}")
+	if diags.HasErrors() || rest != "" || access == nil || len(access.Accessors) == 0 {
+		return e.Return.Value, nil
+	}
+	return access.RootName(), access.Accessors[1:]
+}
+
+// ReturnPathRange returns the range of segment i of the return path, where the root name is segment 0 and each
+// accessor returned by ReturnPath is a further segment. If the segment's position within the return path can't be
+// determined, e.g. because the path is quoted, the range of the whole return path is returned.
+func (e *InvokeExpr) ReturnPathRange(i int) *hcl.Range {
+	if e.Return == nil || e.Return.Syntax() == nil || e.Return.Syntax().Syntax() == nil {
+		return nil
+	}
+	rng := e.Return.Syntax().Syntax().Range()
+	value := e.Return.Value
+	if rng == nil || rng.Start.Line != rng.End.Line || rng.End.Column-rng.Start.Column != len(value) {
+		return rng
+	}
+
+	// Find the start of each segment: a name begins after a '.', and a subscript begins at its '['.
+	var starts []int
+	quoted := false
+	for j := 0; j < len(value); j++ {
+		switch c := value[j]; {
+		case quoted:
+			if c == '\\' {
+				j++
+			} else if c == '"' {
+				quoted = false
+			}
+		case c == '"':
+			quoted = true
+		case c == '.':
+			starts = append(starts, j+1)
+		case c == '[':
+			starts = append(starts, j)
+		}
+	}
+	starts = append([]int{0}, starts...)
+	if i >= len(starts) {
+		return rng
+	}
+	start, end := starts[i], len(value)
+	if i+1 < len(starts) {
+		end = starts[i+1]
+		if value[end-1] == '.' {
+			end--
+		}
+	}
+	segment := *rng
+	segment.Start.Column, segment.End.Column = rng.Start.Column+start, rng.Start.Column+end
+	if rng.End.Byte != 0 {
+		segment.Start.Byte, segment.End.Byte = rng.Start.Byte+start, rng.Start.Byte+end
+	}
+	return &segment
 }
 
 func InvokeSyntax(node *syntax.ObjectNode, name *StringExpr, args *ObjectExpr, token *StringExpr, callArgs *ObjectExpr, callOpts InvokeOptionsDecl, ret *StringExpr) *InvokeExpr {
@@ -491,6 +570,44 @@ func ToJSON(value Expr) *ToJSONExpr {
 	return ToJSONSyntax(nil, name, value)
 }
 
+// ToYAMLExpr returns the underlying structure as a YAML string.
+type ToYAMLExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func ToYAMLSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *ToYAMLExpr {
+	return &ToYAMLExpr{
+		builtinNode: builtin(node, name, args),
+		Value:       args,
+	}
+}
+
+func ToYAML(value Expr) *ToYAMLExpr {
+	name := String("fn::toYAML")
+	return ToYAMLSyntax(nil, name, value)
+}
+
+// FromJSONExpr decodes a JSON string into the value it represents.
+type FromJSONExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func FromJSONSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *FromJSONExpr {
+	return &FromJSONExpr{
+		builtinNode: builtin(node, name, args),
+		Value:       args,
+	}
+}
+
+func FromJSON(value Expr) *FromJSONExpr {
+	name := String("fn::fromJSON")
+	return FromJSONSyntax(nil, name, value)
+}
+
 // JoinExpr appends a set of values into a single value, separated by the specified delimiter.
 // If a delimiter is the empty string, the set of values are concatenated with no delimiter.
 type JoinExpr struct {
@@ -535,6 +652,25 @@ func SplitSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr) *Spl
 	}
 }
 
+// SplitRegexExpr splits a string into a list around each match of a regular expression.
+type SplitRegexExpr struct {
+	builtinNode
+
+	Pattern Expr
+	Source  Expr
+
+	// Regexp is the compiled pattern, if Pattern is a constant.
+	Regexp *regexp.Regexp
+}
+
+func SplitRegexSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, pattern, source Expr) *SplitRegexExpr {
+	return &SplitRegexExpr{
+		builtinNode: builtin(node, name, args),
+		Pattern:     pattern,
+		Source:      source,
+	}
+}
+
 func Split(delimiter, source Expr) *SplitExpr {
 	name := String("fn::split")
 	return &SplitExpr{
@@ -550,6 +686,8 @@ type SelectExpr struct {
 
 	Index  Expr
 	Values Expr
+	// Default, if set, is returned when Index is out of range.
+	Default Expr
 }
 
 func SelectSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, index Expr, values Expr) *SelectExpr {
@@ -569,6 +707,148 @@ func Select(index Expr, values Expr) *SelectExpr {
 	}
 }
 
+// SliceExpr returns the elements of a list from Start up to, but not including, End.
+type SliceExpr struct {
+	builtinNode
+
+	Source Expr
+	Start  Expr
+	// End, if set, is the index after the last element to return. If unset, the slice runs to the end of Source.
+	End Expr
+}
+
+func SliceSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, source, start Expr) *SliceExpr {
+	return &SliceExpr{
+		builtinNode: builtin(node, name, args),
+		Source:      source,
+		Start:       start,
+	}
+}
+
+// KeysExpr returns the keys of an object, in sorted order.
+type KeysExpr struct {
+	builtinNode
+
+	Object Expr
+}
+
+func KeysSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *KeysExpr {
+	return &KeysExpr{
+		builtinNode: builtin(node, name, args),
+		Object:      args,
+	}
+}
+
+// ValuesExpr returns the values of an object, in the sorted order of their keys.
+type ValuesExpr struct {
+	builtinNode
+
+	Object Expr
+}
+
+func ValuesSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *ValuesExpr {
+	return &ValuesExpr{
+		builtinNode: builtin(node, name, args),
+		Object:      args,
+	}
+}
+
+// SortExpr sorts a list of strings or numbers, in ascending order unless Descending is true.
+type SortExpr struct {
+	builtinNode
+
+	Values     Expr
+	Descending Expr
+}
+
+func SortSyntax(node *syntax.ObjectNode, name *StringExpr, args, values, descending Expr) *SortExpr {
+	return &SortExpr{
+		builtinNode: builtin(node, name, args),
+		Values:      values,
+		Descending:  descending,
+	}
+}
+
+func parseSort(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	obj, ok := args.(*ObjectExpr)
+	if !ok {
+		return SortSyntax(node, name, args, args, nil), nil
+	}
+
+	var values, descending Expr
+	var diags syntax.Diagnostics
+	for _, entry := range obj.Entries {
+		k, ok := entry.Key.(*StringExpr)
+		if !ok {
+			diags.Extend(ExprError(entry.Key, "fn::sort only accepts literal keys", ""))
+			continue
+		}
+		switch k.Value {
+		case "values":
+			values = entry.Value
+		case "descending":
+			descending = entry.Value
+		default:
+			diags.Extend(ExprError(k, fmt.Sprintf("fn::sort has no argument named %q", k.Value),
+				"Valid arguments are 'values' and 'descending'"))
+		}
+	}
+	if values == nil {
+		diags.Extend(ExprError(obj, "missing required argument 'values' to fn::sort", ""))
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return SortSyntax(node, name, obj, values, descending), diags
+}
+
+// UniqueExpr removes the elements of a list that are deeply equal to an earlier element.
+type UniqueExpr struct {
+	builtinNode
+
+	Values Expr
+}
+
+func UniqueSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *UniqueExpr {
+	return &UniqueExpr{
+		builtinNode: builtin(node, name, args),
+		Values:      args,
+	}
+}
+
+func parseUnique(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return UniqueSyntax(node, name, args), nil
+}
+
+// ToStringExpr converts a number, boolean or resource to a string.
+type ToStringExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func ToStringSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *ToStringExpr {
+	return &ToStringExpr{
+		builtinNode: builtin(node, name, args),
+		Value:       args,
+	}
+}
+
+// ToNumberExpr parses a string into a number.
+type ToNumberExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func ToNumberSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *ToNumberExpr {
+	return &ToNumberExpr{
+		builtinNode: builtin(node, name, args),
+		Value:       args,
+	}
+}
+
 type ToBase64Expr struct {
 	builtinNode
 
@@ -595,6 +875,34 @@ func FromBase64Syntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *Fro
 	}
 }
 
+// Sha256Expr returns the lowercase hex encoded SHA-256 digest of a string.
+type Sha256Expr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func Sha256Syntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *Sha256Expr {
+	return &Sha256Expr{
+		builtinNode: builtin(node, name, args),
+		Value:       args,
+	}
+}
+
+// Sha1Expr returns the lowercase hex encoded SHA-1 digest of a string.
+type Sha1Expr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func Sha1Syntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *Sha1Expr {
+	return &Sha1Expr{
+		builtinNode: builtin(node, name, args),
+		Value:       args,
+	}
+}
+
 type AssetOrArchiveExpr interface {
 	Expr
 	isAssetOrArchive()
@@ -723,9 +1031,20 @@ func SecretSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *SecretE
 	}
 }
 
+// UnsecretExpr returns Value with its secret marker removed. Reason records why the value is safe to reveal, so that
+// declassifying a secret is always a deliberate, reviewable choice.
+type UnsecretExpr struct {
+	builtinNode
+
+	Value  Expr
+	Reason *StringExpr
+}
+
 type ReadFileExpr struct {
 	builtinNode
 	Path Expr
+	// MaxBytes is the largest file that may be read, if set. It can only be given in the object form of fn::readFile.
+	MaxBytes Expr
 }
 
 func ReadFileSyntax(node syntax.Node, name *StringExpr, path Expr) *ReadFileExpr {
@@ -735,98 +1054,854 @@ func ReadFileSyntax(node syntax.Node, name *StringExpr, path Expr) *ReadFileExpr
 	}
 }
 
-func parseReadFile(node *syntax.ObjectNode, name *StringExpr, path Expr) (Expr, syntax.Diagnostics) {
-	return ReadFileSyntax(node, name, path), nil
-}
-
-func tryParseFunction(node *syntax.ObjectNode) (Expr, syntax.Diagnostics, bool) {
-	if node.Len() != 1 {
-		return nil, nil, false
-	}
-
-	kvp := node.Index(0)
-
-	if _, _, ok := getAssetOrArchive(StringSyntax(kvp.Key)); ok {
-		// We will parse this node as an asset or archive later, so we don't need to do it now
-		return nil, nil, false
+func parseReadFile(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	obj, ok := args.(*ObjectExpr)
+	if !ok {
+		return ReadFileSyntax(node, name, args), nil
 	}
 
-	var parse func(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics)
+	var path, maxBytes Expr
 	var diags syntax.Diagnostics
-	set := func(expected string, parseFn func(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics)) {
-		diags.Extend(syntax.UnexpectedCasing(kvp.Key.Syntax().Range(), expected, kvp.Key.Value()))
-		parse = parseFn
-	}
-	switch strings.ToLower(kvp.Key.Value()) {
-	case "fn::invoke":
-		set("fn::invoke", parseInvoke)
-	case "fn::join":
-		set("fn::join", parseJoin)
-	case "fn::tojson":
-		set("fn::toJSON", parseToJSON)
-	case "fn::tobase64":
-		set("fn::toBase64", parseToBase64)
-	case "fn::frombase64":
-		set("fn::fromBase64", parseFromBase64)
-	case "fn::select":
-		set("fn::select", parseSelect)
-	case "fn::split":
-		set("fn::split", parseSplit)
-	case "fn::stackreference":
-		set("fn::stackReference", parseStackReference)
-		diags = append(diags, syntax.Warning(kvp.Key.Syntax().Range(),
-			`'fn::stackReference' is deprecated; please use 'pulumi:pulumi:StackReference' instead`,
-			`see "https://www.pulumi.com/docs/intro/concepts/stack/#stackreferences for more info.`))
-	case "fn::assetarchive":
-		set("fn::assetArchive", parseAssetArchive)
-	case "fn::secret":
-		set("fn::secret", parseSecret)
-	case "fn::readfile":
-		set("fn::readFile", parseReadFile)
-	default:
-		k := kvp.Key.Value()
-		// fn::invoke can be called as fn::${pkg}:${module}(:${name})?
-		// error is thrown if regex pattern cannot be parsed — handled by `regex.MustCompile(fnInvokeRegex)`
-		if fnInvokeRegex.MatchString(strings.ToLower(k)) {
-			// transform the node into standard fn::invoke format
-			fnVal := k[4:]
-			if _, ok := kvp.Value.(*syntax.ObjectNode); ok {
-				kvp.Value = syntax.Object(
-					syntax.ObjectPropertyDef{
-						Key:   syntax.StringSyntax(kvp.Syntax, "arguments"),
-						Value: kvp.Value,
-					},
-					syntax.ObjectPropertyDef{
-						Key:   syntax.StringSyntax(kvp.Syntax, "function"),
-						Value: syntax.String(fnVal),
-					},
-				)
-			} else {
-				kvp.Value = syntax.Object(
-					syntax.ObjectPropertyDef{
-						Key:   syntax.StringSyntax(kvp.Syntax, "function"),
-						Value: syntax.String(fnVal),
-					},
-				)
-			}
-			parse = parseInvoke
-			break
-		} else if strings.HasPrefix(strings.ToLower(k), "fn::") {
-			diags = append(diags, syntax.Warning(kvp.Key.Syntax().Range(),
-				"'fn::' is a reserved prefix",
-				fmt.Sprintf("If you need to use the raw key '%s',"+
-					" please open an issue at https://github.com/pulumi/pulumi-yaml/issues", k)))
+	for _, entry := range obj.Entries {
+		k, ok := entry.Key.(*StringExpr)
+		if !ok {
+			diags.Extend(ExprError(entry.Key, "fn::readFile only accepts literal keys", ""))
+			continue
+		}
+		switch k.Value {
+		case "path":
+			path = entry.Value
+		case "maxBytes":
+			maxBytes = entry.Value
+		default:
+			diags.Extend(ExprError(k, fmt.Sprintf("fn::readFile has no argument named %q", k.Value),
+				"Valid arguments are 'path' and 'maxBytes'"))
 		}
-		return nil, diags, false
+	}
+	if path == nil {
+		diags.Extend(ExprError(obj, "missing required argument 'path' to fn::readFile", ""))
+	}
+	if diags.HasErrors() {
+		return nil, diags
 	}
 
-	name := StringSyntax(kvp.Key)
+	expr := &ReadFileExpr{
+		builtinNode: builtinNode{exprNode: expr(node), name: name, args: obj},
+		Path:        path,
+		MaxBytes:    maxBytes,
+	}
+	return expr, diags
+}
 
-	args, adiags := ParseExpr(kvp.Value)
-	diags.Extend(adiags...)
+// GetEnvExpr reads the environment variable named by Variable. Default, if set, is used when the variable is unset. It can
+// only be given in the object form of fn::getEnv.
+type GetEnvExpr struct {
+	builtinNode
+	Variable Expr
+	Default  Expr
+}
 
-	expr, xdiags := parse(node, name, args)
-	diags.Extend(xdiags...)
+func GetEnvSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr, variable, def Expr) *GetEnvExpr {
+	return &GetEnvExpr{
+		builtinNode: builtin(node, name, args),
+		Variable:    variable,
+		Default:     def,
+	}
+}
+
+func parseGetEnv(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	obj, ok := args.(*ObjectExpr)
+	if !ok {
+		return GetEnvSyntax(node, name, args, args, nil), nil
+	}
+
+	var variable, def Expr
+	var diags syntax.Diagnostics
+	for _, entry := range obj.Entries {
+		k, ok := entry.Key.(*StringExpr)
+		if !ok {
+			diags.Extend(ExprError(entry.Key, "fn::getEnv only accepts literal keys", ""))
+			continue
+		}
+		switch k.Value {
+		case "name":
+			variable = entry.Value
+		case "default":
+			def = entry.Value
+		default:
+			diags.Extend(ExprError(k, fmt.Sprintf("fn::getEnv has no argument named %q", k.Value),
+				"Valid arguments are 'name' and 'default'"))
+		}
+	}
+	if variable == nil {
+		diags.Extend(ExprError(obj, "missing required argument 'name' to fn::getEnv", ""))
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return GetEnvSyntax(node, name, obj, variable, def), diags
+}
+
+// UUIDExpr generates a random (version 4) UUID.
+type UUIDExpr struct {
+	builtinNode
+}
+
+func UUIDSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *UUIDExpr {
+	return &UUIDExpr{builtinNode: builtin(node, name, args)}
+}
+
+func parseUUID(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	if diags := noArguments(name, args); diags.HasErrors() {
+		return nil, diags
+	}
+	return UUIDSyntax(node, name, args), nil
+}
+
+// TimestampExpr returns the current time in RFC 3339 format.
+type TimestampExpr struct {
+	builtinNode
+}
+
+func TimestampSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr) *TimestampExpr {
+	return &TimestampExpr{builtinNode: builtin(node, name, args)}
+}
+
+func parseTimestamp(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	if diags := noArguments(name, args); diags.HasErrors() {
+		return nil, diags
+	}
+	return TimestampSyntax(node, name, args), nil
+}
+
+// noArguments checks the arguments of a builtin that takes none, which are written as null or an empty object.
+func noArguments(name *StringExpr, args Expr) syntax.Diagnostics {
+	switch args := args.(type) {
+	case nil, *NullExpr:
+		return nil
+	case *ObjectExpr:
+		if len(args.Entries) == 0 {
+			return nil
+		}
+	}
+	return syntax.Diagnostics{ExprError(args, fmt.Sprintf("%s takes no arguments", name.Value),
+		fmt.Sprintf("Write it as '%s: {}'", name.Value))}
+}
+
+// ReadDirExpr lists the names of the files in the directory at Path.
+type ReadDirExpr struct {
+	builtinNode
+	Path Expr
+}
+
+func ReadDirSyntax(node *syntax.ObjectNode, name *StringExpr, path Expr) *ReadDirExpr {
+	return &ReadDirExpr{
+		builtinNode: builtin(node, name, path),
+		Path:        path,
+	}
+}
+
+func parseReadDir(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return ReadDirSyntax(node, name, args), nil
+}
+
+// BasenameExpr returns the last element of a path.
+type BasenameExpr struct {
+	builtinNode
+	Path Expr
+}
+
+func BasenameSyntax(node *syntax.ObjectNode, name *StringExpr, path Expr) *BasenameExpr {
+	return &BasenameExpr{
+		builtinNode: builtin(node, name, path),
+		Path:        path,
+	}
+}
+
+func parseBasename(node *syntax.ObjectNode, name *StringExpr, path Expr) (Expr, syntax.Diagnostics) {
+	return BasenameSyntax(node, name, path), nil
+}
+
+// DirnameExpr returns all but the last element of a path.
+type DirnameExpr struct {
+	builtinNode
+	Path Expr
+}
+
+func DirnameSyntax(node *syntax.ObjectNode, name *StringExpr, path Expr) *DirnameExpr {
+	return &DirnameExpr{
+		builtinNode: builtin(node, name, path),
+		Path:        path,
+	}
+}
+
+func parseDirname(node *syntax.ObjectNode, name *StringExpr, path Expr) (Expr, syntax.Diagnostics) {
+	return DirnameSyntax(node, name, path), nil
+}
+
+// FileExtensionExpr returns the extension of a path, including the leading dot.
+type FileExtensionExpr struct {
+	builtinNode
+	Path Expr
+}
+
+func FileExtensionSyntax(node *syntax.ObjectNode, name *StringExpr, path Expr) *FileExtensionExpr {
+	return &FileExtensionExpr{
+		builtinNode: builtin(node, name, path),
+		Path:        path,
+	}
+}
+
+func parseFileExtension(node *syntax.ObjectNode, name *StringExpr, path Expr) (Expr, syntax.Diagnostics) {
+	return FileExtensionSyntax(node, name, path), nil
+}
+
+// DefaultsExpr fills in the keys missing from a target map with the values from a map of defaults. Keys present in
+// the target always take precedence.
+type DefaultsExpr struct {
+	builtinNode
+
+	Target   Expr
+	Defaults Expr
+}
+
+func DefaultsSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, target, defaults Expr) *DefaultsExpr {
+	return &DefaultsExpr{
+		builtinNode: builtin(node, name, args),
+		Target:      target,
+		Defaults:    defaults,
+	}
+}
+
+func parseDefaults(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::defaults must be a two-valued list", "")}
+	}
+
+	return DefaultsSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// CoalesceListExpr returns the first of its arguments that is a non-empty list, or an empty list if there is none.
+// Null arguments are treated as empty lists.
+type CoalesceListExpr struct {
+	builtinNode
+
+	Values *ListExpr
+}
+
+func CoalesceListSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr) *CoalesceListExpr {
+	return &CoalesceListExpr{
+		builtinNode: builtin(node, name, args),
+		Values:      args,
+	}
+}
+
+func parseCoalesceList(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) == 0 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::coalesceList must be a non-empty list", "")}
+	}
+
+	return CoalesceListSyntax(node, name, list), nil
+}
+
+// CoalesceEmptyStringExpr returns the first of its arguments that is neither null nor the empty string, or the empty
+// string if there is none.
+type CoalesceEmptyStringExpr struct {
+	builtinNode
+
+	Values *ListExpr
+}
+
+func CoalesceEmptyStringSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr) *CoalesceEmptyStringExpr {
+	return &CoalesceEmptyStringExpr{
+		builtinNode: builtin(node, name, args),
+		Values:      args,
+	}
+}
+
+func parseCoalesceEmptyString(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) == 0 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::coalesceEmptyString must be a non-empty list", "")}
+	}
+
+	return CoalesceEmptyStringSyntax(node, name, list), nil
+}
+
+// MergeExpr merges a list of objects into a single object. Later objects take precedence, and nested objects are
+// merged recursively.
+type MergeExpr struct {
+	builtinNode
+
+	Values *ListExpr
+}
+
+func MergeSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr) *MergeExpr {
+	return &MergeExpr{
+		builtinNode: builtin(node, name, args),
+		Values:      args,
+	}
+}
+
+func parseMerge(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::merge must be a list of objects", "")}
+	}
+
+	return MergeSyntax(node, name, list), nil
+}
+
+// TextTemplateExpr renders a Go text/template with a map of data.
+type TextTemplateExpr struct {
+	builtinNode
+
+	Template Expr
+	Data     Expr
+}
+
+func TextTemplateSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, template, data Expr) *TextTemplateExpr {
+	return &TextTemplateExpr{
+		builtinNode: builtin(node, name, args),
+		Template:    template,
+		Data:        data,
+	}
+}
+
+func parseTextTemplate(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::template must be a two-valued list", "")}
+	}
+
+	return TextTemplateSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// ReplaceExpr replaces every non-overlapping occurrence of Old in Source with New.
+type ReplaceExpr struct {
+	builtinNode
+
+	Source Expr
+	Old    Expr
+	New    Expr
+}
+
+func ReplaceSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, source, old, new Expr) *ReplaceExpr {
+	return &ReplaceExpr{
+		builtinNode: builtin(node, name, args),
+		Source:      source,
+		Old:         old,
+		New:         new,
+	}
+}
+
+func parseReplace(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 3 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::replace must be a three-valued list", "")}
+	}
+
+	return ReplaceSyntax(node, name, list, list.Elements[0], list.Elements[1], list.Elements[2]), nil
+}
+
+// CidrSubnetExpr computes the address of a subnet within Prefix, which is extended by Newbits bits. Netnum selects
+// which of the resulting subnets is returned.
+type CidrSubnetExpr struct {
+	builtinNode
+
+	Prefix  Expr
+	Newbits Expr
+	Netnum  Expr
+}
+
+func CidrSubnetSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, prefix, newbits, netnum Expr) *CidrSubnetExpr {
+	return &CidrSubnetExpr{
+		builtinNode: builtin(node, name, args),
+		Prefix:      prefix,
+		Newbits:     newbits,
+		Netnum:      netnum,
+	}
+}
+
+func parseCidrSubnet(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 3 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::cidrSubnet must be a three-valued list",
+			"The values are the prefix, the number of bits to extend it by, and the number of the subnet")}
+	}
+
+	return CidrSubnetSyntax(node, name, list, list.Elements[0], list.Elements[1], list.Elements[2]), nil
+}
+
+// CidrHostExpr computes the address of host number Hostnum within Prefix.
+type CidrHostExpr struct {
+	builtinNode
+
+	Prefix  Expr
+	Hostnum Expr
+}
+
+func CidrHostSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, prefix, hostnum Expr) *CidrHostExpr {
+	return &CidrHostExpr{
+		builtinNode: builtin(node, name, args),
+		Prefix:      prefix,
+		Hostnum:     hostnum,
+	}
+}
+
+func parseCidrHost(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::cidrHost must be a two-valued list",
+			"The values are the prefix and the number of the host")}
+	}
+
+	return CidrHostSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// RangeExpr generates the list of numbers from Start up to, but not including, End. Step is the difference between
+// consecutive numbers, and is nil when it is not given.
+type RangeExpr struct {
+	builtinNode
+
+	Start Expr
+	End   Expr
+	Step  Expr
+}
+
+func RangeSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, start, end, step Expr) *RangeExpr {
+	return &RangeExpr{
+		builtinNode: builtin(node, name, args),
+		Start:       start,
+		End:         end,
+		Step:        step,
+	}
+}
+
+func parseRange(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) < 2 || len(list.Elements) > 3 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::range must be a two- or three-valued list",
+			"The values are the start of the range, the end of the range and an optional step")}
+	}
+
+	var step Expr
+	if len(list.Elements) == 3 {
+		step = list.Elements[2]
+	}
+	return RangeSyntax(node, name, list, list.Elements[0], list.Elements[1], step), nil
+}
+
+// TrimExpr removes leading and trailing whitespace from a string.
+type TrimExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func TrimSyntax(node *syntax.ObjectNode, name *StringExpr, value Expr) *TrimExpr {
+	return &TrimExpr{
+		builtinNode: builtin(node, name, value),
+		Value:       value,
+	}
+}
+
+func parseTrim(node *syntax.ObjectNode, name *StringExpr, value Expr) (Expr, syntax.Diagnostics) {
+	return TrimSyntax(node, name, value), nil
+}
+
+// TrimPrefixExpr removes a leading Prefix from Source. Source is returned unchanged if it does not start with Prefix.
+type TrimPrefixExpr struct {
+	builtinNode
+
+	Source Expr
+	Prefix Expr
+}
+
+func TrimPrefixSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, source, prefix Expr) *TrimPrefixExpr {
+	return &TrimPrefixExpr{
+		builtinNode: builtin(node, name, args),
+		Source:      source,
+		Prefix:      prefix,
+	}
+}
+
+func parseTrimPrefix(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::trimPrefix must be a two-valued list", "")}
+	}
+
+	return TrimPrefixSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// TrimSuffixExpr removes a trailing Suffix from Source. Source is returned unchanged if it does not end with Suffix.
+type TrimSuffixExpr struct {
+	builtinNode
+
+	Source Expr
+	Suffix Expr
+}
+
+func TrimSuffixSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, source, suffix Expr) *TrimSuffixExpr {
+	return &TrimSuffixExpr{
+		builtinNode: builtin(node, name, args),
+		Source:      source,
+		Suffix:      suffix,
+	}
+}
+
+func parseTrimSuffix(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::trimSuffix must be a two-valued list", "")}
+	}
+
+	return TrimSuffixSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// UpperExpr converts a string to upper case.
+type UpperExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func UpperSyntax(node *syntax.ObjectNode, name *StringExpr, value Expr) *UpperExpr {
+	return &UpperExpr{
+		builtinNode: builtin(node, name, value),
+		Value:       value,
+	}
+}
+
+func parseUpper(node *syntax.ObjectNode, name *StringExpr, value Expr) (Expr, syntax.Diagnostics) {
+	return UpperSyntax(node, name, value), nil
+}
+
+// LowerExpr converts a string to lower case.
+type LowerExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func LowerSyntax(node *syntax.ObjectNode, name *StringExpr, value Expr) *LowerExpr {
+	return &LowerExpr{
+		builtinNode: builtin(node, name, value),
+		Value:       value,
+	}
+}
+
+func parseLower(node *syntax.ObjectNode, name *StringExpr, value Expr) (Expr, syntax.Diagnostics) {
+	return LowerSyntax(node, name, value), nil
+}
+
+// FormatExpr formats Values according to a Go-style format string, as fmt.Sprintf does.
+type FormatExpr struct {
+	builtinNode
+
+	Format Expr
+	Values []Expr
+}
+
+func FormatSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, format Expr, values []Expr) *FormatExpr {
+	return &FormatExpr{
+		builtinNode: builtin(node, name, args),
+		Format:      format,
+		Values:      values,
+	}
+}
+
+func parseFormat(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) == 0 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::format must be a list starting with a format string", "")}
+	}
+
+	return FormatSyntax(node, name, list, list.Elements[0], list.Elements[1:]), nil
+}
+
+// FormatNumberExpr formats the number Value as a string according to Format, a spec such as ",.2".
+type FormatNumberExpr struct {
+	builtinNode
+
+	Value  Expr
+	Format Expr
+}
+
+func FormatNumberSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, value, format Expr) *FormatNumberExpr {
+	return &FormatNumberExpr{
+		builtinNode: builtin(node, name, args),
+		Value:       value,
+		Format:      format,
+	}
+}
+
+func parseFormatNumber(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::formatNumber must be a two-valued list", "")}
+	}
+
+	return FormatNumberSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// JSONPathExpr selects the values in Value matched by a JSONPath expression.
+type JSONPathExpr struct {
+	builtinNode
+
+	Value Expr
+	Path  Expr
+}
+
+func JSONPathSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, value, path Expr) *JSONPathExpr {
+	return &JSONPathExpr{
+		builtinNode: builtin(node, name, args),
+		Value:       value,
+		Path:        path,
+	}
+}
+
+func parseJSONPath(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::jsonPath must be a two-valued list", "")}
+	}
+
+	return JSONPathSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// IfExpr evaluates to Then if Condition is true, and to Else otherwise.
+type IfExpr struct {
+	builtinNode
+
+	Condition Expr
+	Then      Expr
+	Else      Expr
+}
+
+func IfSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, condition, then, els Expr) *IfExpr {
+	return &IfExpr{
+		builtinNode: builtin(node, name, args),
+		Condition:   condition,
+		Then:        then,
+		Else:        els,
+	}
+}
+
+func parseIf(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 3 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::if must be a three-valued list", "")}
+	}
+
+	return IfSyntax(node, name, list, list.Elements[0], list.Elements[1], list.Elements[2]), nil
+}
+
+// EqualsExpr evaluates to true if Left and Right are deeply equal.
+type EqualsExpr struct {
+	builtinNode
+
+	Left  Expr
+	Right Expr
+}
+
+func EqualsSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, left, right Expr) *EqualsExpr {
+	return &EqualsExpr{
+		builtinNode: builtin(node, name, args),
+		Left:        left,
+		Right:       right,
+	}
+}
+
+func parseEquals(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::equals must be a two-valued list", "")}
+	}
+
+	return EqualsSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// ContainsExpr evaluates to true if Collection contains Value. A list contains the values deeply equal to one of its
+// elements, and an object contains its keys.
+type ContainsExpr struct {
+	builtinNode
+
+	Collection Expr
+	Value      Expr
+}
+
+func ContainsSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr, collection, value Expr) *ContainsExpr {
+	return &ContainsExpr{
+		builtinNode: builtin(node, name, args),
+		Collection:  collection,
+		Value:       value,
+	}
+}
+
+func parseContains(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::contains must be a two-valued list",
+			"The values are the list or object to search and the value to search for")}
+	}
+
+	return ContainsSyntax(node, name, list, list.Elements[0], list.Elements[1]), nil
+}
+
+// NotExpr negates a boolean.
+type NotExpr struct {
+	builtinNode
+
+	Value Expr
+}
+
+func NotSyntax(node *syntax.ObjectNode, name *StringExpr, value Expr) *NotExpr {
+	return &NotExpr{
+		builtinNode: builtin(node, name, value),
+		Value:       value,
+	}
+}
+
+func parseNot(node *syntax.ObjectNode, name *StringExpr, value Expr) (Expr, syntax.Diagnostics) {
+	return NotSyntax(node, name, value), nil
+}
+
+// AndExpr evaluates to true if all of its values are true.
+type AndExpr struct {
+	builtinNode
+
+	Values *ListExpr
+}
+
+func AndSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr) *AndExpr {
+	return &AndExpr{
+		builtinNode: builtin(node, name, args),
+		Values:      args,
+	}
+}
+
+func parseAnd(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::and must be a list of booleans", "")}
+	}
+
+	return AndSyntax(node, name, list), nil
+}
+
+// OrExpr evaluates to true if any of its values are true.
+type OrExpr struct {
+	builtinNode
+
+	Values *ListExpr
+}
+
+func OrSyntax(node *syntax.ObjectNode, name *StringExpr, args *ListExpr) *OrExpr {
+	return &OrExpr{
+		builtinNode: builtin(node, name, args),
+		Values:      args,
+	}
+}
+
+func parseOr(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::or must be a list of booleans", "")}
+	}
+
+	return OrSyntax(node, name, list), nil
+}
+
+// OutputExpr declares an output that is only exported when Condition is true. It may only be used as the value of an
+// output.
+type OutputExpr struct {
+	builtinNode
+
+	Value     Expr
+	Condition Expr
+}
+
+func OutputSyntax(node *syntax.ObjectNode, name *StringExpr, args Expr, value, condition Expr) *OutputExpr {
+	return &OutputExpr{
+		builtinNode: builtin(node, name, args),
+		Value:       value,
+		Condition:   condition,
+	}
+}
+
+func parseOutput(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	obj, ok := args.(*ObjectExpr)
+	if !ok {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::output must be an object containing 'value' and 'condition'", "")}
+	}
+
+	var value, condition Expr
+	var diags syntax.Diagnostics
+	for _, entry := range obj.Entries {
+		k, ok := entry.Key.(*StringExpr)
+		if !ok {
+			diags.Extend(ExprError(entry.Key, "fn::output only accepts literal keys", ""))
+			continue
+		}
+		switch k.Value {
+		case "value":
+			value = entry.Value
+		case "condition":
+			condition = entry.Value
+		default:
+			diags.Extend(ExprError(k, fmt.Sprintf("fn::output has no argument named %q", k.Value),
+				"Valid arguments are 'value' and 'condition'"))
+		}
+	}
+	if value == nil {
+		diags.Extend(ExprError(obj, "missing required argument 'value' to fn::output", ""))
+	}
+	if condition == nil {
+		diags.Extend(ExprError(obj, "missing required argument 'condition' to fn::output", ""))
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return OutputSyntax(node, name, obj, value, condition), diags
+}
+
+type builtinParser func(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics)
+
+func tryParseFunction(node *syntax.ObjectNode) (Expr, syntax.Diagnostics, bool) {
+	if node.Len() != 1 {
+		return nil, nil, false
+	}
+
+	kvp := node.Index(0)
+
+	if _, _, ok := getAssetOrArchive(StringSyntax(kvp.Key)); ok {
+		// We will parse this node as an asset or archive later, so we don't need to do it now
+		return nil, nil, false
+	}
+
+	parse, diags, ok := lookupBuiltin(&kvp)
+	if !ok {
+		return nil, diags, false
+	}
+
+	name := StringSyntax(kvp.Key)
+
+	value, then := kvp.Value, syntax.Node(nil)
+	if strings.EqualFold(kvp.Key.Value(), "fn::invoke") {
+		value, then = splitInvokeThen(value)
+	}
+
+	args, adiags := ParseExpr(value)
+	diags.Extend(adiags...)
+
+	expr, xdiags := parse(node, name, args)
+	diags.Extend(xdiags...)
 
 	if expr == nil {
 		expr = ObjectSyntax(node, ObjectProperty{
@@ -834,11 +1909,269 @@ func tryParseFunction(node *syntax.ObjectNode) (Expr, syntax.Diagnostics, bool)
 			Key:    name,
 			Value:  args,
 		})
+	} else if then != nil {
+		result, tdiags := parseInvokeThen(expr, then)
+		diags.Extend(tdiags...)
+		if result != nil {
+			expr = result
+		}
 	}
 
 	return expr, diags, true
 }
 
+// lookupBuiltin returns the parser for the builtin function named by the key of kvp. The value of kvp may be
+// rewritten into the canonical form expected by the parser.
+func lookupBuiltin(kvp *syntax.ObjectPropertyDef) (builtinParser, syntax.Diagnostics, bool) {
+	var parse builtinParser
+	var diags syntax.Diagnostics
+	set := func(expected string, parseFn builtinParser) {
+		diags.Extend(syntax.UnexpectedCasing(kvp.Key.Syntax().Range(), expected, kvp.Key.Value()))
+		parse = parseFn
+	}
+	switch strings.ToLower(kvp.Key.Value()) {
+	case "fn::invoke":
+		set("fn::invoke", parseInvoke)
+	case "fn::join":
+		set("fn::join", parseJoin)
+	case "fn::tojson":
+		set("fn::toJSON", parseToJSON)
+	case "fn::fromjson":
+		set("fn::fromJSON", parseFromJSON)
+	case "fn::toyaml":
+		set("fn::toYAML", parseToYAML)
+	case "fn::keys":
+		set("fn::keys", parseKeys)
+	case "fn::values":
+		set("fn::values", parseValues)
+	case "fn::sort":
+		set("fn::sort", parseSort)
+	case "fn::unique":
+		set("fn::unique", parseUnique)
+	case "fn::tostring":
+		set("fn::toString", parseToString)
+	case "fn::tonumber":
+		set("fn::toNumber", parseToNumber)
+	case "fn::tobase64":
+		set("fn::toBase64", parseToBase64)
+	case "fn::frombase64":
+		set("fn::fromBase64", parseFromBase64)
+	case "fn::sha256":
+		set("fn::sha256", parseSha256)
+	case "fn::sha1":
+		set("fn::sha1", parseSha1)
+	case "fn::select":
+		set("fn::select", parseSelect)
+	case "fn::slice":
+		set("fn::slice", parseSlice)
+	case "fn::split":
+		set("fn::split", parseSplit)
+	case "fn::splitregex":
+		set("fn::splitRegex", parseSplitRegex)
+	case "fn::stackreference":
+		set("fn::stackReference", parseStackReference)
+		diags = append(diags, syntax.Warning(kvp.Key.Syntax().Range(),
+			`'fn::stackReference' is deprecated; please use 'pulumi:pulumi:StackReference' instead`,
+			`see "https://www.pulumi.com/docs/intro/concepts/stack/#stackreferences for more info.`))
+	case "fn::assetarchive":
+		set("fn::assetArchive", parseAssetArchive)
+	case "fn::secret":
+		set("fn::secret", parseSecret)
+	case "fn::unsecret":
+		set("fn::unsecret", parseUnsecret)
+	case "fn::readfile":
+		set("fn::readFile", parseReadFile)
+	case "fn::readdir":
+		set("fn::readDir", parseReadDir)
+	case "fn::getenv":
+		set("fn::getEnv", parseGetEnv)
+	case "fn::uuid":
+		set("fn::uuid", parseUUID)
+	case "fn::timestamp":
+		set("fn::timestamp", parseTimestamp)
+	case "fn::basename":
+		set("fn::basename", parseBasename)
+	case "fn::dirname":
+		set("fn::dirname", parseDirname)
+	case "fn::fileextension":
+		set("fn::fileExtension", parseFileExtension)
+	case "fn::defaults":
+		set("fn::defaults", parseDefaults)
+	case "fn::coalescelist":
+		set("fn::coalesceList", parseCoalesceList)
+	case "fn::coalesceemptystring":
+		set("fn::coalesceEmptyString", parseCoalesceEmptyString)
+	case "fn::merge":
+		set("fn::merge", parseMerge)
+	case "fn::template":
+		set("fn::template", parseTextTemplate)
+	case "fn::replace":
+		set("fn::replace", parseReplace)
+	case "fn::cidrsubnet":
+		set("fn::cidrSubnet", parseCidrSubnet)
+	case "fn::cidrhost":
+		set("fn::cidrHost", parseCidrHost)
+	case "fn::range":
+		set("fn::range", parseRange)
+	case "fn::trim":
+		set("fn::trim", parseTrim)
+	case "fn::trimprefix":
+		set("fn::trimPrefix", parseTrimPrefix)
+	case "fn::trimsuffix":
+		set("fn::trimSuffix", parseTrimSuffix)
+	case "fn::upper":
+		set("fn::upper", parseUpper)
+	case "fn::lower":
+		set("fn::lower", parseLower)
+	case "fn::format":
+		set("fn::format", parseFormat)
+	case "fn::formatnumber":
+		set("fn::formatNumber", parseFormatNumber)
+	case "fn::jsonpath":
+		set("fn::jsonPath", parseJSONPath)
+	case "fn::if":
+		set("fn::if", parseIf)
+	case "fn::equals":
+		set("fn::equals", parseEquals)
+	case "fn::contains":
+		set("fn::contains", parseContains)
+	case "fn::not":
+		set("fn::not", parseNot)
+	case "fn::and":
+		set("fn::and", parseAnd)
+	case "fn::or":
+		set("fn::or", parseOr)
+	case "fn::output":
+		set("fn::output", parseOutput)
+	default:
+		k := kvp.Key.Value()
+		// fn::invoke can be called as fn::${pkg}:${module}(:${name})?
+		// error is thrown if regex pattern cannot be parsed — handled by `regex.MustCompile(fnInvokeRegex)`
+		if fnInvokeRegex.MatchString(strings.ToLower(k)) {
+			// transform the node into standard fn::invoke format
+			fnVal := k[4:]
+			if _, ok := kvp.Value.(*syntax.ObjectNode); ok {
+				kvp.Value = syntax.Object(
+					syntax.ObjectPropertyDef{
+						Key:   syntax.StringSyntax(kvp.Syntax, "arguments"),
+						Value: kvp.Value,
+					},
+					syntax.ObjectPropertyDef{
+						Key:   syntax.StringSyntax(kvp.Syntax, "function"),
+						Value: syntax.String(fnVal),
+					},
+				)
+			} else {
+				kvp.Value = syntax.Object(
+					syntax.ObjectPropertyDef{
+						Key:   syntax.StringSyntax(kvp.Syntax, "function"),
+						Value: syntax.String(fnVal),
+					},
+				)
+			}
+			parse = parseInvoke
+			break
+		} else if strings.HasPrefix(strings.ToLower(k), "fn::") {
+			diags = append(diags, syntax.Warning(kvp.Key.Syntax().Range(),
+				"'fn::' is a reserved prefix",
+				fmt.Sprintf("If you need to use the raw key '%s',"+
+					" please open an issue at https://github.com/pulumi/pulumi-yaml/issues", k)))
+		}
+		return nil, diags, false
+	}
+
+	return parse, diags, true
+}
+
+// splitInvokeThen removes the `then` directive from the arguments of fn::invoke, returning the remaining
+// arguments and the directive's value.
+func splitInvokeThen(node syntax.Node) (syntax.Node, syntax.Node) {
+	obj, ok := node.(*syntax.ObjectNode)
+	if !ok {
+		return node, nil
+	}
+	var then syntax.Node
+	entries := make([]syntax.ObjectPropertyDef, 0, obj.Len())
+	for i := 0; i < obj.Len(); i++ {
+		kvp := obj.Index(i)
+		if strings.EqualFold(kvp.Key.Value(), "then") {
+			then = kvp.Value
+			continue
+		}
+		entries = append(entries, kvp)
+	}
+	if then == nil {
+		return node, nil
+	}
+	return syntax.ObjectSyntax(obj.Syntax(), entries...), then
+}
+
+// parseInvokeThen applies the `then` steps of fn::invoke to its result. Each step is a builtin function whose
+// arguments receive the result of the previous step: an empty argument is replaced by it, and it is appended
+// to a list of arguments. An empty argument is either null or `{}`; `{}` is always this placeholder, never an
+// empty object literal, so `fn::toJSON: {}` encodes the previous result.
+//
+//	fn::invoke:
+//	  function: test:invoke:type
+//	  return: items
+//	  then:
+//	    - fn::select: [0]
+//	    - fn::toJSON: {}
+func parseInvokeThen(result Expr, then syntax.Node) (Expr, syntax.Diagnostics) {
+	const mustBuiltinMsg = "each step of the 'then' directive must be a builtin function"
+
+	steps, ok := then.(*syntax.ListNode)
+	if !ok {
+		return nil, syntax.Diagnostics{syntax.NodeError(then, "the 'then' directive must be a list of builtin functions", "")}
+	}
+
+	var diags syntax.Diagnostics
+	for i := 0; i < steps.Len(); i++ {
+		step, ok := steps.Index(i).(*syntax.ObjectNode)
+		if !ok || step.Len() != 1 {
+			diags.Extend(syntax.NodeError(steps.Index(i), mustBuiltinMsg, ""))
+			return nil, diags
+		}
+		kvp := step.Index(0)
+		parse, pdiags, ok := lookupBuiltin(&kvp)
+		diags.Extend(pdiags...)
+		if !ok {
+			diags.Extend(syntax.NodeError(kvp.Key, mustBuiltinMsg, ""))
+			return nil, diags
+		}
+
+		args, adiags := ParseExpr(kvp.Value)
+		diags.Extend(adiags...)
+		if adiags.HasErrors() {
+			return nil, diags
+		}
+		switch a := args.(type) {
+		case *NullExpr:
+			args = result
+		case *ObjectExpr:
+			if len(a.Entries) != 0 {
+				diags.Extend(ExprError(args, "the arguments of a 'then' step must be a list or empty", ""))
+				return nil, diags
+			}
+			args = result
+		case *ListExpr:
+			elements := append(append([]Expr{}, a.Elements...), result)
+			args = ListSyntax(a.Syntax().(*syntax.ListNode), elements...)
+		default:
+			diags.Extend(ExprError(args, "the arguments of a 'then' step must be a list or empty", ""))
+			return nil, diags
+		}
+
+		expr, xdiags := parse(step, StringSyntax(kvp.Key), args)
+		diags.Extend(xdiags...)
+		if expr == nil || xdiags.HasErrors() {
+			return nil, diags
+		}
+		result = expr
+	}
+	return result, diags
+}
+
 func parseInvoke(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
 	obj, ok := args.(*ObjectExpr)
 	if !ok {
@@ -848,6 +2181,7 @@ func parseInvoke(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, sy
 	var functionExpr, argumentsExpr, returnExpr Expr
 	var diags syntax.Diagnostics
 	opts := InvokeOptionsDecl{}
+	var collect *InvokeCollectDecl
 
 	for i := 0; i < len(obj.Entries); i++ {
 		kvp := obj.Entries[i]
@@ -868,6 +2202,17 @@ func parseInvoke(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, sy
 			case "return":
 				diags.Extend(syntax.UnexpectedCasing(str.syntax.Syntax().Range(), "return", str.GetValue()))
 				returnExpr = kvp.Value
+			case "collect":
+				diags.Extend(syntax.UnexpectedCasing(str.syntax.Syntax().Range(), "collect", str.GetValue()))
+				collect = &InvokeCollectDecl{}
+				diags.Extend(parseRecord("collect", collect, kvp.syntax.Value, true)...)
+				if diags.HasErrors() {
+					return nil, diags
+				}
+				if collect.Items == nil || collect.Token == nil {
+					diags.Extend(ExprError(kvp.Value, "collect must name the output holding the items ('items') "+
+						"and the output holding the continuation token ('token')", ""))
+				}
 			}
 		}
 	}
@@ -882,8 +2227,10 @@ func parseInvoke(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, sy
 	}
 
 	arguments, ok := argumentsExpr.(*ObjectExpr)
-	if !ok && argumentsExpr != nil {
-		diags.Extend(ExprError(argumentsExpr, "function arguments ('arguments') must be an object", ""))
+	positional, isList := argumentsExpr.(*ListExpr)
+	symbol, isSymbol := argumentsExpr.(*SymbolExpr)
+	if !ok && !isList && !isSymbol && argumentsExpr != nil {
+		diags.Extend(ExprError(argumentsExpr, "function arguments ('arguments') must be an object, a list or a reference", ""))
 	}
 
 	ret, ok := returnExpr.(*StringExpr)
@@ -891,11 +2238,20 @@ func parseInvoke(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, sy
 		diags.Extend(ExprError(returnExpr, "return directive must be a string literal", ""))
 	}
 
+	if collect != nil && returnExpr != nil {
+		diags.Extend(ExprError(returnExpr, "the return directive cannot be used with collect",
+			"A collecting invoke returns the list of items from every page of results"))
+	}
+
 	if diags.HasErrors() {
 		return nil, diags
 	}
 
-	return InvokeSyntax(node, name, obj, function, arguments, opts, ret), diags
+	invoke := InvokeSyntax(node, name, obj, function, arguments, opts, ret)
+	invoke.PositionalArgs = positional
+	invoke.ArgumentsExpr = symbol
+	invoke.Collect = collect
+	return invoke, diags
 }
 
 func parseJoin(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
@@ -911,15 +2267,42 @@ func parseToJSON(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, sy
 	return ToJSONSyntax(node, name, args), nil
 }
 
+func parseToYAML(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return ToYAMLSyntax(node, name, args), nil
+}
+
+func parseFromJSON(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return FromJSONSyntax(node, name, args), nil
+}
+
 func parseSelect(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
 	list, ok := args.(*ListExpr)
-	if !ok || len(list.Elements) != 2 {
-		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::select must be a two-valued list", "")}
+	if !ok || (len(list.Elements) != 2 && len(list.Elements) != 3) {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::select must be a two- or three-valued list",
+			"A third value may be given as the default when the index is out of range")}
 	}
 
 	index := list.Elements[0]
 	values := list.Elements[1]
-	return SelectSyntax(node, name, list, index, values), nil
+	expr := SelectSyntax(node, name, list, index, values)
+	if len(list.Elements) == 3 {
+		expr.Default = list.Elements[2]
+	}
+	return expr, nil
+}
+
+func parseSlice(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || (len(list.Elements) != 2 && len(list.Elements) != 3) {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::slice must be a two- or three-valued list",
+			"A third value may be given as the end index of the slice")}
+	}
+
+	expr := SliceSyntax(node, name, list, list.Elements[0], list.Elements[1])
+	if len(list.Elements) == 3 {
+		expr.End = list.Elements[2]
+	}
+	return expr, nil
 }
 
 func parseSplit(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
@@ -931,6 +2314,39 @@ func parseSplit(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syn
 	return SplitSyntax(node, name, list), nil
 }
 
+func parseSplitRegex(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	list, ok := args.(*ListExpr)
+	if !ok || len(list.Elements) != 2 {
+		return nil, syntax.Diagnostics{ExprError(args, "the argument to fn::splitRegex must be a two-valued list", "")}
+	}
+
+	expr := SplitRegexSyntax(node, name, list, list.Elements[0], list.Elements[1])
+	if pattern, ok := expr.Pattern.(*StringExpr); ok {
+		re, err := regexp.Compile(pattern.Value)
+		if err != nil {
+			return nil, syntax.Diagnostics{ExprError(pattern, fmt.Sprintf("invalid regular expression %q: %v", pattern.Value, err), "")}
+		}
+		expr.Regexp = re
+	}
+	return expr, nil
+}
+
+func parseKeys(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return KeysSyntax(node, name, args), nil
+}
+
+func parseValues(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return ValuesSyntax(node, name, args), nil
+}
+
+func parseToString(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return ToStringSyntax(node, name, args), nil
+}
+
+func parseToNumber(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return ToNumberSyntax(node, name, args), nil
+}
+
 func parseToBase64(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
 	return ToBase64Syntax(node, name, args), nil
 }
@@ -939,6 +2355,14 @@ func parseFromBase64(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr
 	return FromBase64Syntax(node, name, args), nil
 }
 
+func parseSha256(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return Sha256Syntax(node, name, args), nil
+}
+
+func parseSha1(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	return Sha1Syntax(node, name, args), nil
+}
+
 func parseStackReference(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
 	list, ok := args.(*ListExpr)
 	if !ok || len(list.Elements) != 2 {
@@ -957,13 +2381,61 @@ func parseSecret(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, sy
 	return SecretSyntax(node, name, args), nil
 }
 
+func parseUnsecret(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
+	const usage = "The argument to fn::unsecret must be an object with the 'value' to reveal " +
+		"and the 'reason' it is safe to do so"
+
+	obj, ok := args.(*ObjectExpr)
+	if !ok {
+		return nil, syntax.Diagnostics{ExprError(args, "fn::unsecret requires a reason", usage)}
+	}
+
+	var value Expr
+	var reason *StringExpr
+	var diags syntax.Diagnostics
+	for _, entry := range obj.Entries {
+		k, ok := entry.Key.(*StringExpr)
+		if !ok {
+			diags.Extend(ExprError(entry.Key, "fn::unsecret only accepts literal keys", ""))
+			continue
+		}
+		switch k.Value {
+		case "value":
+			value = entry.Value
+		case "reason":
+			reason, ok = entry.Value.(*StringExpr)
+			if !ok || strings.TrimSpace(reason.Value) == "" {
+				diags.Extend(ExprError(entry.Value, "the reason given to fn::unsecret must be a non-empty string literal", ""))
+			}
+		default:
+			diags.Extend(ExprError(k, fmt.Sprintf("fn::unsecret has no argument named %q", k.Value),
+				"Valid arguments are 'value' and 'reason'"))
+		}
+	}
+	if value == nil {
+		diags.Extend(ExprError(obj, "missing required argument 'value' to fn::unsecret", usage))
+	}
+	if reason == nil && !diags.HasErrors() {
+		diags.Extend(ExprError(obj, "fn::unsecret requires a reason", usage))
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &UnsecretExpr{
+		builtinNode: builtin(node, name, obj),
+		Value:       value,
+		Reason:      reason,
+	}, diags
+}
+
 // We expect the following format
 //
 //	fn::assetArchive:
 //	  path:
 //	    AssetOrArchive
 //
-// Where `AssetOrArchive` is an object.
+// Where `AssetOrArchive` is an object, or a reference to an existing archive such as `${archive}`.
 func parseAssetArchive(node *syntax.ObjectNode, name *StringExpr, args Expr) (Expr, syntax.Diagnostics) {
 	const mustObjectMsg string = "the argument to fn::assetArchive must be an object"
 	const mustStringMsg string = "keys in fn::assetArchive arguments must be string literals"
@@ -979,8 +2451,13 @@ func parseAssetArchive(node *syntax.ObjectNode, name *StringExpr, args Expr) (Ex
 		if !ok {
 			tdiags.Extend(ExprError(kv.Key, mustStringMsg, ""))
 		}
-		v, ok := kv.Value.(AssetOrArchiveExpr)
-		if !ok {
+		var v Expr
+		switch value := kv.Value.(type) {
+		case AssetOrArchiveExpr:
+			v = value
+		case *SymbolExpr:
+			v = value
+		default:
 			tdiags.Extend(ExprError(kv.Value, fmt.Sprintf("value must be an asset or an archive, not a %T", kv.Value), ""))
 		}
 		if !tdiags.HasErrors() {
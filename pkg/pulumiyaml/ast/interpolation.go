@@ -3,6 +3,7 @@
 package ast
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/pulumi/pulumi-yaml/pkg/pulumiyaml/syntax"
@@ -11,10 +12,14 @@ import (
 type Interpolation struct {
 	Text  string
 	Value *PropertyAccess
+
+	// Default, if set, is substituted for Value when it is null, as in `${name:-default}`.
+	Default *string
 }
 
 func parseInterpolate(node syntax.Node, value string) ([]Interpolation, syntax.Diagnostics) {
 	var parts []Interpolation
+	var diags syntax.Diagnostics
 	var str strings.Builder
 	for len(value) > 0 {
 		switch {
@@ -22,13 +27,42 @@ func parseInterpolate(node syntax.Node, value string) ([]Interpolation, syntax.D
 			str.WriteByte('$')
 			value = value[2:]
 		case strings.HasPrefix(value, "${"):
-			rest, access, diags := parsePropertyAccess(node, value[2:])
-			if len(diags) != 0 {
-				return nil, diags
+			rest, access, pdiags := parsePropertyAccess(node, value[2:])
+			if len(pdiags) != 0 {
+				for _, d := range pdiags {
+					if d.Detail == "" {
+						d.Detail = `To include a literal "${" in a string, escape it as "$${"`
+					}
+				}
+				return nil, pdiags
+			}
+			var def *string
+			if strings.HasPrefix(rest, ":-") {
+				end := strings.IndexByte(rest, '}')
+				if end == -1 {
+					return nil, syntax.Diagnostics{syntax.NodeError(node, "unterminated interpolation",
+						`To include a literal "${" in a string, escape it as "$${"`)}
+				}
+				text := rest[2:end]
+				if strings.Contains(text, "${") {
+					return nil, syntax.Diagnostics{syntax.NodeError(node,
+						"the default value of an interpolation cannot contain another interpolation",
+						"The default value is substituted as written")}
+				}
+				def, rest = &text, rest[end+1:]
+			}
+			if len(access.Accessors) > 0 {
+				if name, ok := access.Accessors[0].(*PropertyName); ok && looksLikeShellExpansion(name.Name) {
+					literal := value[:len(value)-len(rest)]
+					diags = append(diags, syntax.NodeWarning(node,
+						fmt.Sprintf("%q is not a valid property access", literal),
+						fmt.Sprintf("If it is meant literally, for example in a shell script, escape it as %q", "$"+literal)))
+				}
 			}
 			parts = append(parts, Interpolation{
-				Text:  str.String(),
-				Value: access,
+				Text:    str.String(),
+				Value:   access,
+				Default: def,
 			})
 			str.Reset()
 
@@ -41,5 +75,12 @@ func parseInterpolate(node syntax.Node, value string) ([]Interpolation, syntax.D
 	if str.Len() != 0 {
 		parts = append(parts, Interpolation{Text: str.String()})
 	}
-	return parts, nil
+	return parts, diags
+}
+
+// looksLikeShellExpansion reports whether name is more likely part of a shell parameter expansion, such as
+// ${VAR:=default} or ${#LIST[@]}, than the name of a value in the template. ${VAR:-default} is an interpolation with
+// a default value.
+func looksLikeShellExpansion(name string) bool {
+	return strings.ContainsAny(name, " \t#%/!@*?^,=+~") || strings.Contains(name, ":=")
 }
@@ -16,3 +16,79 @@ func TestEscapeInterpolationWorks(t *testing.T) {
 	assert.Len(t, parts, 1, "Expected one interpolation part")
 	assert.Equal(t, "Hello ${world}!", parts[0].Text)
 }
+
+func TestUnescapedShellExpansionWarns(t *testing.T) {
+	t.Parallel()
+	node := syntax.String("echo ${NAME:=world} ${USER_HOME/#~/x} ${greeting}")
+	parts, diags := parseInterpolate(node, node.Value())
+	assert.False(t, diags.HasErrors())
+	assert.Len(t, parts, 3)
+
+	var summaries []string
+	for _, d := range diags {
+		summaries = append(summaries, d.Summary+"; "+d.Detail)
+	}
+	assert.Equal(t, []string{
+		`"${NAME:=world}" is not a valid property access; ` +
+			`If it is meant literally, for example in a shell script, escape it as "$${NAME:=world}"`,
+		`"${USER_HOME/#~/x}" is not a valid property access; ` +
+			`If it is meant literally, for example in a shell script, escape it as "$${USER_HOME/#~/x}"`,
+	}, summaries)
+
+	node = syntax.String("echo ${#ITEMS[@]}")
+	_, diags = parseInterpolate(node, node.Value())
+	assert.Len(t, diags, 1)
+	assert.Equal(t, "invalid list index", diags[0].Summary)
+	assert.Equal(t, `To include a literal "${" in a string, escape it as "$${"`, diags[0].Detail)
+}
+
+func TestInterpolationDefault(t *testing.T) {
+	t.Parallel()
+	node := syntax.String("${name:-world} ${config.region:-us-west-2} ${empty:-}!")
+	parts, diags := parseInterpolate(node, node.Value())
+	assert.Empty(t, diags)
+	if assert.Len(t, parts, 4) {
+		assert.Equal(t, "name", parts[0].Value.String())
+		assert.Equal(t, "world", *parts[0].Default)
+		assert.Equal(t, "config.region", parts[1].Value.String())
+		assert.Equal(t, "us-west-2", *parts[1].Default)
+		assert.Equal(t, "", *parts[2].Default)
+		assert.Nil(t, parts[3].Default)
+		assert.Equal(t, "!", parts[3].Text)
+	}
+
+	interpolate, diags := Interpolate("${name:-world}")
+	assert.Empty(t, diags)
+	assert.Equal(t, "${name:-world}", interpolate.String())
+
+	for value, summary := range map[string]string{
+		"${name:-world":        "unterminated interpolation",
+		"${name:-${greeting}}": "the default value of an interpolation cannot contain another interpolation",
+	} {
+		node := syntax.String(value)
+		_, diags := parseInterpolate(node, node.Value())
+		if assert.Len(t, diags, 1, value) {
+			assert.Equal(t, summary, diags[0].Summary)
+		}
+	}
+}
+
+func TestInterpolationQuotedClosingBrace(t *testing.T) {
+	t.Parallel()
+	node := syntax.String(`${["weird}key"]} ${obj["a}b"].c} ${obj["say \"}\""]}`)
+	parts, diags := parseInterpolate(node, node.Value())
+	assert.Empty(t, diags)
+	if assert.Len(t, parts, 3) {
+		assert.Equal(t, []PropertyAccessor{&PropertySubscript{Index: "weird}key"}}, parts[0].Value.Accessors)
+		assert.Equal(t, []PropertyAccessor{
+			&PropertyName{Name: "obj"},
+			&PropertySubscript{Index: "a}b"},
+			&PropertyName{Name: "c"},
+		}, parts[1].Value.Accessors)
+		assert.Equal(t, " ", parts[1].Text)
+		assert.Equal(t, []PropertyAccessor{
+			&PropertyName{Name: "obj"},
+			&PropertySubscript{Index: `say "}"`},
+		}, parts[2].Value.Accessors)
+	}
+}
@@ -97,6 +97,7 @@ func (p *PropertySubscript) rootName() string {
 // - root.nested.array[0].double[1]
 // - root["key with \"escaped\" quotes"]
 // - root["key with a ."]
+// - root["key with a }"]
 // - ["root key with \"escaped\" quotes"].nested
 // - ["root key with a ."][100]
 func parsePropertyAccess(node syntax.Node, access string) (string, *PropertyAccess, syntax.Diagnostics) {
@@ -157,9 +158,16 @@ func parsePropertyAccess(node syntax.Node, access string) (string, *PropertyAcce
 				indexNode, access = int(index), access[rbracket:]
 			}
 			accessors, access = append(accessors, &PropertySubscript{Index: indexNode}), access[1:]
+		case ':':
+			if strings.HasPrefix(access, ":-") {
+				// The default value of an interpolation, which parseInterpolate reads.
+				return access, &PropertyAccess{Accessors: accessors}, nil
+			}
+			fallthrough
 		default:
 			for i := 0; ; i++ {
-				if i == len(access) || access[i] == '.' || access[i] == '[' || access[i] == '}' {
+				if i == len(access) || access[i] == '.' || access[i] == '[' || access[i] == '}' ||
+					strings.HasPrefix(access[i:], ":-") {
 					accessors, access = append(accessors, &PropertyName{Name: access[:i]}), access[i:]
 					break
 				}
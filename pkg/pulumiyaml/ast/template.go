@@ -276,6 +276,13 @@ type ConfigParamDecl struct {
 	Secret  *BooleanExpr
 	Default Expr
 	Value   Expr
+	// AllowedValues, if set, lists the only values the config value may take.
+	AllowedValues *ListExpr
+	// Minimum and Maximum, if set, bound the value of Number and Integer config.
+	Minimum *NumberExpr
+	Maximum *NumberExpr
+	// Pattern, if set, is a regular expression that the value of String config must match.
+	Pattern *StringExpr
 }
 
 func (d *ConfigParamDecl) recordSyntax() *syntax.Node {
@@ -304,7 +311,7 @@ type ResourceOptionsDecl struct {
 	AdditionalSecretOutputs *StringListDecl
 	Aliases                 *StringListDecl
 	CustomTimeouts          *CustomTimeoutsDecl
-	DeleteBeforeReplace     *BooleanExpr
+	DeleteBeforeReplace     Expr
 	DependsOn               Expr
 	IgnoreChanges           *StringListDecl
 	Import                  *StringExpr
@@ -315,7 +322,7 @@ type ResourceOptionsDecl struct {
 	Version                 *StringExpr
 	PluginDownloadURL       *StringExpr
 	ReplaceOnChanges        *StringListDecl
-	RetainOnDelete          *BooleanExpr
+	RetainOnDelete          Expr
 	DeletedWith             Expr
 }
 
@@ -329,10 +336,10 @@ func (d *ResourceOptionsDecl) recordSyntax() *syntax.Node {
 
 func ResourceOptionsSyntax(node *syntax.ObjectNode,
 	additionalSecretOutputs, aliases *StringListDecl, customTimeouts *CustomTimeoutsDecl,
-	deleteBeforeReplace *BooleanExpr, dependsOn Expr, ignoreChanges *StringListDecl, importID *StringExpr,
+	deleteBeforeReplace Expr, dependsOn Expr, ignoreChanges *StringListDecl, importID *StringExpr,
 	parent Expr, protect Expr, provider, providers Expr, version *StringExpr,
 	pluginDownloadURL *StringExpr, replaceOnChanges *StringListDecl,
-	retainOnDelete *BooleanExpr, deletedWith Expr) ResourceOptionsDecl {
+	retainOnDelete Expr, deletedWith Expr) ResourceOptionsDecl {
 
 	return ResourceOptionsDecl{
 		declNode:                decl(node),
@@ -355,10 +362,10 @@ func ResourceOptionsSyntax(node *syntax.ObjectNode,
 }
 
 func ResourceOptions(additionalSecretOutputs, aliases *StringListDecl,
-	customTimeouts *CustomTimeoutsDecl, deleteBeforeReplace *BooleanExpr,
+	customTimeouts *CustomTimeoutsDecl, deleteBeforeReplace Expr,
 	dependsOn Expr, ignoreChanges *StringListDecl, importID *StringExpr, parent Expr,
 	protect Expr, provider, providers Expr, version *StringExpr, pluginDownloadURL *StringExpr,
-	replaceOnChanges *StringListDecl, retainOnDelete *BooleanExpr, deletedWith Expr) ResourceOptionsDecl {
+	replaceOnChanges *StringListDecl, retainOnDelete Expr, deletedWith Expr) ResourceOptionsDecl {
 
 	return ResourceOptionsSyntax(nil, additionalSecretOutputs, aliases, customTimeouts,
 		deleteBeforeReplace, dependsOn, ignoreChanges, importID, parent, protect, provider, providers,
@@ -382,6 +389,34 @@ func (d *InvokeOptionsDecl) recordSyntax() *syntax.Node {
 	return &d.syntax
 }
 
+// InvokeCollectDecl describes how fn::invoke pages through the results of a paginated function.
+type InvokeCollectDecl struct {
+	declNode
+
+	// Items names the output holding the items returned by each call.
+	Items *StringExpr
+	// Token names the output holding the continuation token. Collection stops when it is empty.
+	Token *StringExpr
+	// Argument names the argument the token is passed back as. It defaults to Token.
+	Argument *StringExpr
+}
+
+func (d *InvokeCollectDecl) defaultValue() interface{} {
+	return &InvokeCollectDecl{}
+}
+
+func (d *InvokeCollectDecl) recordSyntax() *syntax.Node {
+	return &d.syntax
+}
+
+// ArgumentName returns the name of the argument the continuation token is passed as.
+func (d *InvokeCollectDecl) ArgumentName() string {
+	if d.Argument != nil {
+		return d.Argument.Value
+	}
+	return d.Token.Value
+}
+
 type GetResourceDecl struct {
 	declNode
 	// We need to call the field Id instead of ID because we want the derived user field to be id instead of iD
@@ -477,19 +512,85 @@ func CustomTimeouts(create, update, delete *StringExpr) *CustomTimeoutsDecl {
 	return CustomTimeoutsSyntax(nil, create, update, delete)
 }
 
+// A PluginDecl declares a provider plugin that the template requires. This is only needed for packages that are not
+// referenced by a type token, for example because their resources are created with a computed type.
+type PluginDecl struct {
+	declNode
+
+	Package     *StringExpr
+	Version     *StringExpr
+	DownloadURL *StringExpr
+}
+
+func (d *PluginDecl) recordSyntax() *syntax.Node {
+	return &d.syntax
+}
+
+func PluginSyntax(node *syntax.ObjectNode, pkg, version, downloadURL *StringExpr) *PluginDecl {
+	return &PluginDecl{
+		declNode:    declNode{syntax: node},
+		Package:     pkg,
+		Version:     version,
+		DownloadURL: downloadURL,
+	}
+}
+
+func Plugin(pkg, version, downloadURL *StringExpr) *PluginDecl {
+	return PluginSyntax(nil, pkg, version, downloadURL)
+}
+
+type PluginListDecl struct {
+	declNode
+
+	Elements []*PluginDecl
+}
+
+func (d *PluginListDecl) defaultValue() interface{} {
+	return &PluginListDecl{}
+}
+
+func (d *PluginListDecl) parse(name string, node syntax.Node) syntax.Diagnostics {
+	// In a project file, an object-valued `plugins` field configures local plugins for the CLI and is not part of
+	// the template.
+	if _, ok := node.(*syntax.ObjectNode); ok {
+		return nil
+	}
+
+	list, ok := node.(*syntax.ListNode)
+	if !ok {
+		return syntax.Diagnostics{syntax.NodeError(node, fmt.Sprintf("%v must be a list", name), "")}
+	}
+
+	var diags syntax.Diagnostics
+
+	elements := make([]*PluginDecl, list.Len())
+	for i := range elements {
+		ename := fmt.Sprintf("%s[%d]", name, i)
+		ediags := parseField(ename, reflect.ValueOf(&elements[i]).Elem(), list.Index(i))
+		diags.Extend(ediags...)
+	}
+	d.Elements = elements
+
+	return diags
+}
+
 // A TemplateDecl represents a Pulumi YAML template.
 type TemplateDecl struct {
 	source []byte
 
 	syntax syntax.Node
 
-	Name          *StringExpr
-	Description   *StringExpr
+	Name        *StringExpr
+	Description *StringExpr
+	// NamePrefix is prepended to the name of every resource that doesn't declare an explicit name. It is
+	// evaluated before anything else, so it may only reference the `pulumi` variable.
+	NamePrefix    Expr
 	Configuration ConfigMapDecl
 	Config        ConfigMapDecl
 	Variables     VariablesMapDecl
 	Resources     ResourcesMapDecl
 	Outputs       PropertyMapDecl
+	Plugins       PluginListDecl
 }
 
 func (d *TemplateDecl) Syntax() syntax.Node {
@@ -540,7 +641,72 @@ func ParseTemplate(source []byte, node syntax.Node) (*TemplateDecl, syntax.Diagn
 	template := TemplateDecl{source: source}
 
 	diags := parseRecord("template", &template, node, false)
-	return &template, diags
+	return &template, diags.Attribute(syntax.SourceParse)
+}
+
+// Merge merges the declarations of other into d. Entries keep their original syntax, so diagnostics continue to
+// point into the document that declared them. It is an error for both templates to declare the same entry, or to
+// declare different names or descriptions.
+func (d *TemplateDecl) Merge(other *TemplateDecl) syntax.Diagnostics {
+	var diags syntax.Diagnostics
+
+	mergeString := func(field string, dest **StringExpr, src *StringExpr) {
+		switch {
+		case src == nil:
+		case *dest == nil:
+			*dest = src
+		case (*dest).Value != src.Value:
+			diags.Extend(ExprError(src, fmt.Sprintf("conflicting template %s %q, already declared as %q", field, src.Value, (*dest).Value), ""))
+		}
+	}
+	mergeString("name", &d.Name, other.Name)
+	mergeString("description", &d.Description, other.Description)
+	if other.NamePrefix != nil {
+		if d.NamePrefix != nil {
+			diags.Extend(ExprError(other.NamePrefix, "namePrefix is declared in more than one document", ""))
+		} else {
+			d.NamePrefix = other.NamePrefix
+		}
+	}
+
+	var mdiags syntax.Diagnostics
+	d.Configuration.Entries, mdiags = mergeEntries("configuration", d.Configuration.Entries, other.Configuration.Entries,
+		func(e ConfigMapEntry) *StringExpr { return e.Key })
+	diags.Extend(mdiags...)
+	d.Config.Entries, mdiags = mergeEntries("config", d.Config.Entries, other.Config.Entries,
+		func(e ConfigMapEntry) *StringExpr { return e.Key })
+	diags.Extend(mdiags...)
+	d.Variables.Entries, mdiags = mergeEntries("variable", d.Variables.Entries, other.Variables.Entries,
+		func(e VariablesMapEntry) *StringExpr { return e.Key })
+	diags.Extend(mdiags...)
+	d.Resources.Entries, mdiags = mergeEntries("resource", d.Resources.Entries, other.Resources.Entries,
+		func(e ResourcesMapEntry) *StringExpr { return e.Key })
+	diags.Extend(mdiags...)
+	d.Outputs.Entries, mdiags = mergeEntries("output", d.Outputs.Entries, other.Outputs.Entries,
+		func(e PropertyMapEntry) *StringExpr { return e.Key })
+	diags.Extend(mdiags...)
+	// Conflicting plugin declarations are diagnosed when the referenced plugins are collected.
+	d.Plugins.Elements = append(d.Plugins.Elements, other.Plugins.Elements...)
+
+	return diags.Attribute(syntax.SourceParse)
+}
+
+func mergeEntries[E any](kind string, dest, src []E, key func(E) *StringExpr) ([]E, syntax.Diagnostics) {
+	var diags syntax.Diagnostics
+	existing := make(map[string]struct{}, len(dest))
+	for _, e := range dest {
+		existing[key(e).Value] = struct{}{}
+	}
+	for _, e := range src {
+		k := key(e)
+		if _, ok := existing[k.Value]; ok {
+			diags.Extend(ExprError(k, fmt.Sprintf("%s %q is declared in more than one document", kind, k.Value), ""))
+			continue
+		}
+		existing[k.Value] = struct{}{}
+		dest = append(dest, e)
+	}
+	return dest, diags
 }
 
 var parseDeclType = reflect.TypeOf((*parseDecl)(nil)).Elem()
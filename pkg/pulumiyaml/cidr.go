@@ -0,0 +1,73 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// parseCIDRPrefix parses an IPv4 or IPv6 prefix such as "10.0.0.0/16", returning the network address as an integer,
+// the length of the prefix and the number of bits in an address.
+func parseCIDRPrefix(prefix string) (*big.Int, int, int, error) {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%q is not a valid CIDR prefix", prefix)
+	}
+	ones, bits := network.Mask.Size()
+	return new(big.Int).SetBytes(network.IP), ones, bits, nil
+}
+
+// addressFromInt converts an integer to an address of the given number of bits.
+func addressFromInt(n *big.Int, bits int) net.IP {
+	ip := make(net.IP, bits/8)
+	b := n.Bytes()
+	copy(ip[len(ip)-len(b):], b)
+	return ip
+}
+
+// cidrSubnet computes the address of a subnet within prefix, following Terraform's cidrsubnet: the prefix is extended
+// by newbits bits, and netnum selects which of the resulting subnets is returned. For example, the subnets of
+// "10.0.0.0/16" extended by 8 bits are "10.0.0.0/24", "10.0.1.0/24" and so on.
+func cidrSubnet(prefix string, newbits, netnum int) (string, error) {
+	base, ones, bits, err := parseCIDRPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	if newbits < 0 {
+		return "", fmt.Errorf("the number of new bits must not be negative, not %d", newbits)
+	}
+	if ones+newbits > bits {
+		return "", fmt.Errorf("insufficient address space to extend prefix %s by %d bits", prefix, newbits)
+	}
+	count := new(big.Int).Lsh(big.NewInt(1), uint(newbits))
+	num := big.NewInt(int64(netnum))
+	if num.Sign() < 0 || num.Cmp(count) >= 0 {
+		return "", fmt.Errorf("prefix %s extended by %d bits has no network number %d", prefix, newbits, netnum)
+	}
+
+	length := ones + newbits
+	base.Or(base, num.Lsh(num, uint(bits-length)))
+	subnet := net.IPNet{IP: addressFromInt(base, bits), Mask: net.CIDRMask(length, bits)}
+	return subnet.String(), nil
+}
+
+// cidrHost computes the address of a host within prefix, following Terraform's cidrhost. Host numbers count from the
+// network address, and negative host numbers count back from the end of the range, so -1 is the last address.
+func cidrHost(prefix string, hostnum int) (string, error) {
+	base, ones, bits, err := parseCIDRPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	num := big.NewInt(int64(hostnum))
+	if num.Sign() < 0 {
+		num.Add(num, size)
+	}
+	if num.Sign() < 0 || num.Cmp(size) >= 0 {
+		return "", fmt.Errorf("prefix %s has no host number %d", prefix, hostnum)
+	}
+
+	return addressFromInt(base.Add(base, num), bits).String(), nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCidrSubnet(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		prefix   string
+		newbits  int
+		netnum   int
+		expected string
+		err      string
+	}{
+		{prefix: "172.16.0.0/12", newbits: 4, netnum: 2, expected: "172.18.0.0/16"},
+		{prefix: "10.1.2.0/24", newbits: 4, netnum: 15, expected: "10.1.2.240/28"},
+		{prefix: "10.1.2.3/24", newbits: 0, netnum: 0, expected: "10.1.2.0/24"},
+		{prefix: "fd00:fd12:3456:7890::/56", newbits: 16, netnum: 162, expected: "fd00:fd12:3456:7800:a200::/72"},
+		{prefix: "10.1.2.0/24", newbits: 4, netnum: 16, err: "prefix 10.1.2.0/24 extended by 4 bits has no network number 16"},
+		{prefix: "10.1.2.0/24", newbits: 9, netnum: 0, err: "insufficient address space to extend prefix 10.1.2.0/24 by 9 bits"},
+		{prefix: "10.1.2.0/24", newbits: -1, netnum: 0, err: "the number of new bits must not be negative, not -1"},
+		{prefix: "10.1.2.0", newbits: 4, netnum: 0, err: `"10.1.2.0" is not a valid CIDR prefix`},
+	}
+	for _, tt := range tests {
+		actual, err := cidrSubnet(tt.prefix, tt.newbits, tt.netnum)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err)
+			continue
+		}
+		if assert.NoError(t, err) {
+			assert.Equal(t, tt.expected, actual)
+		}
+	}
+}
+
+func TestCidrHost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		prefix   string
+		hostnum  int
+		expected string
+		err      string
+	}{
+		{prefix: "10.12.112.0/20", hostnum: 16, expected: "10.12.112.16"},
+		{prefix: "10.12.112.0/20", hostnum: 268, expected: "10.12.113.12"},
+		{prefix: "10.12.112.0/20", hostnum: -1, expected: "10.12.127.255"},
+		{prefix: "fd00:fd12:3456:7890:00a2::/72", hostnum: 34, expected: "fd00:fd12:3456:7890::22"},
+		{prefix: "10.12.112.0/20", hostnum: 4096, err: "prefix 10.12.112.0/20 has no host number 4096"},
+		{prefix: "10.12.112.0/20", hostnum: -4097, err: "prefix 10.12.112.0/20 has no host number -4097"},
+		{prefix: "not-a-prefix", hostnum: 1, err: `"not-a-prefix" is not a valid CIDR prefix`},
+	}
+	for _, tt := range tests {
+		actual, err := cidrHost(tt.prefix, tt.hostnum)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err)
+			continue
+		}
+		if assert.NoError(t, err) {
+			assert.Equal(t, tt.expected, actual)
+		}
+	}
+}
@@ -70,6 +70,14 @@ func (m FakePackage) ResolveResource(typeName string) (pulumiyaml.ResourceTypeTo
 	}
 }
 
+func (m FakePackage) ListResources() []string {
+	return []string{"test:mod:prov", "test:mod:typ"}
+}
+
+func (m FakePackage) ListFunctions() []string {
+	return []string{"test:mod:fn"}
+}
+
 func (m FakePackage) ResourceTypeHint(typeName pulumiyaml.ResourceTypeToken) *schema.ResourceType {
 	switch typeName {
 	case "test:mod:prov", "test:mod:typ":
@@ -179,6 +179,10 @@ func (imp *importer) importInterpolate(node *ast.InterpolateExpr, substitutions
 	for _, part := range node.Parts {
 		parts = append(parts, plainLit(part.Text))
 
+		if part.Default != nil {
+			diags.Extend(ast.ExprError(node, "default values in interpolations are not supported when converting programs", ""))
+			continue
+		}
 		if part.Value != nil {
 			ref, rdiags := imp.importPropertyAccess(node, part.Value, environment, nil)
 			diags.Extend(rdiags...)
@@ -237,6 +241,11 @@ func (imp *importer) importSplit(node *ast.SplitExpr) (model.Expression, syntax.
 	return call, diags
 }
 
+// importUnsupportedBuiltin reports a builtin that has no equivalent PCL function.
+func (imp *importer) importUnsupportedBuiltin(node ast.BuiltinExpr) (model.Expression, syntax.Diagnostics) {
+	return nil, syntax.Diagnostics{ast.ExprError(node, fmt.Sprintf("%s is not supported when converting programs", node.Name().Value), "")}
+}
+
 // importFunctionCall imports a call to an AWS intrinsic function. The way the function is imported depends on the
 // function:
 //
@@ -291,6 +300,9 @@ func (imp *importer) importBuiltin(node ast.BuiltinExpr) (model.Expression, synt
 			Args: []model.Expression{path},
 		}, pdiags
 	case *ast.InvokeExpr:
+		if node.Collect != nil {
+			return nil, syntax.Diagnostics{ast.ExprError(node, "collecting the results of fn::invoke is not supported when converting programs", "")}
+		}
 		var diags syntax.Diagnostics
 
 		version, err := pulumiyaml.ParseVersion(node.CallOpts.Version)
@@ -303,10 +315,20 @@ func (imp *importer) importBuiltin(node ast.BuiltinExpr) (model.Expression, synt
 		}
 		function := quotedLit(string(functionName))
 
+		hint := pkg.FunctionTypeHint(functionName)
+		callArgs, cdiags := pulumiyaml.InvokeArguments(node, hint)
+		if cdiags.HasErrors() {
+			return nil, cdiags
+		}
+
 		invokeArgs := []model.Expression{function}
-		if node.CallArgs != nil {
-			inputs := pkg.FunctionTypeHint(functionName).Inputs
-			args, adiags := imp.importExpr(node.CallArgs, inputs)
+		if node.ArgumentsExpr != nil {
+			args, adiags := imp.importExpr(node.ArgumentsExpr, nil)
+			diags.Extend(adiags...)
+
+			invokeArgs = append(invokeArgs, args)
+		} else if callArgs != nil {
+			args, adiags := imp.importExpr(callArgs, hint.Inputs)
 			diags.Extend(adiags...)
 
 			invokeArgs = append(invokeArgs, args)
@@ -321,10 +343,29 @@ func (imp *importer) importBuiltin(node ast.BuiltinExpr) (model.Expression, synt
 		if node.Return == nil {
 			return fn, diags
 		}
-		return relativeTraversal(fn, node.Return.Value), diags
+		name, accessors := node.ReturnPath()
+		access := relativeTraversal(fn, name)
+		for _, accessor := range accessors {
+			switch accessor := accessor.(type) {
+			case *ast.PropertyName:
+				access.Traversal = append(access.Traversal, hcl.TraverseAttr{Name: accessor.Name})
+			case *ast.PropertySubscript:
+				switch index := accessor.Index.(type) {
+				case string:
+					access.Traversal = append(access.Traversal, hcl.TraverseAttr{Name: index})
+				case int:
+					access.Traversal = append(access.Traversal, hcl.TraverseIndex{Key: cty.NumberIntVal(int64(index))})
+				}
+			}
+			access.Parts = append(access.Parts, model.DynamicType)
+		}
+		return access, diags
 	case *ast.JoinExpr:
 		return imp.importJoin(node)
 	case *ast.SelectExpr:
+		if node.Default != nil {
+			return nil, syntax.Diagnostics{ast.ExprError(node.Default, "a default for fn::select is not supported when converting programs", "")}
+		}
 		var diags syntax.Diagnostics
 
 		index, idiags := imp.importExpr(node.Index, nil)
@@ -401,6 +442,16 @@ func (imp *importer) importBuiltin(node ast.BuiltinExpr) (model.Expression, synt
 			Name: "fromBase64",
 			Args: []model.Expression{path},
 		}, pdiags
+	case *ast.BasenameExpr, *ast.DirnameExpr, *ast.FileExtensionExpr, *ast.DefaultsExpr, *ast.CoalesceListExpr,
+		*ast.FromJSONExpr, *ast.MergeExpr, *ast.ToYAMLExpr, *ast.TextTemplateExpr,
+		*ast.ReplaceExpr, *ast.TrimExpr, *ast.TrimPrefixExpr, *ast.TrimSuffixExpr,
+		*ast.UpperExpr, *ast.LowerExpr, *ast.FormatExpr, *ast.FormatNumberExpr, *ast.JSONPathExpr, *ast.IfExpr,
+		*ast.EqualsExpr, *ast.ContainsExpr, *ast.NotExpr, *ast.AndExpr, *ast.OrExpr, *ast.Sha256Expr, *ast.Sha1Expr,
+		*ast.CidrSubnetExpr, *ast.CidrHostExpr, *ast.RangeExpr,
+		*ast.ReadDirExpr, *ast.GetEnvExpr, *ast.UUIDExpr, *ast.TimestampExpr, *ast.SplitRegexExpr,
+		*ast.CoalesceEmptyStringExpr, *ast.SliceExpr, *ast.KeysExpr, *ast.ValuesExpr, *ast.SortExpr, *ast.UniqueExpr,
+		*ast.ToStringExpr, *ast.ToNumberExpr, *ast.UnsecretExpr, *ast.OutputExpr:
+		return imp.importUnsupportedBuiltin(node)
 	default:
 		contract.Failf("unexpected builtin type %T", node)
 		return nil, nil
@@ -564,7 +615,7 @@ func (imp *importer) getResourceRefList(optionField ast.Expr, name string, field
 			diags.Extend(ast.ExprError(optionField, fmt.Sprintf("expected %v of resource '%v' to be a list of resource expressions, got '%v'", field, name, reflect.TypeOf(e)), ""))
 			continue
 		}
-		resourceName := sym.Property.Accessors[0].(*ast.PropertyName).Name
+		resourceName := sym.Property.RootName()
 		if resourceVar, ok := imp.resources[resourceName]; ok {
 			refs = append(refs, model.VariableReference(resourceVar))
 		} else {
@@ -580,7 +631,7 @@ func (imp *importer) getResourceRefItem(optionField ast.Expr, name string, field
 	if !ok {
 		return nil, ast.ExprError(optionField, fmt.Sprintf("expected %v of resource '%v' to be a resource, got '%v'", field, name, reflect.TypeOf(sym)), "")
 	}
-	resourceName := sym.Property.Accessors[0].(*ast.PropertyName).Name
+	resourceName := sym.Property.RootName()
 	resourceVar, ok := imp.resources[resourceName]
 	if !ok {
 		return nil, ast.ExprError(optionField, fmt.Sprintf("unknown resource '%v'", resourceName), "")
@@ -5,7 +5,9 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/pulumi/pulumi/pkg/v3/codegen/hcl2/model"
@@ -50,6 +52,8 @@ func (t typ) Pcl() model.Type {
 	switch t := t.inner.(type) {
 	case *schema.ArrayType:
 		return model.NewListType(typ{t.ElementType}.Pcl())
+	case *schema.MapType:
+		return model.NewMapType(typ{t.ElementType}.Pcl())
 	}
 
 	// We should never hit this, but if we do an error should be reported instead of
@@ -63,9 +67,13 @@ var (
 	Number           = typ{schema.NumberType}
 	NumberList       = typ{&schema.ArrayType{ElementType: schema.NumberType}}
 	Boolean          = typ{schema.BoolType}
-	BooleanList      = typ{&schema.ArrayType{ElementType: schema.NumberType}}
+	BooleanList      = typ{&schema.ArrayType{ElementType: schema.BoolType}}
 	Int              = typ{schema.IntType}
 	IntList          = typ{&schema.ArrayType{ElementType: schema.IntType}}
+	StringMap        = typ{&schema.MapType{ElementType: schema.StringType}}
+	NumberMap        = typ{&schema.MapType{ElementType: schema.NumberType}}
+	BooleanMap       = typ{&schema.MapType{ElementType: schema.BoolType}}
+	IntMap           = typ{&schema.MapType{ElementType: schema.IntType}}
 )
 
 type Types []Type
@@ -86,6 +94,10 @@ var ConfigTypes = Types{
 	IntList,
 	Boolean,
 	BooleanList,
+	StringMap,
+	NumberMap,
+	IntMap,
+	BooleanMap,
 }
 
 func newList(c Type) typ {
@@ -104,6 +116,22 @@ func newList(c Type) typ {
 	}
 }
 
+func newMap(c Type) typ {
+	// This is necessary to preserve switch equality
+	switch c {
+	case String:
+		return StringMap
+	case Number:
+		return NumberMap
+	case Int:
+		return IntMap
+	case Boolean:
+		return BooleanMap
+	default:
+		return typ{&schema.MapType{ElementType: c.(typ).inner}}
+	}
+}
+
 func IsValidType(c Type) bool {
 	for _, v := range ConfigTypes {
 		if v == c {
@@ -131,6 +159,14 @@ func Parse(s string) (Type, bool) {
 		}
 		return newList(inner), true
 	}
+	if strings.HasPrefix(s, "map<") && strings.HasSuffix(s, ">") {
+		innerString := strings.TrimSuffix(strings.TrimPrefix(s, "map<"), ">")
+		inner, ok := Parse(strings.TrimSpace(innerString))
+		if !ok {
+			return nil, false
+		}
+		return newMap(inner), true
+	}
 
 	switch s {
 	case "string":
@@ -148,7 +184,9 @@ func Parse(s string) (Type, bool) {
 
 var (
 	ErrHeterogeneousList = HeterogeneousListErr{}
+	ErrHeterogeneousMap  = HeterogeneousMapErr{}
 	ErrEmptyList         = fmt.Errorf("empty list")
+	ErrEmptyMap          = fmt.Errorf("empty map")
 	ErrUnexpectedType    = UnexpectedTypeErr{}
 )
 
@@ -170,6 +208,24 @@ func (e *HeterogeneousListErr) Is(err error) bool {
 	return ok
 }
 
+type HeterogeneousMapErr struct {
+	T1 Type
+	T2 Type
+}
+
+func (e *HeterogeneousMapErr) Error() string {
+	if e.T1 == nil || e.T2 == nil {
+		return "heterogeneous typed maps are not allowed"
+	}
+	return fmt.Sprintf("heterogeneous typed maps are not allowed: found types %s and %s",
+		e.T1, e.T2)
+}
+
+func (e *HeterogeneousMapErr) Is(err error) bool {
+	_, ok := err.(*HeterogeneousMapErr)
+	return ok
+}
+
 type UnexpectedTypeErr struct {
 	T interface{}
 }
@@ -188,10 +244,119 @@ func (e *UnexpectedTypeErr) Is(err error) bool {
 	return ok
 }
 
+type TypeMismatchErr struct {
+	Expected Type
+	Actual   Type
+}
+
+func (e *TypeMismatchErr) Error() string {
+	return fmt.Sprintf("value of type %s but type %s was specified", e.Actual, e.Expected)
+}
+
+// ElementTypeErr describes an element of a list or map that doesn't conform to the element type of the list or map.
+type ElementTypeErr struct {
+	// The index of the element in a list, or its key in a map.
+	Index interface{}
+	TypeMismatchErr
+}
+
+// Element describes the element that doesn't conform, e.g. `element 2` or `entry "key"`.
+func (e *ElementTypeErr) Element() string {
+	if k, ok := e.Index.(string); ok {
+		return fmt.Sprintf("entry %q", k)
+	}
+	return fmt.Sprintf("element %v", e.Index)
+}
+
+func (e *ElementTypeErr) Error() string {
+	return fmt.Sprintf("%s has type %s but type %s was specified", e.Element(), e.Actual, e.Expected)
+}
+
+// Conform checks that a go value is a valid value of the configuration type t, returning the value converted to that
+// type: whole numbers are accepted as integers and integers as numbers. The elements of lists and maps are checked
+// individually, and an *ElementTypeErr identifies the first that doesn't conform. Otherwise, the error is a
+// *TypeMismatchErr or one of the errors returned by TypeValue.
+func Conform(t Type, v interface{}) (interface{}, error) {
+	var elem Type
+	switch inner := t.Schema().(type) {
+	case *schema.ArrayType:
+		elem = typ{inner.ElementType}
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, mismatch(t, v)
+		}
+		conformed := make([]interface{}, len(list))
+		for i, e := range list {
+			c, err := conformPrimitive(elem, e)
+			if err != nil {
+				return nil, elementErr(i, err)
+			}
+			conformed[i] = c
+		}
+		return conformed, nil
+	case *schema.MapType:
+		elem = typ{inner.ElementType}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, mismatch(t, v)
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		conformed := make(map[string]interface{}, len(m))
+		for _, k := range keys {
+			c, err := conformPrimitive(elem, m[k])
+			if err != nil {
+				return nil, elementErr(k, err)
+			}
+			conformed[k] = c
+		}
+		return conformed, nil
+	default:
+		return conformPrimitive(t, v)
+	}
+}
+
+func conformPrimitive(t Type, v interface{}) (interface{}, error) {
+	actual, err := TypeValue(v)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case actual == t:
+		return v, nil
+	case t == Number && actual == Int:
+		return float64(v.(int)), nil
+	case t == Int && actual == Number && v.(float64) == math.Trunc(v.(float64)):
+		return int(v.(float64)), nil
+	default:
+		return nil, &TypeMismatchErr{Expected: t, Actual: actual}
+	}
+}
+
+func mismatch(t Type, v interface{}) error {
+	actual, err := TypeValue(v)
+	if err != nil {
+		return err
+	}
+	return &TypeMismatchErr{Expected: t, Actual: actual}
+}
+
+func elementErr(index interface{}, err error) error {
+	if err, ok := err.(*TypeMismatchErr); ok {
+		return &ElementTypeErr{Index: index, TypeMismatchErr: *err}
+	}
+	return err
+}
+
 // Type a go value into a configuration value.
 // If an error is returned, it is one of
 // - ErrHeterogeneousList
+// - ErrHeterogeneousMap
 // - ErrEmptyList
+// - ErrEmptyMap
 // - ErrUnexpectedType
 func TypeValue(v interface{}) (Type, error) {
 	switch v := v.(type) {
@@ -232,6 +397,31 @@ func TypeValue(v interface{}) (Type, error) {
 			}
 		}
 		return expected, nil
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return nil, ErrEmptyMap
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var expected Type
+		for _, k := range keys {
+			t, err := TypeValue(v[k])
+			if err != nil {
+				return nil, err
+			}
+			if expected == nil {
+				expected = t
+			} else if t != expected {
+				return nil, &HeterogeneousMapErr{expected, t}
+			}
+		}
+		if !IsValidType(newMap(expected)) {
+			return nil, &UnexpectedTypeErr{v}
+		}
+		return newMap(expected), nil
 	case []float64:
 		return NumberList, nil
 	case []int:
@@ -17,6 +17,9 @@ func TestParse(t *testing.T) {
 		{"Number", Number},
 		{"List<Boolean>", BooleanList},
 		{"List< String >", StringList},
+		{"Map<String>", StringMap},
+		{"map<integer>", IntMap},
+		{"Map<>", nil},
 		{"List", nil},
 		{"List<>", nil},
 	}
@@ -53,6 +56,9 @@ func TestTypeValue(t *testing.T) {
 		{[]int{}, IntList, nil},
 		{[]interface{}{}, nil, ErrEmptyList},
 		{[]interface{}{false, true}, BooleanList, nil},
+		{map[string]interface{}{"a": "x", "b": "y"}, StringMap, nil},
+		{map[string]interface{}{"a": "x", "b": 3.14}, nil, &ErrHeterogeneousMap},
+		{map[string]interface{}{}, nil, ErrEmptyMap},
 	}
 	//nolint:paralleltest // false positive that the "c" var isn't used, it is used via "c.input"
 	for _, c := range cases {
@@ -69,3 +75,37 @@ func TestTypeValue(t *testing.T) {
 		})
 	}
 }
+
+func TestConform(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		typ      Type
+		input    interface{}
+		expected interface{}
+		error    string
+	}{
+		{Int, 42.0, 42, ""},
+		{Int, 4.2, nil, "value of type number but type integer was specified"},
+		{Number, 42, 42.0, ""},
+		{StringList, []interface{}{"a", "b"}, []interface{}{"a", "b"}, ""},
+		{IntList, []interface{}{1.0, 2.0}, []interface{}{1, 2}, ""},
+		{BooleanList, []interface{}{true, "false"}, nil,
+			"element 1 has type string but type boolean was specified"},
+		{NumberMap, map[string]interface{}{"a": 1, "b": "x"}, nil,
+			`entry "b" has type string but type number was specified`},
+		{StringList, "a", nil, "value of type string but type List<string> was specified"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(fmt.Sprintf("%s/%v", c.typ, c.input), func(t *testing.T) {
+			t.Parallel()
+			output, err := Conform(c.typ, c.input)
+			if c.error != "" {
+				assert.EqualError(t, err, c.error)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, output)
+		})
+	}
+}
@@ -61,6 +61,16 @@ func sortByEditDistance(words []string, comparedTo string) []string {
 	return w
 }
 
+// ClosestMatch returns the word closest to comparedTo, provided it is no more than maxDistance edits away. Ties are
+// broken alphabetically.
+func ClosestMatch(words []string, comparedTo string, maxDistance int) (string, bool) {
+	sorted := sortByEditDistance(words, comparedTo)
+	if len(sorted) == 0 || editDistance(sorted[0], comparedTo) > maxDistance {
+		return "", false
+	}
+	return sorted[0], true
+}
+
 // A list that displays in the human readable format: "a, b and c".
 type AndList []string
 
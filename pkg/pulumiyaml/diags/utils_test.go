@@ -42,6 +42,27 @@ func TestSortByEditDistance(t *testing.T) {
 	}
 }
 
+func TestClosestMatch(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		words       []string
+		comparedTo  string
+		maxDistance int
+		expected    string
+		found       bool
+	}{
+		{[]string{}, "test", 3, "", false},
+		{[]string{"tset", "other"}, "test", 2, "tset", true},
+		{[]string{"tset", "other"}, "test", 1, "", false},
+		{[]string{"best", "rest"}, "test", 1, "best", true},
+	}
+	for _, c := range cases {
+		match, found := ClosestMatch(c.words, c.comparedTo, c.maxDistance)
+		assert.Equalf(t, c.expected, match, "ClosestMatch(%v, %v, %v)", c.words, c.comparedTo, c.maxDistance)
+		assert.Equal(t, c.found, found)
+	}
+}
+
 func TestDisplayList(t *testing.T) {
 	t.Parallel()
 	cases := []struct {
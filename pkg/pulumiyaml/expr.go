@@ -53,7 +53,7 @@ func getExpressionDependencies(deps *[]*ast.StringExpr, x ast.Expr) {
 	case *ast.InterpolateExpr:
 		for _, p := range x.Parts {
 			if p.Value != nil && len(p.Value.Accessors) > 0 {
-				name := p.Value.Accessors[0].(*ast.PropertyName).Name
+				name := p.Value.RootName()
 				sx := ast.StringSyntax(syntax.StringSyntax(x.Syntax().Syntax(), name))
 				*deps = append(*deps, sx)
 			}
@@ -74,3 +74,29 @@ func getExpressionDependencies(deps *[]*ast.StringExpr, x ast.Expr) {
 		getExpressionDependencies(deps, x.Args())
 	}
 }
+
+// visitReferences calls visit with the root name of each symbol and interpolation in x. Unlike
+// getExpressionDependencies, it doesn't allocate.
+func visitReferences(x ast.Expr, visit func(name string)) {
+	switch x := x.(type) {
+	case *ast.ListExpr:
+		for _, e := range x.Elements {
+			visitReferences(e, visit)
+		}
+	case *ast.ObjectExpr:
+		for _, kvp := range x.Entries {
+			visitReferences(kvp.Key, visit)
+			visitReferences(kvp.Value, visit)
+		}
+	case *ast.InterpolateExpr:
+		for _, p := range x.Parts {
+			if p.Value != nil {
+				visit(p.Value.RootName())
+			}
+		}
+	case *ast.SymbolExpr:
+		visit(x.Property.RootName())
+	case ast.BuiltinExpr:
+		visitReferences(x.Args(), visit)
+	}
+}
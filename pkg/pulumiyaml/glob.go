@@ -0,0 +1,90 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// isGlob returns true if a path contains any of the glob metacharacters understood by fn::fileArchive.
+func isGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// pathExists reports whether a file or directory exists at p.
+func pathExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// splitGlob splits a glob into the directory that contains every file it can match and the pattern relative to
+// that directory. For example, "./site/**/*.html" is split into "./site" and "**/*.html".
+func splitGlob(glob string) (string, string) {
+	segments := strings.Split(filepath.ToSlash(glob), "/")
+	for i, segment := range segments {
+		if isGlob(segment) {
+			base := strings.Join(segments[:i], "/")
+			if base == "" && i > 0 {
+				base = "/"
+			} else if base == "" {
+				base = "."
+			}
+			return filepath.FromSlash(base), strings.Join(segments[i:], "/")
+		}
+	}
+	return glob, ""
+}
+
+// matchGlob reports whether a slash separated path matches a slash separated pattern. Each segment of the pattern is
+// matched with path.Match, except for "**", which matches any number of segments, including none.
+func matchGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// globFiles returns the files under dir that match pattern, keyed by their slash separated path relative to dir.
+func globFiles(dir, pattern string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchGlob(pattern, rel) {
+			files[rel] = p
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,52 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*", "index.html", true},
+		{"*", "css/site.css", false},
+		{"**", "css/site.css", true},
+		{"**/*.css", "site.css", true},
+		{"**/*.css", "css/vendor/site.css", true},
+		{"**/*.css", "css/site.js", false},
+		{"css/**", "css/vendor/site.css", true},
+		{"css/**", "js/site.js", false},
+		{"img/?.png", "img/a.png", true},
+		{"img/[ab].png", "img/c.png", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, matchGlob(tt.pattern, tt.name), "%q matching %q", tt.pattern, tt.name)
+	}
+}
+
+func TestSplitGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		glob, dir, pattern string
+	}{
+		{"./site/**", "site", "**"},
+		{"site/**/*.html", "site", "**/*.html"},
+		{"*.txt", ".", "*.txt"},
+		{"/srv/www/*", "/srv/www", "*"},
+	}
+	for _, tt := range tests {
+		dir, pattern := splitGlob(tt.glob)
+		assert.Equal(t, filepath.FromSlash(tt.dir), filepath.Clean(dir), tt.glob)
+		assert.Equal(t, tt.pattern, pattern, tt.glob)
+	}
+}
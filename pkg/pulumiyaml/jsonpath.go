@@ -0,0 +1,127 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A jsonPath is a parsed JSONPath expression, as used by fn::jsonPath.
+//
+// Only the subset of JSONPath that selects values by position is supported: the root `$`, child access by name
+// (`.name` or `['name']`), indexing (`[0]`, `[-1]`) and wildcards (`.*` or `[*]`).
+type jsonPath []jsonPathSegment
+
+type jsonPathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func parseJSONPath(path string) (jsonPath, error) {
+	rest := strings.TrimPrefix(path, "$")
+
+	var segments jsonPath
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			if strings.HasPrefix(rest, ".") {
+				return nil, fmt.Errorf("recursive descent is not supported")
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("expected a property name after '.'")
+			}
+			rest = rest[end:]
+			if name == "*" {
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			} else {
+				segments = append(segments, jsonPathSegment{key: name})
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("missing closing ']'")
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, jsonPathSegment{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("%q is not a valid index", inner)
+				}
+				segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected character %q", rest[0])
+		}
+	}
+	return segments, nil
+}
+
+// definite returns true if the path can select at most one value.
+func (p jsonPath) definite() bool {
+	for _, s := range p {
+		if s.wildcard {
+			return false
+		}
+	}
+	return true
+}
+
+// query returns the values selected by the path, in document order. Object keys are visited in sorted order.
+func (p jsonPath) query(v interface{}) []interface{} {
+	current := []interface{}{v}
+	for _, s := range p {
+		var next []interface{}
+		for _, v := range current {
+			switch v := v.(type) {
+			case map[string]interface{}:
+				switch {
+				case s.wildcard:
+					keys := make([]string, 0, len(v))
+					for k := range v {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, v[k])
+					}
+				case !s.isIndex:
+					if e, ok := v[s.key]; ok {
+						next = append(next, e)
+					}
+				}
+			case []interface{}:
+				switch {
+				case s.wildcard:
+					next = append(next, v...)
+				case s.isIndex:
+					index := s.index
+					if index < 0 {
+						index += len(v)
+					}
+					if index >= 0 && index < len(v) {
+						next = append(next, v[index])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
@@ -0,0 +1,71 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPathQuery(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]interface{}{
+		"name": "doc",
+		"tags": map[string]interface{}{"b": "2", "a": "1", "with.dot": "3"},
+		"items": []interface{}{
+			map[string]interface{}{"id": "x"},
+			map[string]interface{}{"id": "y"},
+		},
+	}
+
+	tests := []struct {
+		path     string
+		expected []interface{}
+	}{
+		{"$", []interface{}{doc}},
+		{"$.name", []interface{}{"doc"}},
+		{".name", []interface{}{"doc"}},
+		{"$.tags['with.dot']", []interface{}{"3"}},
+		{`$["tags"].a`, []interface{}{"1"}},
+		{"$.tags.*", []interface{}{"1", "2", "3"}},
+		{"$.items[1].id", []interface{}{"y"}},
+		{"$.items[-2].id", []interface{}{"x"}},
+		{"$.items[2].id", nil},
+		{"$.items[*].id", []interface{}{"x", "y"}},
+		{"$.name[0]", nil},
+		{"$.missing.id", nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			path, err := parseJSONPath(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, path.query(doc))
+		})
+	}
+}
+
+func TestJSONPathInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"name":        "unexpected character 'n'",
+		"$..name":     "recursive descent is not supported",
+		"$.":          "expected a property name after '.'",
+		"$.items[0":   "missing closing ']'",
+		"$.items[a]":  `"a" is not a valid index`,
+		"$.items[0]x": `unexpected character 'x'`,
+	}
+	for path, expected := range tests {
+		path, expected := path, expected
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+			_, err := parseJSONPath(path)
+			assert.EqualError(t, err, expected)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/blang/semver"
 	"github.com/iancoleman/strcase"
@@ -50,6 +51,10 @@ type Package interface {
 	//
 	// e.g.: given "aws:s3:Bucket", it will return "aws:s3/bucket:Bucket".
 	ResolveFunction(typeName string) (FunctionTypeToken, error)
+	// Returns the canonical type tokens of the resources the package defines, in ascending order.
+	ListResources() []string
+	// Returns the canonical type tokens of the functions the package defines, in ascending order.
+	ListFunctions() []string
 	// Given the canonical name of a resource, return the IsComponent property of the resource schema.
 	IsComponent(typeName ResourceTypeToken) (bool, error)
 	// Information on the properties of a resource. All resource type tokens generated by a
@@ -91,13 +96,57 @@ func NewPackageLoader() (PackageLoader, error) {
 	if err != nil {
 		return nil, err
 	}
-	return packageLoader{schema.NewPluginLoader(host), host}, nil
+	return newCachingPackageLoader(packageLoader{schema.NewPluginLoader(host), host}), nil
 }
 
 // Unsafely create a PackageLoader from a schema.Loader, forfeiting the ability to close the host
 // and clean up plugins when finished. Useful for test cases.
 func NewPackageLoaderFromSchemaLoader(loader schema.ReferenceLoader) PackageLoader {
-	return packageLoader{loader, nil}
+	return newCachingPackageLoader(packageLoader{loader, nil})
+}
+
+// cachingPackageLoader memoizes the packages loaded by another PackageLoader by name and version, since a template
+// looks up the package of every resource and function it references. Failed loads are not cached.
+type cachingPackageLoader struct {
+	loader PackageLoader
+
+	m        sync.Mutex
+	packages map[string]Package
+}
+
+func newCachingPackageLoader(loader PackageLoader) *cachingPackageLoader {
+	return &cachingPackageLoader{loader: loader, packages: map[string]Package{}}
+}
+
+func (l *cachingPackageLoader) LoadPackage(name string, version *semver.Version) (Package, error) {
+	key := name
+	if version != nil {
+		key += "@" + version.String()
+	}
+
+	l.m.Lock()
+	pkg, ok := l.packages[key]
+	l.m.Unlock()
+	if ok {
+		return pkg, nil
+	}
+
+	// The lock isn't held while loading, so that different packages can be loaded concurrently.
+	pkg, err := l.loader.LoadPackage(name, version)
+	if err != nil {
+		return nil, err
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.packages[key] = pkg
+	return pkg, nil
+}
+
+func (l *cachingPackageLoader) Close() {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.packages = map[string]Package{}
+	l.loader.Close()
 }
 
 // Plugin is metadata containing a package name, possibly empty version and download URL. Used to
@@ -113,37 +162,56 @@ type pluginEntry struct {
 	pluginDownloadURL string
 }
 
+// pluginSet collects the version and download URL of each referenced package, reporting references that conflict
+// with an earlier one.
+type pluginSet map[string]*pluginEntry
+
+func (s pluginSet) accept(typeName string, version, pluginDownloadURL *ast.StringExpr, report func(expr ast.Expr, summary string)) {
+	pkg := ResolvePkgName(typeName)
+	if entry, found := s[pkg]; found {
+		if v := version.GetValue(); v != "" && entry.version != v {
+			if entry.version == "" {
+				entry.version = v
+			} else {
+				report(version, fmt.Sprintf("Provider %v already declared with a conflicting version: %v", pkg, entry.version))
+			}
+		}
+		if url := pluginDownloadURL.GetValue(); url != "" && entry.pluginDownloadURL != url {
+			if entry.pluginDownloadURL == "" {
+				entry.pluginDownloadURL = url
+			} else {
+				report(pluginDownloadURL, fmt.Sprintf("Provider %v already declared with a conflicting plugin download URL: %v", pkg, entry.pluginDownloadURL))
+			}
+		}
+	} else {
+		s[pkg] = &pluginEntry{
+			version:           version.GetValue(),
+			pluginDownloadURL: pluginDownloadURL.GetValue(),
+		}
+	}
+}
+
 // GetReferencedPlugins returns the packages and (if provided) versions for each referenced provider
 // used in the program.
 func GetReferencedPlugins(tmpl *ast.TemplateDecl) ([]Plugin, syntax.Diagnostics) {
-	pluginMap := map[string]*pluginEntry{}
+	pluginMap := pluginSet{}
 
 	acceptType := func(r *Runner, typeName string, version, pluginDownloadURL *ast.StringExpr) {
-		pkg := ResolvePkgName(typeName)
-		if entry, found := pluginMap[pkg]; found {
-			if v := version.GetValue(); v != "" && entry.version != v {
-				if entry.version == "" {
-					entry.version = v
-				} else {
-					r.sdiags.Extend(ast.ExprError(version, fmt.Sprintf("Provider %v already declared with a conflicting version: %v", pkg, entry.version), ""))
-				}
-			}
-			if url := pluginDownloadURL.GetValue(); url != "" && entry.pluginDownloadURL != url {
-				if entry.pluginDownloadURL == "" {
-					entry.pluginDownloadURL = url
-				} else {
-					r.sdiags.Extend(ast.ExprError(pluginDownloadURL, fmt.Sprintf("Provider %v already declared with a conflicting plugin download URL: %v", pkg, entry.pluginDownloadURL), ""))
-				}
-			}
-		} else {
-			pluginMap[pkg] = &pluginEntry{
-				version:           version.GetValue(),
-				pluginDownloadURL: pluginDownloadURL.GetValue(),
-			}
+		pluginMap.accept(typeName, version, pluginDownloadURL, func(expr ast.Expr, summary string) {
+			r.sdiags.Extend(ast.ExprError(expr, summary, ""))
+		})
+	}
+
+	r := newRunner(tmpl, nil)
+	for _, plugin := range tmpl.Plugins.Elements {
+		if plugin.Package == nil {
+			r.sdiags.Extend(syntax.NodeError(plugin.Syntax(), "Plugin declared without a 'package'", ""))
+			continue
 		}
+		acceptType(r, plugin.Package.Value, plugin.Version, plugin.DownloadURL)
 	}
 
-	diags := newRunner(tmpl, nil).Run(walker{
+	diags := r.Run(walker{
 		VisitResource: func(r *Runner, node resourceNode) bool {
 			res := node.Value
 
@@ -194,6 +262,49 @@ func GetReferencedPlugins(tmpl *ast.TemplateDecl) ([]Plugin, syntax.Diagnostics)
 	return plugins, nil
 }
 
+// PreloadPackages concurrently loads the packages that GetReferencedPlugins finds in the template, so that they are
+// cached before each resource and function is resolved one at a time. Packages are only preloaded through a caching
+// loader, since otherwise each one would be loaded again as it is resolved. Diagnostics are returned in order of
+// package name, regardless of the order the loads complete in.
+func PreloadPackages(tmpl *ast.TemplateDecl, loader PackageLoader) syntax.Diagnostics {
+	if _, ok := loader.(*cachingPackageLoader); !ok {
+		return nil
+	}
+	plugins, diags := GetReferencedPlugins(tmpl)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	errs := make([]error, len(plugins))
+	var wg sync.WaitGroup
+	for i, plugin := range plugins {
+		// Invalid versions are reported when the resource is resolved.
+		version, err := ParseVersion(ast.String(plugin.Version))
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string, version *semver.Version) {
+			defer wg.Done()
+			// A panic in a goroutine can't be recovered by the caller, so it is reported as a failed load instead.
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("%v", r)
+				}
+			}()
+			_, errs[i] = loader.LoadPackage(name, version)
+		}(i, plugin.Package, version)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			diags.Extend(syntax.NodeError(tmpl.Syntax(), fmt.Sprintf("failed to load package %v: %v", plugins[i].Package, err), ""))
+		}
+	}
+	return diags
+}
+
 func ResolvePkgName(typeString string) string {
 	typeParts := strings.Split(typeString, ":")
 
@@ -290,6 +401,34 @@ func ResolveFunction(loader PackageLoader, typeString string, version *semver.Ve
 	return pkg, canonicalName, nil
 }
 
+// InvokeArguments returns the named arguments of an fn::invoke. Positional arguments are matched to the inputs of
+// the function described by hint in the order the schema declares them with multiArgumentInputs. The schema doesn't
+// otherwise give its inputs a stable order, so positional arguments are rejected for other functions.
+func InvokeArguments(t *ast.InvokeExpr, hint *schema.Function) (*ast.ObjectExpr, syntax.Diagnostics) {
+	if t.PositionalArgs == nil {
+		return t.CallArgs, nil
+	}
+
+	if hint == nil || !hint.MultiArgumentInputs || hint.Inputs == nil {
+		summary := fmt.Sprintf("%s does not declare an order for its inputs, so its arguments must be given by name",
+			t.Token.Value)
+		return nil, syntax.Diagnostics{ast.ExprError(t.PositionalArgs, summary,
+			"Positional arguments are only supported for functions whose schema sets multiArgumentInputs")}
+	}
+	inputs := hint.Inputs.Properties
+	elements := t.PositionalArgs.Elements
+	if len(elements) > len(inputs) {
+		summary := fmt.Sprintf("too many arguments to %s: expected at most %d, got %d", t.Token.Value, len(inputs), len(elements))
+		return nil, syntax.Diagnostics{ast.ExprError(elements[len(inputs)], summary, "")}
+	}
+
+	entries := make([]ast.ObjectProperty, len(elements))
+	for i, arg := range elements {
+		entries[i] = ast.ObjectProperty{Key: ast.String(inputs[i].Name), Value: arg}
+	}
+	return ast.Object(entries...), nil
+}
+
 type resourcePackage struct {
 	schema.PackageReference
 }
@@ -350,6 +489,24 @@ func resolveToken(typeName string, resolve func(string) (string, bool, error)) (
 	return "", false, nil
 }
 
+// tokenSpellings returns the ways that the canonical type token can be written in a template: besides its canonical
+// form, `aws:s3/bucket:Bucket` may be written as `aws:s3:Bucket`, and `pkg:index:Type` as `pkg:Type`.
+func tokenSpellings(token string) []string {
+	spellings := []string{token}
+	typeParts := strings.Split(token, ":")
+	if len(typeParts) != 3 {
+		return spellings
+	}
+	if module, file, ok := strings.Cut(typeParts[1], "/"); ok && file == strcase.ToLowerCamel(typeParts[2]) {
+		typeParts[1] = module
+		spellings = append(spellings, strings.Join(typeParts, ":"))
+	}
+	if typeParts[1] == "index" {
+		spellings = append(spellings, typeParts[0]+":"+typeParts[2])
+	}
+	return spellings
+}
+
 func (p resourcePackage) ResolveResource(typeName string) (ResourceTypeToken, error) {
 	if tk, ok := p.resolveProvider(typeName); ok {
 		return tk, nil
@@ -373,6 +530,24 @@ func (p resourcePackage) ResolveResource(typeName string) (ResourceTypeToken, er
 	return ResourceTypeToken(tk), nil
 }
 
+func (p resourcePackage) ListResources() []string {
+	var tokens []string
+	for it := p.Resources().Range(); it.Next(); {
+		tokens = append(tokens, it.Token())
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+func (p resourcePackage) ListFunctions() []string {
+	var tokens []string
+	for it := p.Functions().Range(); it.Next(); {
+		tokens = append(tokens, it.Token())
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
 func (p resourcePackage) ResolveFunction(typeName string) (FunctionTypeToken, error) {
 	typeParts := strings.Split(typeName, ":")
 	if len(typeParts) < 2 || len(typeParts) > 3 {
@@ -0,0 +1,181 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingPackageLoader struct {
+	PackageLoader
+
+	m      sync.Mutex
+	loads  map[string]int
+	closed bool
+}
+
+func (l *countingPackageLoader) LoadPackage(name string, version *semver.Version) (Package, error) {
+	l.m.Lock()
+	l.loads[name]++
+	l.m.Unlock()
+	return l.PackageLoader.LoadPackage(name, version)
+}
+
+func (l *countingPackageLoader) Close() {
+	l.closed = true
+}
+
+func TestCachingPackageLoader(t *testing.T) {
+	t.Parallel()
+
+	inner := &countingPackageLoader{PackageLoader: newMockPackageMap(), loads: map[string]int{}}
+	loader := newCachingPackageLoader(inner)
+	v1 := semver.MustParse("1.0.0")
+
+	for i := 0; i < 3; i++ {
+		_, err := loader.LoadPackage("test", nil)
+		require.NoError(t, err)
+		_, err = loader.LoadPackage("test", &v1)
+		require.NoError(t, err)
+	}
+	// A load without a version and a load of a specific version are cached separately.
+	assert.Equal(t, 2, inner.loads["test"])
+
+	// Failed loads are retried.
+	for i := 0; i < 2; i++ {
+		_, err := loader.LoadPackage("missing", nil)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, inner.loads["missing"])
+
+	// Closing the loader invalidates the cache.
+	loader.Close()
+	assert.True(t, inner.closed)
+	_, err := loader.LoadPackage("test", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.loads["test"])
+}
+
+type panickingPackageLoader struct {
+	PackageLoader
+}
+
+func (l panickingPackageLoader) LoadPackage(name string, version *semver.Version) (Package, error) {
+	if name == "boom" {
+		panic("plugin crashed")
+	}
+	return l.PackageLoader.LoadPackage(name, version)
+}
+
+func TestPreloadPackages(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  a:
+    type: test:resource:type
+  b:
+    type: test:resource:type
+    options:
+      version: 1.2.3
+  c:
+    type: zzz:resource:type
+  d:
+    type: boom:resource:type
+  e:
+    type: missing:resource:type
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	inner := &countingPackageLoader{
+		PackageLoader: panickingPackageLoader{newMockPackageMap()},
+		loads:         map[string]int{},
+	}
+	loader := newCachingPackageLoader(inner)
+
+	diags := PreloadPackages(tmpl, loader)
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	require.Len(t, diagStrings, 3)
+	assert.Equal(t, "<stdin>:1:1: failed to load package boom: plugin crashed", diagStrings[0])
+	assert.True(t, strings.HasPrefix(diagStrings[1], "<stdin>:1:1: failed to load package missing: "), diagStrings[1])
+	assert.True(t, strings.HasPrefix(diagStrings[2], "<stdin>:1:1: failed to load package zzz: "), diagStrings[2])
+	assert.Equal(t, 1, inner.loads["test"])
+
+	// Resolving the resources afterwards doesn't load the package again.
+	v := semver.MustParse("1.2.3")
+	_, _, err := ResolveResource(loader, "test:resource:type", &v)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.loads["test"])
+
+	// Without a cache, preloading would only load each package twice.
+	uncached := &countingPackageLoader{PackageLoader: newMockPackageMap(), loads: map[string]int{}}
+	assert.Empty(t, PreloadPackages(tmpl, uncached))
+	assert.Empty(t, uncached.loads)
+}
+
+func newSchemaPackage(t *testing.T, resources, functions []string) Package {
+	spec := schema.PackageSpec{
+		Name:      "test",
+		Resources: map[string]schema.ResourceSpec{},
+		Functions: map[string]schema.FunctionSpec{},
+	}
+	for _, token := range resources {
+		spec.Resources[token] = schema.ResourceSpec{}
+	}
+	for _, token := range functions {
+		spec.Functions[token] = schema.FunctionSpec{}
+	}
+	pkg, diags, err := schema.BindSpec(spec, nil)
+	require.NoError(t, err)
+	require.False(t, diags.HasErrors(), diags.Error())
+	return NewResourcePackage(pkg.Reference())
+}
+
+func TestListTokens(t *testing.T) {
+	t.Parallel()
+
+	pkg := newSchemaPackage(t,
+		[]string{"test:s3/bucket:Bucket", "test:index:Thing", "test:ec2/vpc:Vpc"},
+		[]string{"test:s3/getBucket:getBucket", "test:index:getThing"})
+	assert.Equal(t, []string{"test:ec2/vpc:Vpc", "test:index:Thing", "test:s3/bucket:Bucket"}, pkg.ListResources())
+	assert.Equal(t, []string{"test:index:getThing", "test:s3/getBucket:getBucket"}, pkg.ListFunctions())
+}
+
+func TestUnknownResourceTypeSuggestion(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  bucket:
+    type: test:s3:Bukcet
+  thing:
+    type: test:Thnig
+  unrelated:
+    type: test:completely:Different
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	loader := MockPackageLoader{packages: map[string]Package{
+		"test": newSchemaPackage(t, []string{"test:s3/bucket:Bucket", "test:index:Thing"}, nil),
+	}}
+	_, diags := TypeCheck(newRunner(tmpl, loader))
+
+	var details []string
+	for _, d := range diags {
+		assert.True(t, strings.HasPrefix(d.Summary, "error resolving type of resource "), d.Summary)
+		details = append(details, d.Detail)
+	}
+	assert.Equal(t, []string{`Did you mean "test:s3:Bucket"?`, `Did you mean "test:Thing"?`, ""}, details)
+}
@@ -0,0 +1,106 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi-yaml/pkg/pulumiyaml/ast"
+	"github.com/pulumi/pulumi-yaml/pkg/pulumiyaml/syntax"
+)
+
+// A ResourceTypePolicy restricts the resource types a template may declare.
+//
+// Patterns are matched against canonical type tokens, such as "aws:s3/bucket:Bucket", and may use '*' to match any
+// sequence of characters, e.g. "aws:s3*" or "*:index:Provider".
+type ResourceTypePolicy struct {
+	// Allow lists the permitted types. If it is empty, every type that is not denied is permitted.
+	Allow []string
+	// Deny lists the forbidden types. A type matching Deny is forbidden even if it also matches Allow.
+	Deny []string
+}
+
+// A ResourceTypeViolation describes a resource whose type is not permitted by a ResourceTypePolicy.
+type ResourceTypeViolation struct {
+	// Resource is the logical name of the resource.
+	Resource string
+	// Type is the canonical type token of the resource.
+	Type ResourceTypeToken
+	// Pattern is the Deny pattern the type matched, or "" if the type did not match any Allow pattern.
+	Pattern string
+}
+
+// CheckResourceTypes reports the resources in a template whose types are not permitted by policy. Type tokens are
+// resolved with loader, so aliases such as "aws:s3:Bucket" are checked under their canonical name.
+//
+// Each violation is also reported as an error diagnostic on the resource's type. A resource whose type cannot be
+// resolved is reported as an error diagnostic, but not as a violation.
+func CheckResourceTypes(tmpl *ast.TemplateDecl, loader PackageLoader, policy ResourceTypePolicy) ([]ResourceTypeViolation, syntax.Diagnostics) {
+	allow, deny := compileTypePatterns(policy.Allow), compileTypePatterns(policy.Deny)
+
+	var violations []ResourceTypeViolation
+	var diags syntax.Diagnostics
+	for _, entry := range tmpl.Resources.Entries {
+		k, v := entry.Key.Value, entry.Value
+		if v == nil || v.Type == nil {
+			continue
+		}
+		version, err := ParseVersion(v.Options.Version)
+		if err != nil {
+			diags.Extend(ast.ExprError(v.Type, fmt.Sprintf("unable to parse resource %v provider version: %v", k, err), ""))
+			continue
+		}
+		_, typ, err := ResolveResource(loader, v.Type.Value, version)
+		if err != nil {
+			diags.Extend(ast.ExprError(v.Type, fmt.Sprintf("error resolving type of resource %v: %v", k, err), ""))
+			continue
+		}
+
+		violation := ResourceTypeViolation{Resource: k, Type: typ}
+		if pattern, ok := matchTypePattern(deny, typ); ok {
+			violation.Pattern = pattern
+			diags.Extend(ast.ExprError(v.Type,
+				fmt.Sprintf("resource %v has type %v, which is denied by the pattern %q", k, typ, pattern), ""))
+		} else if _, ok := matchTypePattern(allow, typ); len(allow) > 0 && !ok {
+			diags.Extend(ast.ExprError(v.Type,
+				fmt.Sprintf("resource %v has type %v, which does not match any allowed pattern", k, typ),
+				fmt.Sprintf("Allowed patterns are %v", strings.Join(policy.Allow, ", "))))
+		} else {
+			continue
+		}
+		violations = append(violations, violation)
+	}
+	return violations, diags
+}
+
+type typePattern struct {
+	text string
+	re   *regexp.Regexp
+}
+
+func compileTypePatterns(patterns []string) []typePattern {
+	compiled := make([]typePattern, len(patterns))
+	for i, p := range patterns {
+		parts := strings.Split(p, "*")
+		for j, part := range parts {
+			parts[j] = regexp.QuoteMeta(part)
+		}
+		compiled[i] = typePattern{
+			text: p,
+			re:   regexp.MustCompile("^" + strings.Join(parts, ".*") + "$"),
+		}
+	}
+	return compiled
+}
+
+// matchTypePattern returns the first pattern that matches typ.
+func matchTypePattern(patterns []typePattern, typ ResourceTypeToken) (string, bool) {
+	for _, p := range patterns {
+		if p.re.MatchString(typ.String()) {
+			return p.text, true
+		}
+	}
+	return "", false
+}
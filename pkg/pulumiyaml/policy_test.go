@@ -0,0 +1,92 @@
+// Copyright 2022, Pulumi Corporation.  All rights reserved.
+
+package pulumiyaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckResourceTypes(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-policy
+runtime: yaml
+resources:
+  bucket:
+    type: aws:s3:Bucket
+  instance:
+    type: aws:ec2/instance:Instance
+  network:
+    type: docker:index:Network
+  thing:
+    type: test:resource:type
+`
+	loader := MockPackageLoader{
+		packages: map[string]Package{
+			"aws": MockPackage{
+				resolveResource: func(typeName string) (ResourceTypeToken, error) {
+					if typeName == "aws:s3:Bucket" {
+						return "aws:s3/bucket:Bucket", nil
+					}
+					return ResourceTypeToken(typeName), nil
+				},
+			},
+			"docker": MockPackage{},
+			"test":   MockPackage{},
+		},
+	}
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+
+	violations, diags := CheckResourceTypes(tmpl, loader, ResourceTypePolicy{
+		Allow: []string{"aws:*", "test:*"},
+		Deny:  []string{"aws:ec2*"},
+	})
+	assert.Equal(t, []ResourceTypeViolation{
+		{Resource: "instance", Type: "aws:ec2/instance:Instance", Pattern: "aws:ec2*"},
+		{Resource: "network", Type: "docker:index:Network"},
+	}, violations)
+
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:7:11: resource instance has type aws:ec2/instance:Instance, which is denied by the pattern "aws:ec2*"`,
+		"<stdin>:9:11: resource network has type docker:index:Network, which does not match any allowed pattern; " +
+			"Allowed patterns are aws:*, test:*",
+	}, diagStrings)
+}
+
+func TestCheckResourceTypesMatchesCanonicalTokens(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-policy
+runtime: yaml
+resources:
+  bucket:
+    type: aws:s3:Bucket
+`
+	loader := MockPackageLoader{
+		packages: map[string]Package{
+			"aws": MockPackage{
+				resolveResource: func(typeName string) (ResourceTypeToken, error) {
+					return "aws:s3/bucket:Bucket", nil
+				},
+			},
+		},
+	}
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+
+	violations, diags := CheckResourceTypes(tmpl, loader, ResourceTypePolicy{
+		Deny: []string{"aws:s3/bucket:*"},
+	})
+	assert.Equal(t, []ResourceTypeViolation{
+		{Resource: "bucket", Type: "aws:s3/bucket:Bucket", Pattern: "aws:s3/bucket:*"},
+	}, violations)
+	assert.True(t, diags.HasErrors())
+}
@@ -4,22 +4,30 @@ package pulumiyaml
 
 import (
 	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
 	b64 "encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	gotemplate "text/template"
+	"time"
 	"unicode/utf8"
 
 	"github.com/google/shlex"
+	"github.com/google/uuid"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
@@ -118,22 +126,35 @@ func LoadYAML(filename string, r io.Reader) (*ast.TemplateDecl, syntax.Diagnosti
 func LoadYAMLBytes(filename string, source []byte) (*ast.TemplateDecl, syntax.Diagnostics, error) {
 	var diags syntax.Diagnostics
 
-	syn, sdiags := encoding.DecodeYAML(filename, yaml.NewDecoder(bytes.NewReader(source)), TagDecoder)
-	diags.Extend(sdiags...)
+	docs, sdiags := encoding.DecodeYAMLDocuments(filename, yaml.NewDecoder(bytes.NewReader(source)), TagDecoder)
+	diags.Extend(sdiags.Attribute(syntax.SourceParse)...)
 	if sdiags.HasErrors() {
 		return nil, diags, nil
 	}
 
-	t, tdiags := ast.ParseTemplate(source, syn)
+	t, tdiags := ast.ParseTemplate(source, docs[0])
 	diags.Extend(tdiags...)
 	if tdiags.HasErrors() {
 		return nil, diags, nil
 	}
+	// Additional `---` separated documents are merged into the first.
+	for _, doc := range docs[1:] {
+		other, tdiags := ast.ParseTemplate(source, doc)
+		diags.Extend(tdiags...)
+		if tdiags.HasErrors() {
+			return nil, diags, nil
+		}
+		mdiags := t.Merge(other)
+		diags.Extend(mdiags...)
+		if mdiags.HasErrors() {
+			return nil, diags, nil
+		}
+	}
 	if t.Configuration.Entries != nil {
 		diags = append(diags, syntax.Warning(nil, "Pulumi.yaml: root-level `configuration` field is deprecated; please use `config` instead.", ""))
 	}
 
-	return t, diags, nil
+	return t, diags.Attribute(syntax.SourceParse), nil
 }
 
 // LoadTemplate decodes a Template value into a YAML template.
@@ -141,7 +162,7 @@ func LoadTemplate(t *Template) (*ast.TemplateDecl, syntax.Diagnostics) {
 	var diags syntax.Diagnostics
 
 	syn, sdiags := encoding.DecodeValue(t)
-	diags.Extend(sdiags...)
+	diags.Extend(sdiags.Attribute(syntax.SourceParse)...)
 	if sdiags.HasErrors() {
 		return nil, diags
 	}
@@ -189,6 +210,21 @@ func (r *Runner) validateResources() {
 				v.Syntax(),
 				fmt.Sprintf("Required field 'type' is missing on resource \"%s\"", resource.Key.Value), ""))
 		}
+
+		// The logical name (the key, unless overridden by `name`) becomes part of the resource's URN, which
+		// uses "::" to separate its components.
+		name := resource.Key
+		if v.Name != nil {
+			name = v.Name
+		}
+		switch {
+		case name.Value == "":
+			r.sdiags.Extend(ast.ExprError(name, fmt.Sprintf("resource \"%s\" must have a non-empty name", resource.Key.Value), ""))
+		case strings.Contains(name.Value, "::"):
+			r.sdiags.Extend(ast.ExprError(name,
+				fmt.Sprintf("resource name \"%s\" must not contain \"::\"", name.Value),
+				"\"::\" separates the components of a resource URN"))
+		}
 	}
 }
 
@@ -330,6 +366,11 @@ func PrepareTemplate(t *ast.TemplateDecl, r *Runner, loader PackageLoader) (*Run
 	// to r.setDefaultProviders.
 	r.setIntermediates("", nil, nil, true /*force*/)
 
+	// Warm the loader with the packages the template uses. Conflicting versions and packages that fail to load are
+	// reported with the location of each reference when the template is type checked, so the preload's own
+	// diagnostics would only repeat them.
+	PreloadPackages(t, loader)
+
 	// do some basic validation of each resource
 	r.validateResources()
 
@@ -338,12 +379,170 @@ func PrepareTemplate(t *ast.TemplateDecl, r *Runner, loader PackageLoader) (*Run
 
 	// runner type checks nodes
 	_, diags := TypeCheck(r)
+	diags.Extend(r.checkUnused()...)
 	return r, diags, nil
 }
 
+// A RunOption configures how RunTemplate evaluates a template.
+type RunOption func(*Runner)
+
+// ResourceHookArgs describes a resource that is about to be registered. A ResourceHook may modify its fields.
+type ResourceHookArgs struct {
+	// Type is the canonical type token of the resource. It cannot be changed.
+	Type ResourceTypeToken
+	// Name is the name the resource will be registered with. It includes the template's name prefix, if any.
+	Name string
+	// Properties holds the evaluated inputs of the resource, or its state if it is read with get. Values may be
+	// outputs.
+	Properties map[string]interface{}
+	// Options holds the resource options the resource will be registered with. Options appended here take
+	// precedence over those given in the template.
+	Options []pulumi.ResourceOption
+
+	warnings []string
+}
+
+// Warn attaches a warning to the resource's declaration in the template.
+func (a *ResourceHookArgs) Warn(format string, args ...interface{}) {
+	a.warnings = append(a.warnings, fmt.Sprintf(format, args...))
+}
+
+// A ResourceHook is called for every resource before it is registered, after its properties and options have been
+// evaluated. Returning an error prevents the resource from being registered and is reported as a diagnostic on the
+// resource's declaration. Properties that the hook adds or changes must be properties of the resource's type, with
+// values of the property's type.
+type ResourceHook func(args *ResourceHookArgs) error
+
+// WithResourceHook adds a hook that is called for every resource in the template before it is registered. Hooks
+// run in the order they are added. This lets a program embedding a template enforce policy, such as mandatory tags
+// or naming conventions, without editing the template.
+func WithResourceHook(hook ResourceHook) RunOption {
+	return func(r *Runner) {
+		r.resourceHooks = append(r.resourceHooks, hook)
+	}
+}
+
+// checkHookProperties checks the properties that resource hooks added or changed against the declared properties of
+// the resource, since the type checker only saw the properties in the template.
+func (e *programEvaluator) checkHookProperties(key ast.Expr, name string, declared []*schema.Property,
+	before, after map[string]interface{}) bool {
+	types := make(map[string]schema.Type, len(declared))
+	for _, prop := range declared {
+		types[prop.Name] = prop.Type
+	}
+	keys := make([]string, 0, len(after))
+	for k := range after {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ok := true
+	for _, k := range keys {
+		v := after[k]
+		if old, existed := before[k]; existed && reflect.DeepEqual(old, v) {
+			continue
+		}
+		typ, known := types[k]
+		if !known {
+			e.errorf(key, "resource hook set property %v of resource %v, which is not a property of its type", k, name)
+			ok = false
+		} else if err := checkValueType(k, typ, v); err != nil {
+			e.errorf(key, "resource hook set an invalid value for resource %v: %v", name, err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// checkValueType checks that v, an evaluated value found at path, has type typ. Outputs are not checked, since their
+// values may not be known yet.
+func checkValueType(path string, typ schema.Type, v interface{}) error {
+	if _, isOutput := v.(pulumi.Output); isOutput || v == nil {
+		return nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("%v must be %v, not %v", path, displayType(typ), typeString(v))
+	}
+	switch typ := typ.(type) {
+	case *schema.OptionalType:
+		return checkValueType(path, typ.ElementType, v)
+	case *schema.InputType:
+		return checkValueType(path, typ.ElementType, v)
+	case *schema.EnumType:
+		return checkValueType(path, typ.ElementType, v)
+	case *schema.UnionType:
+		for _, elem := range typ.ElementTypes {
+			if checkValueType(path, elem, v) == nil {
+				return nil
+			}
+		}
+		return mismatch()
+	case *schema.ArrayType:
+		elems, ok := listElements(v)
+		if !ok {
+			return mismatch()
+		}
+		for i, elem := range elems {
+			if err := checkValueType(fmt.Sprintf("%v[%d]", path, i), typ.ElementType, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *schema.MapType:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := checkValueType(path+"."+k, typ.ElementType, obj[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *schema.ObjectType:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		for _, prop := range typ.Properties {
+			if err := checkValueType(path+"."+prop.Name, prop.Type, obj[prop.Name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var ok bool
+	switch typ {
+	case schema.StringType:
+		_, ok = v.(string)
+	case schema.BoolType:
+		_, ok = v.(bool)
+	case schema.NumberType:
+		_, ok = numberValue(v)
+	case schema.IntType:
+		n, isNumber := numberValue(v)
+		ok = isNumber && n == math.Trunc(n)
+	default:
+		// Assets, archives, resource references and untyped values are not checked.
+		ok = true
+	}
+	if !ok {
+		return mismatch()
+	}
+	return nil
+}
+
 // RunTemplate runs the programEvaluator against a template using the given request/settings.
-func RunTemplate(ctx *pulumi.Context, t *ast.TemplateDecl, config map[string]string, configPropertyMap resource.PropertyMap, loader PackageLoader) error {
+func RunTemplate(ctx *pulumi.Context, t *ast.TemplateDecl, config map[string]string, configPropertyMap resource.PropertyMap, loader PackageLoader, opts ...RunOption) error {
 	r := newRunner(t, loader)
+	for _, opt := range opts {
+		opt(r)
+	}
 	r.setIntermediates(ctx.Project(), config, configPropertyMap, false)
 	if r.sdiags.HasErrors() {
 		return &r.sdiags
@@ -360,7 +559,9 @@ func RunTemplate(ctx *pulumi.Context, t *ast.TemplateDecl, config map[string]str
 		return diags
 	}
 
-	// runtime evaluation here
+	// runtime evaluation here. Nothing reads the runner's values once the program has been evaluated, so they can
+	// be released as we go.
+	r.streamValues = true
 	diags.Extend(r.Evaluate(ctx)...)
 	if diags.HasErrors() {
 		return diags
@@ -412,6 +613,16 @@ type Runner struct {
 	// Used to store sorted nodes. A non `nil` value indicates that the runner
 	// is already setup for running.
 	intermediates []graphNode
+
+	// The evaluated template namePrefix, if any.
+	namePrefix string
+
+	// When set, Run releases the value of each variable once the last node that references it has been
+	// evaluated, so that large templates don't keep every intermediate value alive until the program exits.
+	streamValues bool
+
+	// Called for every resource before it is registered.
+	resourceHooks []ResourceHook
 }
 
 type evalContext struct {
@@ -555,6 +766,12 @@ func (st *lateboundProviderResourceState) GetResourceSchema() *schema.Resource {
 
 type poisonMarker struct{}
 
+// missingConfig stands in for a config value that is not set and has no default, but that the template interpolates
+// with a default. Interpolations with a default substitute it, and any other reference reports err.
+type missingConfig struct {
+	err error
+}
+
 // GetOutputs returns the resource's outputs.
 func (st poisonMarker) GetOutputs() pulumi.Output {
 	return nil
@@ -723,6 +940,14 @@ func (e programEvaluator) EvalResource(r *Runner, node resourceNode) bool {
 
 func (e programEvaluator) EvalOutput(r *Runner, node ast.PropertyMapEntry) bool {
 	ctx := r.newContext(node)
+	if value, condition := conditionalOutput(node.Value); condition != nil {
+		enabled, ok := e.evaluateBooleanOption(condition, "condition")
+		if !ok || !enabled {
+			// Disabled outputs are not exported. Errors have already been reported.
+			return true
+		}
+		node.Value = value
+	}
 	out, ok := e.registerOutput(node)
 	if !ok {
 		msg := fmt.Sprintf("Error registering output [%v]: %v", node.Key.Value, ctx.sdiags.Error())
@@ -738,7 +963,41 @@ func (e programEvaluator) EvalOutput(r *Runner, node ast.PropertyMapEntry) bool
 
 func (r *Runner) Evaluate(ctx *pulumi.Context) syntax.Diagnostics {
 	eCtx := r.newContext(nil)
-	return r.Run(programEvaluator{evalContext: eCtx, pulumiCtx: ctx})
+	diags := r.Run(programEvaluator{evalContext: eCtx, pulumiCtx: ctx})
+	return diags.Attribute(syntax.SourceRuntime)
+}
+
+// EvaluateExpr parses source as a standalone expression, e.g. `${res.out}` or `{"fn::toJSON": "${vars}"}`, and
+// evaluates it against the config, variables and resources that r has already evaluated. It is intended for
+// interactive debugging of templates, and should be called after Evaluate.
+func (r *Runner) EvaluateExpr(ctx *pulumi.Context, source string) (interface{}, syntax.Diagnostics) {
+	const filename = "<expr>"
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(source), &doc); err != nil {
+		return nil, syntax.Diagnostics{syntax.Error(nil, err.Error(), "")}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, syntax.Diagnostics{syntax.Error(nil, "expected a single expression", "")}
+	}
+
+	node, diags := encoding.UnmarshalYAML(filename, doc.Content[0], TagDecoder)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	expr, ediags := ast.ParseExpr(node)
+	diags.Extend(ediags...)
+	if ediags.HasErrors() {
+		return nil, diags
+	}
+
+	e := &programEvaluator{evalContext: r.newContext(expr), pulumiCtx: ctx}
+	v, ok := e.evaluateExpr(expr)
+	diags.Extend(e.sdiags.diags...)
+	if !ok {
+		return nil, diags
+	}
+	return v, diags
 }
 
 func getConfNodesFromMap(project string, configPropertyMap resource.PropertyMap) []configNode {
@@ -821,7 +1080,65 @@ func (r *Runner) Run(e Evaluator) syntax.Diagnostics {
 		return returnDiags()
 	}
 
-	for _, kvp := range r.intermediates {
+	if r.t.NamePrefix != nil {
+		ok := true
+		switch e := e.(type) {
+		case programEvaluator:
+			ok = e.evaluateNamePrefix(r.t.NamePrefix)
+		case walker:
+			ok = e.EvalNamePrefix(r, r.t.NamePrefix)
+		}
+		if !ok {
+			return returnDiags()
+		}
+	}
+
+	// Stop between nodes once the caller's context is done, so that an embedding host isn't left waiting on
+	// the remaining resources and invokes.
+	cancelled := func() bool {
+		if ctx == nil {
+			return false
+		}
+		if err := ctx.Context().Err(); err != nil {
+			r.sdiags.Extend(syntax.Error(nil, fmt.Sprintf("evaluation was cancelled: %v", err), ""))
+			return true
+		}
+		return false
+	}
+
+	var releases []variableRelease
+	if r.streamValues && ctx != nil {
+		releases = r.variableReleaseOrder()
+	}
+
+	// When the evaluator continues past errors, nodes that fail are recorded here, and nodes that reference them are
+	// skipped rather than reporting errors that follow from the first.
+	continueOnError := false
+	if w, ok := e.(walker); ok {
+		continueOnError = w.ContinueOnError
+	}
+	failed := map[string]struct{}{}
+	dependsOnFailure := func(node interface{}) bool {
+		if len(failed) == 0 {
+			return false
+		}
+		for name := range r.references(node) {
+			if _, ok := failed[name]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i, kvp := range r.intermediates {
+		if cancelled() {
+			return returnDiags()
+		}
+		if dependsOnFailure(kvp) {
+			failed[kvp.key().Value] = struct{}{}
+			continue
+		}
+		ok := true
 		switch kvp := kvp.(type) {
 		case configNode:
 			if ctx != nil {
@@ -831,9 +1148,7 @@ func (r *Runner) Run(e Evaluator) syntax.Diagnostics {
 				}
 			}
 
-			if !e.EvalConfig(r, kvp) {
-				return returnDiags()
-			}
+			ok = e.EvalConfig(r, kvp)
 		case variableNode:
 			if ctx != nil {
 				err := ctx.Log.Debug(fmt.Sprintf("Registering variable [%v]", kvp.Key.Value), &pulumi.LogArgs{})
@@ -841,9 +1156,7 @@ func (r *Runner) Run(e Evaluator) syntax.Diagnostics {
 					return returnDiags()
 				}
 			}
-			if !e.EvalVariable(r, kvp) {
-				return returnDiags()
-			}
+			ok = e.EvalVariable(r, kvp)
 		case resourceNode:
 			if ctx != nil {
 				err := ctx.Log.Debug(fmt.Sprintf("Registering resource [%v]", kvp.Key.Value), &pulumi.LogArgs{})
@@ -851,14 +1164,28 @@ func (r *Runner) Run(e Evaluator) syntax.Diagnostics {
 					return returnDiags()
 				}
 			}
-			if !e.EvalResource(r, kvp) {
+			ok = e.EvalResource(r, kvp)
+		}
+		if !ok {
+			if !continueOnError {
 				return returnDiags()
 			}
+			failed[kvp.key().Value] = struct{}{}
+		}
+		for len(releases) > 0 && releases[0].last == i {
+			delete(r.variables, releases[0].name)
+			releases = releases[1:]
 		}
 	}
 
 	for _, kvp := range r.t.Outputs.Entries {
-		if !e.EvalOutput(r, kvp) {
+		if cancelled() {
+			return returnDiags()
+		}
+		if dependsOnFailure(kvp) {
+			continue
+		}
+		if !e.EvalOutput(r, kvp) && !continueOnError {
 			return returnDiags()
 		}
 	}
@@ -866,17 +1193,177 @@ func (r *Runner) Run(e Evaluator) syntax.Diagnostics {
 	return returnDiags()
 }
 
+// variableRelease is a variable that can be released once the intermediate at index last has been evaluated.
+type variableRelease struct {
+	name string
+	last int
+}
+
+// variableReleaseOrder returns the variables that are not referenced by any later intermediate or output, in the
+// order they can be released. The references are collected without allocating for each node, so that streaming
+// doesn't cost more than it saves on large templates.
+func (r *Runner) variableReleaseOrder() []variableRelease {
+	lastUse := make(map[string]int, len(r.intermediates))
+	index := 0
+	use := func(name string) {
+		lastUse[name] = index
+	}
+	for i, node := range r.intermediates {
+		index = i
+		switch node := node.(type) {
+		case variableNode:
+			visitReferences(node.Value, use)
+		case resourceNode:
+			r.walkExprs(node, func(x ast.Expr) {
+				switch x.(type) {
+				case *ast.SymbolExpr, *ast.InterpolateExpr:
+					visitReferences(x, use)
+				}
+			})
+		}
+	}
+	// Variables referenced by outputs are kept until the end.
+	index = len(r.intermediates)
+	for _, node := range r.t.Outputs.Entries {
+		visitReferences(node.Value, use)
+	}
+
+	releases := make([]variableRelease, 0, len(r.t.Variables.Entries))
+	for i, node := range r.intermediates {
+		node, ok := node.(variableNode)
+		if !ok {
+			continue
+		}
+		last, ok := lastUse[node.Key.Value]
+		if !ok || last < i {
+			last = i
+		}
+		if last < len(r.intermediates) {
+			releases = append(releases, variableRelease{name: node.Key.Value, last: last})
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool { return releases[i].last < releases[j].last })
+	return releases
+}
+
+// references returns the root names referenced by the expressions of an intermediate or output.
+func (r *Runner) references(node interface{}) map[string]struct{} {
+	refs := map[string]struct{}{}
+	r.walkExprs(node, func(x ast.Expr) {
+		switch x := x.(type) {
+		case *ast.SymbolExpr:
+			refs[x.Property.RootName()] = struct{}{}
+		case *ast.InterpolateExpr:
+			for _, part := range x.Parts {
+				if part.Value != nil {
+					refs[part.Value.RootName()] = struct{}{}
+				}
+			}
+		}
+	})
+	return refs
+}
+
+// interpolatesWithDefault reports whether any expression in the template interpolates the config value named key with
+// a default, as in `${key:-default}`.
+func (r *Runner) interpolatesWithDefault(key string) bool {
+	found := false
+	visit := func(x ast.Expr) {
+		if x, ok := x.(*ast.InterpolateExpr); ok {
+			for _, part := range x.Parts {
+				if part.Value == nil || part.Default == nil {
+					continue
+				}
+				if name := part.Value.RootName(); name == key || stripConfigNamespace(r.t.Name.GetValue(), name) == key {
+					found = true
+				}
+			}
+		}
+	}
+	for _, node := range r.intermediates {
+		r.walkExprs(node, visit)
+	}
+	for _, node := range r.t.Outputs.Entries {
+		r.walkExprs(node, visit)
+	}
+	return found
+}
+
+// walkExprs calls visit on each of the expressions of an intermediate or output.
+func (r *Runner) walkExprs(node interface{}, visit func(ast.Expr)) {
+	w := walker{
+		VisitExpr: func(_ *evalContext, x ast.Expr) bool {
+			visit(x)
+			return true
+		},
+	}
+	switch node := node.(type) {
+	case configNode:
+		w.EvalConfig(r, node)
+	case variableNode:
+		w.EvalVariable(r, node)
+	case resourceNode:
+		w.EvalResource(r, node)
+	case ast.PropertyMapEntry:
+		w.EvalOutput(r, node)
+	}
+}
+
+// evaluateNamePrefix evaluates the template's namePrefix. This happens before any config, variable or resource has
+// been evaluated, so the prefix may only reference the `pulumi` variable.
+func (e *programEvaluator) evaluateNamePrefix(expr ast.Expr) bool {
+	if !e.checkNamePrefixReferences(expr) {
+		return false
+	}
+
+	prefix, ok := e.evaluateExpr(expr)
+	if !ok {
+		return false
+	}
+	s, ok := prefix.(string)
+	if !ok {
+		e.error(expr, fmt.Sprintf("namePrefix must be a string, not %v", typeString(prefix)))
+		return false
+	}
+	// "::" separates the components of a URN, so a name containing it can't be registered.
+	if strings.Contains(s, "::") {
+		e.error(expr, fmt.Sprintf("namePrefix must not contain \"::\", but evaluated to %q", s))
+		return false
+	}
+	e.namePrefix = s
+	return true
+}
+
+// checkNamePrefixReferences reports an error for each name the namePrefix references other than the `pulumi`
+// variable, which is the only one available when it is evaluated.
+func (ctx *evalContext) checkNamePrefixReferences(expr ast.Expr) bool {
+	var deps []*ast.StringExpr
+	getExpressionDependencies(&deps, expr)
+	ok := true
+	for _, dep := range deps {
+		if dep.Value != PulumiVarName {
+			ctx.error(dep, fmt.Sprintf("namePrefix cannot reference %q; only the %q variable is available when it is evaluated",
+				dep.Value, PulumiVarName))
+			ok = false
+		}
+	}
+	return ok
+}
+
 func (e *programEvaluator) registerConfig(intm configNode) (interface{}, bool) {
 	var expectedType ctypes.Type
 	var isSecretInConfig, markSecret bool
 	var defaultValue interface{}
 	var k string
 	var intmKey ast.Expr
+	var decl *ast.ConfigParamDecl
+	var pattern *regexp.Regexp
 
 	switch intm := intm.(type) {
 	case configNodeYaml:
 		k, intmKey = intm.Key.Value, intm.Key
 		c := intm.Value
+		decl = c
 		if c.Name != nil && c.Name.Value != "" {
 			k = c.Name.Value
 		}
@@ -885,44 +1372,52 @@ func (e *programEvaluator) registerConfig(intm configNode) (interface{}, bool) {
 		if c.Type == nil && c.Default == nil {
 			return e.errorf(intm.Key, "unable to infer type: either 'default' or 'type' is required")
 		}
+		if c.Type != nil {
+			t, ok := ctypes.Parse(c.Type.Value)
+			if !ok || !ctypes.IsValidType(t) {
+				return e.errorf(c.Type,
+					"unexpected configuration type '%s': valid types are %s",
+					c.Type.Value, ctypes.ConfigTypes)
+			}
+			expectedType = t
+		}
 		if c.Default != nil {
 			d, ok := e.evaluateExpr(c.Default)
 			if !ok {
 				return nil, false
 			}
-			var err error
-			expectedType, err = ctypes.TypeValue(d)
-			if err != nil {
-				return e.error(c.Default, err.Error())
+			if expectedType == nil {
+				var err error
+				expectedType, err = ctypes.TypeValue(d)
+				if err != nil {
+					return e.error(c.Default, err.Error())
+				}
+			} else {
+				// We have both a default value and a explicit type. Make sure they
+				// agree.
+				var err error
+				d, err = ctypes.Conform(expectedType, d)
+				var mismatchErr *ctypes.TypeMismatchErr
+				var elementErr *ctypes.ElementTypeErr
+				switch {
+				case errors.As(err, &elementErr):
+					return e.errorf(intm.Key, "type mismatch: %s of the default value has type %s but type %s was specified",
+						elementErr.Element(), elementErr.Actual, elementErr.Expected)
+				case errors.As(err, &mismatchErr):
+					return e.errorf(intm.Key, "type mismatch: default value of type %s but type %s was specified",
+						mismatchErr.Actual, mismatchErr.Expected)
+				case err != nil:
+					return e.error(c.Default, err.Error())
+				}
 			}
 			defaultValue = d
 		}
-		if c.Type != nil {
-			t, ok := ctypes.Parse(c.Type.Value)
-			if !ok {
-				return e.errorf(c.Type,
-					"unexpected configuration type '%s': valid types are %s",
-					c.Type.Value, ctypes.ConfigTypes)
-			}
-
-			// We have both a default value and a explicit type. Make sure they
-			// agree.
-			if ctypes.IsValidType(expectedType) && t != expectedType {
-				return e.errorf(intm.Key,
-					"type mismatch: default value of type %s but type %s was specified",
-					expectedType, t)
-			}
-
-			expectedType = t
-
+		var ok bool
+		if pattern, ok = e.checkConfigConstraintDecls(c, expectedType); !ok {
+			return nil, false
 		}
 		// A value is considered secret if either it is either marked as secret in
 		// the config section or the configuration section.
-		//
-		// We only want to execute a TrySecret* if the value is secret in the config
-		// section. It the value is specified as secret only in the configuration
-		// section, we call Try* normally, and later wrap the value with
-		// `pulumi.ToSecret`.
 		isSecretInConfig = e.pulumiCtx.IsConfigSecret(e.pulumiCtx.Project() + ":" + k)
 
 		if isSecretInConfig && c.Secret != nil && !c.Secret.Value {
@@ -931,10 +1426,7 @@ func (e *programEvaluator) registerConfig(intm configNode) (interface{}, bool) {
 					" if the associated config value is secret")
 		}
 
-		// We only want to mark a value as secret if it is not already secret. If
-		// isSecretInConfig is true, we will retrieve a secret value and thus won't need
-		// to mark it as secret (since it already will be).
-		if (c.Secret != nil && c.Secret.Value) && !isSecretInConfig {
+		if c.Secret != nil && c.Secret.Value {
 			markSecret = true
 		}
 	case configNodeProp:
@@ -955,132 +1447,234 @@ func (e *programEvaluator) registerConfig(intm configNode) (interface{}, bool) {
 	var err error
 	switch expectedType {
 	case ctypes.String:
-		if isSecretInConfig {
-			v, err = config.TrySecret(e.pulumiCtx, k)
-		} else {
-			v, err = config.Try(e.pulumiCtx, k)
-		}
+		v, err = config.Try(e.pulumiCtx, k)
 	case ctypes.Number:
-		if isSecretInConfig {
-			v, err = config.TrySecretFloat64(e.pulumiCtx, k)
-		} else {
-			v, err = config.TryFloat64(e.pulumiCtx, k)
-		}
+		v, err = config.TryFloat64(e.pulumiCtx, k)
 	case ctypes.Int:
-		if isSecretInConfig {
-			v, err = config.TrySecretInt(e.pulumiCtx, k)
-		} else {
-			v, err = config.TryInt(e.pulumiCtx, k)
-		}
+		v, err = config.TryInt(e.pulumiCtx, k)
 	case ctypes.Boolean:
-		if isSecretInConfig {
-			v, err = config.TrySecretBool(e.pulumiCtx, k)
-		} else {
-			v, err = config.TryBool(e.pulumiCtx, k)
-		}
+		v, err = config.TryBool(e.pulumiCtx, k)
 	case ctypes.NumberList:
 		var arr []float64
-		if isSecretInConfig {
-			v, err = config.TrySecretObject(e.pulumiCtx, k, &arr)
-		} else {
-			err = config.TryObject(e.pulumiCtx, k, &arr)
-			if err == nil {
-				v = arr
-			}
+		err = config.TryObject(e.pulumiCtx, k, &arr)
+		if err == nil {
+			v = arr
 		}
 	case ctypes.IntList:
 		var arr []int
-		if isSecretInConfig {
-			v, err = config.TrySecretObject(e.pulumiCtx, k, &arr)
-		} else {
-			err = config.TryObject(e.pulumiCtx, k, &arr)
-			if err != nil {
-				v = arr
-			}
+		err = config.TryObject(e.pulumiCtx, k, &arr)
+		if err == nil {
+			v = arr
 		}
 	case ctypes.StringList:
 		var arr []string
-		if isSecretInConfig {
-			v, err = config.TrySecretObject(e.pulumiCtx, k, &arr)
-		} else {
-			err = config.TryObject(e.pulumiCtx, k, &arr)
-			if err == nil {
-				v = arr
-			}
+		err = config.TryObject(e.pulumiCtx, k, &arr)
+		if err == nil {
+			v = arr
 		}
 	case ctypes.BooleanList:
 		var arr []bool
-		if isSecretInConfig {
-			v, err = config.TrySecretObject(e.pulumiCtx, k, &arr)
-		} else {
-			err = config.TryObject(e.pulumiCtx, k, &arr)
-			if err == nil {
-				v = arr
-			}
+		err = config.TryObject(e.pulumiCtx, k, &arr)
+		if err == nil {
+			v = arr
 		}
+	case ctypes.StringMap:
+		v, err = tryConfigMap[string](e.pulumiCtx, k)
+	case ctypes.NumberMap:
+		v, err = tryConfigMap[float64](e.pulumiCtx, k)
+	case ctypes.IntMap:
+		v, err = tryConfigMap[int](e.pulumiCtx, k)
+	case ctypes.BooleanMap:
+		v, err = tryConfigMap[bool](e.pulumiCtx, k)
 	}
 
+	var jsonTypeErr *json.UnmarshalTypeError
+	var jsonSyntaxErr *json.SyntaxError
+	if errors.As(err, &jsonTypeErr) || errors.As(err, &jsonSyntaxErr) {
+		return e.errorf(intmKey, "type mismatch: the configured value of %s is not of type %s", k, expectedType)
+	}
 	if errors.Is(err, config.ErrMissingVar) && defaultValue != nil {
 		v = defaultValue
+	} else if errors.Is(err, config.ErrMissingVar) && e.interpolatesWithDefault(intm.key().Value) {
+		// The value is optional where it is interpolated with a default, and required everywhere else.
+		return missingConfig{err: err}, true
 	} else if err != nil {
 		return e.errorf(intmKey, err.Error())
 	}
+	if !e.checkConfigConstraints(intmKey, k, decl, expectedType, pattern, v) {
+		return nil, false
+	}
 
 	contract.Assertf(v != nil, "let an uninitialized var slip through")
 
-	// The value was marked secret in the configuration section, but in the
-	// config section. We need to wrap it in `pulumi.ToSecret`.
-	if markSecret {
+	// The value is read as plaintext so that its constraints can be checked, and is marked secret afterwards.
+	if isSecretInConfig || markSecret {
 		v = pulumi.ToSecret(v)
 	}
 
 	return v, true
 }
 
-func (e *programEvaluator) registerResource(kvp resourceNode) (lateboundResource, bool) {
-	k, v := kvp.Key.Value, kvp.Value
-
-	// Read the properties and then evaluate them in case there are expressions contained inside.
-	props := make(map[string]interface{})
-	overallOk := true
-
-	var opts []pulumi.ResourceOption
-	version, err := ParseVersion(v.Options.Version)
-	if err != nil {
-		e.error(v.Options.Version, fmt.Sprintf("error parsing version of resource %v: %v", k, err))
-		return nil, true
+// checkConfigConstraintDecls checks that the minimum, maximum and pattern of a config declaration apply to its type,
+// returning the compiled pattern.
+func (e *programEvaluator) checkConfigConstraintDecls(c *ast.ConfigParamDecl, typ ctypes.Type) (*regexp.Regexp, bool) {
+	ok := true
+	checkBound := func(name string, bound *ast.NumberExpr) {
+		if bound != nil && typ != ctypes.Number && typ != ctypes.Int {
+			e.errorf(bound, "%s can only be used with Number or Integer config, not %s", name, typ)
+			ok = false
+		}
 	}
-	if version != nil {
-		opts = append(opts, pulumi.Version(version.String()))
+	checkBound("minimum", c.Minimum)
+	checkBound("maximum", c.Maximum)
+	if c.Minimum != nil && c.Maximum != nil && c.Minimum.Value > c.Maximum.Value {
+		e.errorf(c.Minimum, "the minimum (%v) must not be greater than the maximum (%v)", c.Minimum.Value, c.Maximum.Value)
+		ok = false
 	}
-
-	pkg, typ, err := ResolveResource(e.pkgLoader, v.Type.Value, version)
-	if err != nil {
-		e.error(v.Type, fmt.Sprintf("error resolving type of resource %v: %v", kvp.Key.Value, err))
-		overallOk = false
+	if c.Pattern == nil {
+		return nil, ok
 	}
-
-	readIntoProperties := func(obj ast.PropertyMapDecl) (poisonMarker, bool) {
-		for _, kvp := range obj.Entries {
-			vv, ok := e.evaluateExpr(kvp.Value)
-			if !ok {
-				overallOk = false
-			}
-			if p, ok := vv.(poisonMarker); ok {
-				return p, true
-			}
-			props[kvp.Key.Value] = vv
-		}
-		return poisonMarker{}, false
+	if typ != ctypes.String {
+		e.errorf(c.Pattern, "pattern can only be used with String config, not %s", typ)
+		return nil, false
 	}
-
-	if p, isPoison := readIntoProperties(v.Properties); isPoison {
-		return p, isPoison
+	pattern, err := regexp.Compile(c.Pattern.Value)
+	if err != nil {
+		e.errorf(c.Pattern, "invalid pattern %q: %v", c.Pattern.Value, err)
+		return nil, false
 	}
+	return pattern, ok
+}
 
-	if v.Options.Aliases != nil {
-		var aliases []pulumi.Alias
-		for _, s := range v.Options.Aliases.Elements {
+// checkConfigConstraints checks a configured value against the allowed values, minimum, maximum and pattern of its
+// declaration. The value itself is left out of the diagnostics, since it may be secret.
+func (e *programEvaluator) checkConfigConstraints(key ast.Expr, k string, c *ast.ConfigParamDecl, typ ctypes.Type,
+	pattern *regexp.Regexp, v interface{}) bool {
+	if c == nil || (c.AllowedValues == nil && c.Minimum == nil && c.Maximum == nil && pattern == nil) {
+		return true
+	}
+	var n float64
+	switch x := v.(type) {
+	case string:
+		if !e.checkConfigAllowedValues(key, k, c.AllowedValues, x) {
+			return false
+		}
+		if pattern != nil && !pattern.MatchString(x) {
+			e.errorf(key, "config value %s must match the pattern %q", k, pattern.String())
+			return false
+		}
+		return true
+	case int:
+		n = float64(x)
+	case float64:
+		n = x
+	default:
+		return true
+	}
+	if !e.checkConfigAllowedValues(key, k, c.AllowedValues, n) {
+		return false
+	}
+	if c.Minimum != nil && n < c.Minimum.Value {
+		e.errorf(key, "config value %s must be at least %v", k, c.Minimum.Value)
+		return false
+	}
+	if c.Maximum != nil && n > c.Maximum.Value {
+		e.errorf(key, "config value %s must be at most %v", k, c.Maximum.Value)
+		return false
+	}
+	return true
+}
+
+// checkConfigAllowedValues checks that a string or number config value is one of its allowed values, if there are
+// any.
+func (e *programEvaluator) checkConfigAllowedValues(key ast.Expr, k string, allowed *ast.ListExpr, v interface{}) bool {
+	if allowed == nil {
+		return true
+	}
+	permitted := make([]string, 0, len(allowed.Elements))
+	for _, elem := range allowed.Elements {
+		var value interface{}
+		switch elem := elem.(type) {
+		case *ast.StringExpr:
+			value = elem.Value
+		case *ast.NumberExpr:
+			value = elem.Value
+		default:
+			// The type checker reports allowed values that aren't constants.
+			continue
+		}
+		if value == v {
+			return true
+		}
+		permitted = append(permitted, fmt.Sprint(value))
+	}
+	e.errorf(key, "config value %s must be one of %s", k, strings.Join(permitted, ", "))
+	return false
+}
+
+// tryConfigMap reads a map-typed configuration value whose elements have type T, converting it to an object.
+func tryConfigMap[T any](ctx *pulumi.Context, k string) (interface{}, error) {
+	var m map[string]T
+	if err := config.TryObject(ctx, k, &m); err != nil {
+		return nil, err
+	}
+	return configObject(m), nil
+}
+
+// configObject converts a map decoded from a configuration value to the representation used for objects during
+// evaluation.
+func configObject[T any](m map[string]T) map[string]interface{} {
+	obj := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		obj[k] = v
+	}
+	return obj
+}
+
+func (e *programEvaluator) registerResource(kvp resourceNode) (lateboundResource, bool) {
+	k, v := kvp.Key.Value, kvp.Value
+
+	// Read the properties and then evaluate them in case there are expressions contained inside.
+	props := make(map[string]interface{})
+	overallOk := true
+
+	var opts []pulumi.ResourceOption
+	version, err := ParseVersion(v.Options.Version)
+	if err != nil {
+		e.error(v.Options.Version, fmt.Sprintf("error parsing version of resource %v: %v", k, err))
+		return nil, true
+	}
+	if version != nil {
+		opts = append(opts, pulumi.Version(version.String()))
+	}
+
+	pkg, typ, err := ResolveResource(e.pkgLoader, v.Type.Value, version)
+	if err != nil {
+		e.error(v.Type, fmt.Sprintf("error resolving type of resource %v: %v", kvp.Key.Value, err))
+		overallOk = false
+	}
+
+	readIntoProperties := func(obj ast.PropertyMapDecl) (poisonMarker, bool) {
+		for _, kvp := range obj.Entries {
+			vv, ok := e.evaluateExpr(kvp.Value)
+			if !ok {
+				overallOk = false
+			}
+			if p, ok := vv.(poisonMarker); ok {
+				return p, true
+			}
+			props[kvp.Key.Value] = vv
+		}
+		return poisonMarker{}, false
+	}
+
+	if p, isPoison := readIntoProperties(v.Properties); isPoison {
+		return p, isPoison
+	}
+
+	if v.Options.Aliases != nil {
+		var aliases []pulumi.Alias
+		for _, s := range v.Options.Aliases.Elements {
 			alias := pulumi.Alias{
 				URN: pulumi.URN(s.Value),
 			}
@@ -1103,7 +1697,12 @@ func (e *programEvaluator) registerResource(kvp resourceNode) (lateboundResource
 		opts = append(opts, pulumi.Timeouts(&cts))
 	}
 	if v.Options.DeleteBeforeReplace != nil {
-		opts = append(opts, pulumi.DeleteBeforeReplace(v.Options.DeleteBeforeReplace.Value))
+		deleteBeforeReplaceOpt, ok := e.evaluateBooleanResourceOption(v.Options.DeleteBeforeReplace, "deleteBeforeReplace", pulumi.DeleteBeforeReplace)
+		if !ok {
+			overallOk = false
+		} else if deleteBeforeReplaceOpt != nil {
+			opts = append(opts, deleteBeforeReplaceOpt)
+		}
 	}
 	if v.Options.DependsOn != nil {
 		dependOnOpt, ok := e.evaluateResourceListValuedOption(v.Options.DependsOn, "dependsOn")
@@ -1138,23 +1737,11 @@ func (e *programEvaluator) registerResource(kvp resourceNode) (lateboundResource
 		}
 	}
 	if v.Options.Protect != nil {
-		protectValue, ok := e.evaluateExpr(v.Options.Protect)
-		if ok {
-			if !hasOutputs(protectValue) {
-				protect, ok := protectValue.(bool)
-				if ok {
-					opts = append(opts, pulumi.Protect(protect))
-				} else {
-					e.error(v.Options.Protect, "protect must be a boolean value")
-					overallOk = false
-				}
-			} else {
-				e.error(v.Options.Protect, "protect must be not be an output")
-				overallOk = false
-			}
-		} else {
-			e.error(v.Options.Protect, "couldn't evaluate the 'protect' resource option")
+		protectOpt, ok := e.evaluateBooleanResourceOption(v.Options.Protect, "protect", pulumi.Protect)
+		if !ok {
 			overallOk = false
+		} else if protectOpt != nil {
+			opts = append(opts, protectOpt)
 		}
 	}
 
@@ -1202,8 +1789,13 @@ func (e *programEvaluator) registerResource(kvp resourceNode) (lateboundResource
 	if v.Options.ReplaceOnChanges != nil {
 		opts = append(opts, pulumi.ReplaceOnChanges(listStrings(v.Options.ReplaceOnChanges)))
 	}
-	if b := v.Options.RetainOnDelete; b != nil {
-		opts = append(opts, pulumi.RetainOnDelete(b.Value))
+	if v.Options.RetainOnDelete != nil {
+		retainOnDeleteOpt, ok := e.evaluateBooleanResourceOption(v.Options.RetainOnDelete, "retainOnDelete", pulumi.RetainOnDelete)
+		if !ok {
+			overallOk = false
+		} else if retainOnDeleteOpt != nil {
+			opts = append(opts, retainOnDeleteOpt)
+		}
 	}
 	if v.Options.DeletedWith != nil {
 		deletedWithOpt, ok := e.evaluateResourceValuedOption(v.Options.DeletedWith, "deletedWith")
@@ -1219,7 +1811,7 @@ func (e *programEvaluator) registerResource(kvp resourceNode) (lateboundResource
 
 	// Create either a latebound custom resource or latebound provider resource depending on
 	// whether the type token indicates a special provider type.
-	resourceName := k
+	resourceName := e.namePrefix + k
 	if v.Name != nil && v.Name.Value != "" {
 		resourceName = v.Name.Value
 	}
@@ -1302,6 +1894,36 @@ func (e *programEvaluator) registerResource(kvp resourceNode) (lateboundResource
 		}
 	}
 
+	if len(e.resourceHooks) > 0 {
+		before := make(map[string]interface{}, len(props))
+		for k, v := range props {
+			before[k] = v
+		}
+		args := &ResourceHookArgs{Type: typ, Name: resourceName, Properties: props, Options: opts}
+		for _, hook := range e.resourceHooks {
+			err := hook(args)
+			for _, w := range args.warnings {
+				e.addDiag(syntax.Warning(kvp.Key.Syntax().Syntax().Range(), w, ""))
+			}
+			args.warnings = nil
+			if err != nil {
+				e.errorf(kvp.Key, "resource hook rejected resource %v: %v", k, err)
+				return nil, false
+			}
+		}
+		if args.Properties == nil {
+			args.Properties = map[string]interface{}{}
+		}
+		declared := resourceSchema.InputProperties
+		if isRead {
+			declared = resourceSchema.Properties
+		}
+		if !e.checkHookProperties(kvp.Key, k, declared, before, args.Properties) {
+			return nil, false
+		}
+		resourceName, props, opts = args.Name, args.Properties, args.Options
+	}
+
 	// Now register the resulting resource with the engine.
 	if isComponent {
 		err = e.pulumiCtx.RegisterRemoteComponentResource(string(typ), resourceName, untypedArgs(props), res, opts...)
@@ -1404,6 +2026,49 @@ func (e *programEvaluator) evaluateResourceValuedOption(optionExpr ast.Expr, key
 	return res, true
 }
 
+// evaluateBooleanOption evaluates a boolean valued option such as an output's condition. The value
+// may be computed, e.g. from config, but must be known.
+func (e *programEvaluator) evaluateBooleanOption(optionExpr ast.Expr, key string) (bool, bool) {
+	value, ok := e.evaluateExpr(optionExpr)
+	if !ok {
+		return false, false
+	}
+	return e.booleanOptionValue(optionExpr, key, value)
+}
+
+// evaluateBooleanResourceOption evaluates a boolean valued resource option such as protect, returning
+// the option built by makeOption. During a preview the value may be unknown, in which case no option is
+// returned and the resource is previewed with the option's default; it must be known during an update.
+func (e *programEvaluator) evaluateBooleanResourceOption(optionExpr ast.Expr, key string,
+	makeOption func(bool) pulumi.ResourceOption,
+) (pulumi.ResourceOption, bool) {
+	value, ok := e.evaluateExpr(optionExpr)
+	if !ok {
+		return nil, false
+	}
+	if hasOutputs(value) && e.pulumiCtx.DryRun() {
+		return nil, true
+	}
+	b, ok := e.booleanOptionValue(optionExpr, key, value)
+	if !ok {
+		return nil, false
+	}
+	return makeOption(b), true
+}
+
+func (e *programEvaluator) booleanOptionValue(optionExpr ast.Expr, key string, value interface{}) (bool, bool) {
+	if hasOutputs(value) {
+		e.error(optionExpr, fmt.Sprintf("%s must not be an output", key))
+		return false, false
+	}
+	b, ok := value.(bool)
+	if !ok {
+		e.error(optionExpr, fmt.Sprintf("%s must be a boolean value, not %v", key, typeString(value)))
+		return false, false
+	}
+	return b, true
+}
+
 func asResource(value interface{}) (lateboundResource, error) {
 	switch d := value.(type) {
 	case lateboundResource:
@@ -1413,6 +2078,22 @@ func asResource(value interface{}) (lateboundResource, error) {
 	}
 }
 
+// conditionalOutput splits an output declared in the form
+//
+//	outputs:
+//	  endpoint:
+//	    fn::output:
+//	      value: ${server.endpoint}
+//	      condition: ${exposeEndpoint}
+//
+// into its value and condition. Outputs in any other form are returned as is, with a nil condition.
+func conditionalOutput(expr ast.Expr) (ast.Expr, ast.Expr) {
+	if out, ok := expr.(*ast.OutputExpr); ok {
+		return out.Value, out.Condition
+	}
+	return expr, nil
+}
+
 func (e *programEvaluator) registerOutput(kvp ast.PropertyMapEntry) (pulumi.Input, bool) {
 	out, ok := e.evaluateExpr(kvp.Value)
 	if !ok {
@@ -1468,14 +2149,44 @@ func (e *programEvaluator) evaluateExpr(x ast.Expr) (interface{}, bool) {
 		return e.evaluateBuiltinJoin(x)
 	case *ast.SplitExpr:
 		return e.evaluateBuiltinSplit(x)
+	case *ast.SplitRegexExpr:
+		return e.evaluateBuiltinSplitRegex(x)
 	case *ast.ToJSONExpr:
 		return e.evaluateBuiltinToJSON(x)
+	case *ast.FromJSONExpr:
+		return e.evaluateBuiltinFromJSON(x)
+	case *ast.ToYAMLExpr:
+		return e.evaluateBuiltinToYAML(x)
+	case *ast.SliceExpr:
+		return e.evaluateBuiltinSlice(x)
+	case *ast.SortExpr:
+		return e.evaluateBuiltinSort(x)
+	case *ast.UniqueExpr:
+		return e.evaluateBuiltinUnique(x)
+	case *ast.KeysExpr:
+		return e.evaluateBuiltinKeysValues(x, x.Object, false)
+	case *ast.ValuesExpr:
+		return e.evaluateBuiltinKeysValues(x, x.Object, true)
 	case *ast.SelectExpr:
 		return e.evaluateBuiltinSelect(x)
+	case *ast.ToStringExpr:
+		return e.evaluateBuiltinToString(x)
+	case *ast.ToNumberExpr:
+		return e.evaluateBuiltinToNumber(x)
 	case *ast.ToBase64Expr:
 		return e.evaluateBuiltinToBase64(x)
 	case *ast.FromBase64Expr:
 		return e.evaluateBuiltinFromBase64(x)
+	case *ast.Sha256Expr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Value}, func(args ...string) interface{} {
+			sum := sha256.Sum256([]byte(args[0]))
+			return hex.EncodeToString(sum[:])
+		})
+	case *ast.Sha1Expr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Value}, func(args ...string) interface{} {
+			sum := sha1.Sum([]byte(args[0])) //nolint:gosec
+			return hex.EncodeToString(sum[:])
+		})
 	case *ast.FileAssetExpr:
 		return e.evaluateInterpolatedBuiltinAssetArchive(x, x.Source)
 	case *ast.StringAssetExpr:
@@ -1494,10 +2205,93 @@ func (e *programEvaluator) evaluateExpr(x ast.Expr) (interface{}, bool) {
 			"Please use `pulumi:pulumi:StackReference`; see"+
 				"https://www.pulumi.com/docs/intro/concepts/stack/#stackreferences")
 		return e.evaluateBuiltinStackReference(x)
+	case *ast.UnsecretExpr:
+		return e.evaluateBuiltinUnsecret(x)
 	case *ast.SecretExpr:
 		return e.evaluateBuiltinSecret(x)
 	case *ast.ReadFileExpr:
 		return e.evaluateBuiltinReadFile(x)
+	case *ast.ReadDirExpr:
+		return e.evaluateBuiltinReadDir(x)
+	case *ast.GetEnvExpr:
+		return e.evaluateBuiltinGetEnv(x)
+	case *ast.UUIDExpr:
+		return e.evaluateBuiltinGenerated(uuid.NewString)
+	case *ast.TimestampExpr:
+		return e.evaluateBuiltinGenerated(func() string {
+			return time.Now().UTC().Format(time.RFC3339)
+		})
+	case *ast.BasenameExpr:
+		return e.evaluateBuiltinPath(x, x.Path, filepath.Base)
+	case *ast.DirnameExpr:
+		return e.evaluateBuiltinPath(x, x.Path, filepath.Dir)
+	case *ast.FileExtensionExpr:
+		return e.evaluateBuiltinPath(x, x.Path, filepath.Ext)
+	case *ast.DefaultsExpr:
+		return e.evaluateBuiltinDefaults(x)
+	case *ast.CoalesceListExpr:
+		return e.evaluateBuiltinCoalesceList(x)
+	case *ast.CoalesceEmptyStringExpr:
+		return e.evaluateBuiltinCoalesceEmptyString(x)
+	case *ast.MergeExpr:
+		return e.evaluateBuiltinMerge(x)
+	case *ast.TextTemplateExpr:
+		return e.evaluateBuiltinTemplate(x)
+	case *ast.CidrSubnetExpr:
+		return e.evaluateBuiltinCidr(x, x.Prefix, []ast.Expr{x.Newbits, x.Netnum}, func(prefix string, args []int) (string, error) {
+			return cidrSubnet(prefix, args[0], args[1])
+		})
+	case *ast.CidrHostExpr:
+		return e.evaluateBuiltinCidr(x, x.Prefix, []ast.Expr{x.Hostnum}, func(prefix string, args []int) (string, error) {
+			return cidrHost(prefix, args[0])
+		})
+	case *ast.RangeExpr:
+		return e.evaluateBuiltinRange(x)
+	case *ast.ReplaceExpr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Source, x.Old, x.New}, func(args ...string) interface{} {
+			return strings.ReplaceAll(args[0], args[1], args[2])
+		})
+	case *ast.TrimExpr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Value}, func(args ...string) interface{} {
+			return strings.TrimSpace(args[0])
+		})
+	case *ast.TrimPrefixExpr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Source, x.Prefix}, func(args ...string) interface{} {
+			return strings.TrimPrefix(args[0], args[1])
+		})
+	case *ast.TrimSuffixExpr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Source, x.Suffix}, func(args ...string) interface{} {
+			return strings.TrimSuffix(args[0], args[1])
+		})
+	case *ast.UpperExpr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Value}, func(args ...string) interface{} {
+			return strings.ToUpper(args[0])
+		})
+	case *ast.LowerExpr:
+		return e.evaluateBuiltinStrings(x, []ast.Expr{x.Value}, func(args ...string) interface{} {
+			return strings.ToLower(args[0])
+		})
+	case *ast.FormatExpr:
+		return e.evaluateBuiltinFormat(x)
+	case *ast.FormatNumberExpr:
+		return e.evaluateBuiltinFormatNumber(x)
+	case *ast.JSONPathExpr:
+		return e.evaluateBuiltinJSONPath(x)
+	case *ast.IfExpr:
+		return e.evaluateBuiltinIf(x)
+	case *ast.ContainsExpr:
+		return e.evaluateBuiltinContains(x)
+	case *ast.EqualsExpr:
+		return e.evaluateBuiltinEquals(x)
+	case *ast.NotExpr:
+		return e.evaluateBuiltinNot(x)
+	case *ast.AndExpr:
+		return e.evaluateBuiltinLogical(x, x.Values, false)
+	case *ast.OrExpr:
+		return e.evaluateBuiltinLogical(x, x.Values, true)
+	case *ast.OutputExpr:
+		// Outputs are unwrapped before they are evaluated.
+		return e.error(x, "fn::output may only be used as the value of an output")
 	default:
 		panic(fmt.Sprintf("fatal: invalid expr type %v", reflect.TypeOf(x)))
 	}
@@ -1578,6 +2372,10 @@ func (e *programEvaluator) evaluateInterpolations(x *ast.InterpolateExpr, b *str
 		b.WriteString(i.Text)
 
 		if i.Value != nil {
+			if i.Default != nil && e.isMissingConfig(i.Value.RootName()) {
+				b.WriteString(*i.Default)
+				continue
+			}
 			p, ok := e.evaluatePropertyAccess(x, i.Value)
 			if !ok {
 				return nil, false
@@ -1588,7 +2386,7 @@ func (e *programEvaluator) evaluateInterpolations(x *ast.InterpolateExpr, b *str
 
 			if o, ok := p.(pulumi.Output); ok {
 				return o.ApplyT(func(v interface{}) (interface{}, error) {
-					fmt.Fprintf(b, "%v", v)
+					writeInterpolation(b, i, v)
 					v, ok := e.evaluateInterpolations(x, b, parts[1:])
 					if !ok {
 						return nil, fmt.Errorf("runtime error")
@@ -1597,12 +2395,21 @@ func (e *programEvaluator) evaluateInterpolations(x *ast.InterpolateExpr, b *str
 				}), true
 			}
 
-			fmt.Fprintf(b, "%v", p)
+			writeInterpolation(b, i, p)
 		}
 	}
 	return b.String(), true
 }
 
+// writeInterpolation writes the value of an interpolation, or its default if the value is null.
+func writeInterpolation(b *strings.Builder, i ast.Interpolation, v interface{}) {
+	if v == nil && i.Default != nil {
+		b.WriteString(*i.Default)
+		return
+	}
+	fmt.Fprintf(b, "%v", v)
+}
+
 func unknownOutput() pulumi.Output {
 	return pulumi.UnsafeUnknownOutput(nil)
 }
@@ -1625,10 +2432,29 @@ func (e *programEvaluator) evaluatePropertyAccess(expr ast.Expr, access *ast.Pro
 	} else {
 		return e.error(expr, fmt.Sprintf("resource or variable named %q could not be found", resourceName))
 	}
+	if missing, ok := receiver.(missingConfig); ok {
+		return e.error(expr, missing.err.Error())
+	}
 
 	return e.evaluatePropertyAccessTail(expr, receiver, access.Accessors[1:])
 }
 
+// isMissingConfig reports whether name refers to a config value that is not set, but is interpolated with a default.
+func (e *programEvaluator) isMissingConfig(name string) bool {
+	if _, ok := e.resources[name]; ok {
+		return false
+	}
+	p, ok := e.config[name]
+	if !ok {
+		if _, ok := e.variables[name]; ok {
+			return false
+		}
+		p = e.config[stripConfigNamespace(e.pulumiCtx.Project(), name)]
+	}
+	_, missing := p.(missingConfig)
+	return missing
+}
+
 func (e *programEvaluator) evaluatePropertyAccessTail(expr ast.Expr, receiver interface{}, accessors []ast.PropertyAccessor) (interface{}, bool) {
 	var evaluateAccessF func(args ...interface{}) (interface{}, bool)
 	evaluateAccessF = e.lift(func(args ...interface{}) (interface{}, bool) {
@@ -1828,7 +2654,31 @@ func (e *programEvaluator) evaluatePropertyAccessTail(expr ast.Expr, receiver in
 // evaluateBuiltinInvoke evaluates the "Invoke" builtin, which enables templates to invoke arbitrary
 // data source functions, to fetch information like the current availability zone, lookup AMIs, etc.
 func (e *programEvaluator) evaluateBuiltinInvoke(t *ast.InvokeExpr) (interface{}, bool) {
-	args, ok := e.evaluateExpr(t.CallArgs)
+	callArgs := t.CallArgs
+	if t.PositionalArgs != nil {
+		version, err := ParseVersion(t.CallOpts.Version)
+		if err != nil {
+			return e.error(t.CallOpts.Version, fmt.Sprintf("unable to parse function provider version: %v", err))
+		}
+		pkg, functionName, err := ResolveFunction(e.pkgLoader, t.Token.Value, version)
+		if err != nil {
+			return e.error(t, err.Error())
+		}
+		named, diags := InvokeArguments(t, pkg.FunctionTypeHint(functionName))
+		if diags.HasErrors() {
+			for _, d := range diags {
+				e.addDiag(d)
+			}
+			return nil, false
+		}
+		callArgs = named
+	}
+
+	var argsExpr ast.Expr = callArgs
+	if t.ArgumentsExpr != nil {
+		argsExpr = t.ArgumentsExpr
+	}
+	args, ok := e.evaluateExpr(argsExpr)
 	if !ok {
 		return nil, false
 	}
@@ -1876,29 +2726,108 @@ func (e *programEvaluator) evaluateBuiltinInvoke(t *ast.InvokeExpr) (interface{}
 			e.error(t.CallOpts.Version, fmt.Sprintf("unable to parse function provider version: %v", err))
 			return nil, true
 		}
-		_, functionName, err := ResolveFunction(e.pkgLoader, t.Token.Value, version)
+		pkg, functionName, err := ResolveFunction(e.pkgLoader, t.Token.Value, version)
 		if err != nil {
 			return e.error(t, err.Error())
 		}
 
+		if _, ok := args[0].(map[string]interface{}); !ok && t.ArgumentsExpr != nil {
+			return e.error(t.ArgumentsExpr, fmt.Sprintf("the arguments of fn::invoke must be an object, not %v", typeString(args[0])))
+		}
+
+		if t.Collect != nil {
+			return e.collectInvoke(t, pkg, functionName, args[0], opts)
+		}
+
+		if err := e.pulumiCtx.Context().Err(); err != nil {
+			return e.error(t, fmt.Sprintf("fn::invoke of %s was cancelled: %v", t.Token.Value, err))
+		}
 		if err := e.pulumiCtx.Invoke(string(functionName), args[0], &result, opts...); err != nil {
 			return e.error(t, err.Error())
 		}
 
+		// Providers don't always mark the outputs that their schema declares secret, so mark them here.
+		if hint := pkg.FunctionTypeHint(functionName); hint != nil && hint.Outputs != nil {
+			for _, prop := range hint.Outputs.Properties {
+				if v, ok := result[prop.Name]; ok && prop.Secret {
+					result[prop.Name] = pulumi.ToSecret(v)
+				}
+			}
+		}
+
 		if t.Return.GetValue() == "" {
 			return result, true
 		}
 
-		retv, ok := result[t.Return.Value]
+		name, accessors := t.ReturnPath()
+		retv, ok := result[name]
 		if !ok {
-			e.error(t.Return, fmt.Sprintf("Unable to evaluate result[%v], result is: %+v", t.Return.Value, t.Return))
-			return e.error(t.Return, fmt.Sprintf("fn::invoke of %s did not contain a property '%s' in the returned value", t.Token.Value, t.Return.Value))
+			e.error(t.Return, fmt.Sprintf("Unable to evaluate result[%v], result is: %+v", name, t.Return))
+			return e.error(t.Return, fmt.Sprintf("fn::invoke of %s did not contain a property '%s' in the returned value", t.Token.Value, name))
+		}
+		if len(accessors) != 0 {
+			return e.evaluatePropertyAccessTail(t.Return, retv, accessors)
 		}
 		return retv, true
 	})
 	return performInvoke(args)
 }
 
+// collectInvoke calls a paginated function until it returns an empty continuation token, passing each token back
+// as an argument to the next call, and returns the items from every call as a single list.
+func (e *programEvaluator) collectInvoke(t *ast.InvokeExpr, pkg Package, functionName FunctionTypeToken,
+	args interface{}, opts []pulumi.InvokeOption,
+) (interface{}, bool) {
+	collect := t.Collect
+	callArgs := map[string]interface{}{}
+	if m, ok := args.(map[string]interface{}); ok {
+		for k, v := range m {
+			callArgs[k] = v
+		}
+	}
+
+	items := []interface{}{}
+	seen := map[string]struct{}{}
+	for {
+		if err := e.pulumiCtx.Context().Err(); err != nil {
+			return e.error(t, fmt.Sprintf("fn::invoke of %s was cancelled: %v", t.Token.Value, err))
+		}
+		result := map[string]interface{}{}
+		if err := e.pulumiCtx.Invoke(string(functionName), callArgs, &result, opts...); err != nil {
+			return e.error(t, err.Error())
+		}
+
+		switch page := result[collect.Items.Value].(type) {
+		case nil:
+		case []interface{}:
+			items = append(items, page...)
+		default:
+			return e.error(collect.Items, fmt.Sprintf("fn::invoke of %s returned %v for '%s', not a list",
+				t.Token.Value, typeString(page), collect.Items.Value))
+		}
+
+		token, ok := result[collect.Token.Value].(string)
+		if !ok || token == "" {
+			break
+		}
+		if _, ok := seen[token]; ok {
+			return e.error(collect.Token, fmt.Sprintf("fn::invoke of %s returned the continuation token %q more than once",
+				t.Token.Value, token))
+		}
+		seen[token] = struct{}{}
+		callArgs[collect.ArgumentName()] = token
+	}
+
+	if hint := pkg.FunctionTypeHint(functionName); hint != nil && hint.Outputs != nil {
+		for _, prop := range hint.Outputs.Properties {
+			if prop.Name == collect.Items.Value && prop.Secret {
+				return pulumi.ToSecret(items), true
+			}
+		}
+	}
+	return items, true
+}
+
 func (e *programEvaluator) evaluateBuiltinJoin(v *ast.JoinExpr) (interface{}, bool) {
 	overallOk := true
 
@@ -1937,12 +2866,15 @@ func (e *programEvaluator) evaluateBuiltinJoin(v *ast.JoinExpr) (interface{}, bo
 
 		strs := make([]string, len(parts))
 		for i, p := range parts {
-			str, ok := p.(string)
-			if !ok {
+			switch p := p.(type) {
+			case string:
+				strs[i] = p
+			case float64, int, bool:
+				// Scalars are joined as they would be interpolated.
+				strs[i] = fmt.Sprint(p)
+			default:
 				e.error(v.Values, fmt.Sprintf("the second argument to fn::join must be a list of strings, found %v at index %v", typeString(p), i))
 				overallOk = false
-			} else {
-				strs[i] = str
 			}
 		}
 
@@ -1955,6 +2887,9 @@ func (e *programEvaluator) evaluateBuiltinJoin(v *ast.JoinExpr) (interface{}, bo
 	return join(delim, items)
 }
 
+// errEmptySplitDelimiter is reported by both the type checker and the evaluator for an empty fn::split delimiter.
+const errEmptySplitDelimiter = "The delimiter of fn::split must not be empty"
+
 func (e *programEvaluator) evaluateBuiltinSplit(v *ast.SplitExpr) (interface{}, bool) {
 	delimiter, delimOk := e.evaluateExpr(v.Delimiter)
 	source, sourceOk := e.evaluateExpr(v.Source)
@@ -1974,11 +2909,43 @@ func (e *programEvaluator) evaluateBuiltinSplit(v *ast.SplitExpr) (interface{},
 		if !delimOk || !sourceOk {
 			return nil, false
 		}
+		if d == "" {
+			return e.error(v.Delimiter, errEmptySplitDelimiter)
+		}
 		return strings.Split(s, d), true
 	})
 	return split(delimiter, source)
 }
 
+func (e *programEvaluator) evaluateBuiltinSplitRegex(v *ast.SplitRegexExpr) (interface{}, bool) {
+	pattern, patternOk := e.evaluateExpr(v.Pattern)
+	source, sourceOk := e.evaluateExpr(v.Source)
+	if !patternOk || !sourceOk {
+		return nil, false
+	}
+
+	split := e.lift(func(args ...interface{}) (interface{}, bool) {
+		p, ok := args[0].(string)
+		if !ok {
+			return e.error(v.Pattern, fmt.Sprintf("the pattern of fn::splitRegex must be a string, not %v", typeString(args[0])))
+		}
+		s, ok := args[1].(string)
+		if !ok {
+			return e.error(v.Source, fmt.Sprintf("the source of fn::splitRegex must be a string, not %v", typeString(args[1])))
+		}
+		re := v.Regexp
+		if re == nil {
+			var err error
+			re, err = regexp.Compile(p)
+			if err != nil {
+				return e.error(v.Pattern, fmt.Sprintf("invalid regular expression %q: %v", p, err))
+			}
+		}
+		return re.Split(s, -1), true
+	})
+	return split(pattern, source)
+}
+
 func (e *programEvaluator) evaluateBuiltinToJSON(v *ast.ToJSONExpr) (interface{}, bool) {
 	value, ok := e.evaluateExpr(v.Value)
 	if !ok {
@@ -1996,6 +2963,44 @@ func (e *programEvaluator) evaluateBuiltinToJSON(v *ast.ToJSONExpr) (interface{}
 	return toJSON(value)
 }
 
+func (e *programEvaluator) evaluateBuiltinToYAML(v *ast.ToYAMLExpr) (interface{}, bool) {
+	value, ok := e.evaluateExpr(v.Value)
+	if !ok {
+		return nil, false
+	}
+
+	toYAML := e.lift(func(args ...interface{}) (interface{}, bool) {
+		// Maps are encoded with their keys sorted, so the result is stable across runs.
+		b, err := yaml.Marshal(args[0])
+		if err != nil {
+			e.error(v, fmt.Sprintf("failed to encode YAML: %v", err))
+			return "", false
+		}
+		return string(b), true
+	})
+	return toYAML(value)
+}
+
+func (e *programEvaluator) evaluateBuiltinFromJSON(v *ast.FromJSONExpr) (interface{}, bool) {
+	value, ok := e.evaluateExpr(v.Value)
+	if !ok {
+		return nil, false
+	}
+
+	fromJSON := e.lift(func(args ...interface{}) (interface{}, bool) {
+		s, ok := args[0].(string)
+		if !ok {
+			return e.error(v.Value, fmt.Sprintf("the argument to fn::fromJSON must be a string, not %v", typeString(args[0])))
+		}
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return e.error(v.Value, fmt.Sprintf("failed to decode JSON: %v", err))
+		}
+		return decoded, true
+	})
+	return fromJSON(value)
+}
+
 func (e *programEvaluator) evaluateBuiltinSelect(v *ast.SelectExpr) (interface{}, bool) {
 	index, ok := e.evaluateExpr(v.Index)
 	if !ok {
@@ -2005,6 +3010,13 @@ func (e *programEvaluator) evaluateBuiltinSelect(v *ast.SelectExpr) (interface{}
 	if !ok {
 		return nil, false
 	}
+	var def interface{}
+	if v.Default != nil {
+		def, ok = e.evaluateExpr(v.Default)
+		if !ok {
+			return nil, false
+		}
+	}
 
 	selectFn := e.lift(func(args ...interface{}) (interface{}, bool) {
 		indexArg := args[0]
@@ -2014,33 +3026,230 @@ func (e *programEvaluator) evaluateBuiltinSelect(v *ast.SelectExpr) (interface{}
 		if !ok {
 			return e.error(v.Index, fmt.Sprintf("index must be a number, not %v", typeString(indexArg)))
 		}
-		if float64(int(index)) != index || int(index) < 0 {
+		if float64(int(index)) != index {
 			// Cannot be a valid index, so we error
 			f := strconv.FormatFloat(index, 'f', -1, 64) // Manual formatting is so -3 does not get formatted as -3.0
-			return e.error(v.Index, fmt.Sprintf("index must be a positive integral, not %s", f))
+			return e.error(v.Index, fmt.Sprintf("index must be an integer, not %s", f))
 		}
 		intIndex := int(index)
+		if elems := reflect.ValueOf(elemsArg); elems.Kind() == reflect.Slice {
+			// Negative indices count back from the end of the list.
+			if intIndex < 0 {
+				intIndex += elems.Len()
+			}
+			if intIndex < 0 || intIndex >= elems.Len() {
+				if v.Default != nil {
+					return args[2], true
+				}
+				return e.error(v.Index, fmt.Sprintf("list index %v out-of-bounds for list of length %v", int(index), elems.Len()))
+			}
+		}
 
 		return e.evaluatePropertyAccessTail(v.Values, elemsArg, []ast.PropertyAccessor{&ast.PropertySubscript{Index: intIndex}})
 	})
-	return selectFn(index, values)
+	return selectFn(index, values, def)
 }
 
-func (e *programEvaluator) evaluateBuiltinFromBase64(v *ast.FromBase64Expr) (interface{}, bool) {
-	str, ok := e.evaluateExpr(v.Value)
+// evaluateBuiltinSort sorts a list of strings or numbers. The list must not mix the two.
+func (e *programEvaluator) evaluateBuiltinSort(v *ast.SortExpr) (interface{}, bool) {
+	values, ok := e.evaluateExpr(v.Values)
 	if !ok {
 		return nil, false
 	}
-	fromBase64 := e.lift(func(args ...interface{}) (interface{}, bool) {
-		s, ok := args[0].(string)
+	var descending interface{} = false
+	if v.Descending != nil {
+		descending, ok = e.evaluateExpr(v.Descending)
 		if !ok {
-			return e.error(v.Value, fmt.Sprintf("expected argument to fn::fromBase64 to be a string, got %v", typeString(args[0])))
+			return nil, false
 		}
-		b, err := b64.StdEncoding.DecodeString(s)
-		if err != nil {
-			return e.error(v.Value, fmt.Sprintf("fn::fromBase64 unable to decode %v, error: %v", args[0], err))
+	}
+
+	sortFn := e.lift(func(args ...interface{}) (interface{}, bool) {
+		elems, ok := listElements(args[0])
+		if !ok {
+			return e.error(v.Values, fmt.Sprintf("the argument to fn::sort must be a list, not %v", typeString(args[0])))
 		}
-		decoded := string(b)
+		desc, ok := args[1].(bool)
+		if !ok {
+			return e.error(v.Descending, fmt.Sprintf("the descending argument of fn::sort must be a boolean, not %v", typeString(args[1])))
+		}
+
+		sorted := make([]interface{}, len(elems))
+		copy(sorted, elems)
+		var byString bool
+		if len(sorted) > 0 {
+			_, byString = sorted[0].(string)
+		}
+		for _, elem := range sorted {
+			_, isString := elem.(string)
+			_, isNumber := numberValue(elem)
+			if !isString && !isNumber {
+				return e.error(v.Values, fmt.Sprintf("fn::sort can only sort strings and numbers, not %v", typeString(elem)))
+			}
+			if isString != byString {
+				return e.error(v.Values, "fn::sort cannot sort a list that mixes strings and numbers")
+			}
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if desc {
+				i, j = j, i
+			}
+			if byString {
+				return sorted[i].(string) < sorted[j].(string)
+			}
+			a, _ := numberValue(sorted[i])
+			b, _ := numberValue(sorted[j])
+			return a < b
+		})
+		return sorted, true
+	})
+	return sortFn(values, descending)
+}
+
+// evaluateBuiltinUnique removes duplicate elements from a list, keeping the first occurrence of each.
+func (e *programEvaluator) evaluateBuiltinUnique(v *ast.UniqueExpr) (interface{}, bool) {
+	values, ok := e.evaluateExpr(v.Values)
+	if !ok {
+		return nil, false
+	}
+
+	uniqueFn := e.lift(func(args ...interface{}) (interface{}, bool) {
+		elems, ok := listElements(args[0])
+		if !ok {
+			return e.error(v.Values, fmt.Sprintf("the argument to fn::unique must be a list, not %v", typeString(args[0])))
+		}
+
+		unique := []interface{}{}
+	elems:
+		for _, elem := range elems {
+			for _, seen := range unique {
+				if valuesEqual(elem, seen) {
+					continue elems
+				}
+			}
+			unique = append(unique, elem)
+		}
+		return unique, true
+	})
+	return uniqueFn(values)
+}
+
+func (e *programEvaluator) evaluateBuiltinSlice(v *ast.SliceExpr) (interface{}, bool) {
+	source, ok := e.evaluateExpr(v.Source)
+	if !ok {
+		return nil, false
+	}
+	start, ok := e.evaluateExpr(v.Start)
+	if !ok {
+		return nil, false
+	}
+	var end interface{}
+	if v.End != nil {
+		end, ok = e.evaluateExpr(v.End)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	sliceFn := e.lift(func(args ...interface{}) (interface{}, bool) {
+		elems := reflect.ValueOf(args[0])
+		if elems.Kind() != reflect.Slice {
+			return e.error(v.Source, fmt.Sprintf("the source of fn::slice must be a list, not %v", typeString(args[0])))
+		}
+		index := func(expr ast.Expr, arg interface{}) (int, bool) {
+			i, ok := arg.(float64)
+			if !ok {
+				e.error(expr, fmt.Sprintf("index must be a number, not %v", typeString(arg)))
+				return 0, false
+			}
+			if float64(int(i)) != i {
+				e.error(expr, fmt.Sprintf("index must be an integer, not %s", strconv.FormatFloat(i, 'f', -1, 64)))
+				return 0, false
+			}
+			return int(i), true
+		}
+
+		from, ok := index(v.Start, args[1])
+		if !ok {
+			return nil, false
+		}
+		to := elems.Len()
+		if v.End != nil {
+			if to, ok = index(v.End, args[2]); !ok {
+				return nil, false
+			}
+			if from > to {
+				return e.error(v, fmt.Sprintf("the start index of fn::slice (%v) must not be greater than its end index (%v)", from, to))
+			}
+		}
+
+		// Out of range indices are clamped to the bounds of the list.
+		clamp := func(i int) int {
+			if i < 0 {
+				return 0
+			}
+			if i > elems.Len() {
+				return elems.Len()
+			}
+			return i
+		}
+		from, to = clamp(from), clamp(to)
+		result := make([]interface{}, 0, to-from)
+		for i := from; i < to; i++ {
+			result = append(result, elems.Index(i).Interface())
+		}
+		return result, true
+	})
+	return sliceFn(source, start, end)
+}
+
+// evaluateBuiltinKeysValues evaluates fn::keys and fn::values. Keys are sorted, and values are
+// returned in the order of their keys, so the result is stable across runs.
+func (e *programEvaluator) evaluateBuiltinKeysValues(v ast.BuiltinExpr, object ast.Expr, values bool) (interface{}, bool) {
+	obj, ok := e.evaluateExpr(object)
+	if !ok {
+		return nil, false
+	}
+
+	keysValues := e.lift(func(args ...interface{}) (interface{}, bool) {
+		m := reflect.ValueOf(args[0])
+		if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+			return e.error(object, fmt.Sprintf("the argument to %v must be an object, not %v", v.Name().Value, typeString(args[0])))
+		}
+		keys := make([]string, 0, m.Len())
+		for _, k := range m.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		result := make([]interface{}, len(keys))
+		for i, k := range keys {
+			if values {
+				result[i] = m.MapIndex(reflect.ValueOf(k).Convert(m.Type().Key())).Interface()
+			} else {
+				result[i] = k
+			}
+		}
+		return result, true
+	})
+	return keysValues(obj)
+}
+
+func (e *programEvaluator) evaluateBuiltinFromBase64(v *ast.FromBase64Expr) (interface{}, bool) {
+	str, ok := e.evaluateExpr(v.Value)
+	if !ok {
+		return nil, false
+	}
+	fromBase64 := e.lift(func(args ...interface{}) (interface{}, bool) {
+		s, ok := args[0].(string)
+		if !ok {
+			return e.error(v.Value, fmt.Sprintf("expected argument to fn::fromBase64 to be a string, got %v", typeString(args[0])))
+		}
+		b, err := b64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return e.error(v.Value, fmt.Sprintf("fn::fromBase64 unable to decode %v, error: %v", args[0], err))
+		}
+		decoded := string(b)
 		if !utf8.ValidString(decoded) {
 			return e.error(v.Value, "fn::fromBase64 output is not a valid UTF-8 string")
 		}
@@ -2049,6 +3258,57 @@ func (e *programEvaluator) evaluateBuiltinFromBase64(v *ast.FromBase64Expr) (int
 	return fromBase64(str)
 }
 
+func (e *programEvaluator) evaluateBuiltinToString(v *ast.ToStringExpr) (interface{}, bool) {
+	value, ok := e.evaluateExpr(v.Value)
+	if !ok {
+		return nil, false
+	}
+	toString := e.lift(func(args ...interface{}) (interface{}, bool) {
+		switch x := args[0].(type) {
+		case string:
+			return x, true
+		case float64:
+			// Integral numbers are rendered without a fractional part, and large numbers without an exponent.
+			return strconv.FormatFloat(x, 'f', -1, 64), true
+		case int:
+			return strconv.Itoa(x), true
+		case bool:
+			return strconv.FormatBool(x), true
+		case lateboundResource:
+			return x.CustomResource().URN().ToStringOutput(), true
+		case []interface{}, map[string]interface{}:
+			return e.error(v.Value, fmt.Sprintf("fn::toString cannot convert %v to a string; use fn::toJSON instead", typeString(x)))
+		default:
+			return e.error(v.Value, fmt.Sprintf("fn::toString cannot convert %v to a string", typeString(x)))
+		}
+	})
+	return toString(value)
+}
+
+func (e *programEvaluator) evaluateBuiltinToNumber(v *ast.ToNumberExpr) (interface{}, bool) {
+	value, ok := e.evaluateExpr(v.Value)
+	if !ok {
+		return nil, false
+	}
+	toNumber := e.lift(func(args ...interface{}) (interface{}, bool) {
+		switch x := args[0].(type) {
+		case float64:
+			return x, true
+		case int:
+			return float64(x), true
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+			if err != nil {
+				return e.error(v.Value, fmt.Sprintf("fn::toNumber could not parse %q as a number", x))
+			}
+			return n, true
+		default:
+			return e.error(v.Value, fmt.Sprintf("the argument to fn::toNumber must be a string or a number, not %v", typeString(x)))
+		}
+	})
+	return toNumber(value)
+}
+
 func (e *programEvaluator) evaluateBuiltinToBase64(v *ast.ToBase64Expr) (interface{}, bool) {
 	str, ok := e.evaluateExpr(v.Value)
 	if !ok {
@@ -2064,8 +3324,510 @@ func (e *programEvaluator) evaluateBuiltinToBase64(v *ast.ToBase64Expr) (interfa
 	return toBase64(str)
 }
 
+// evaluateBuiltinPath evaluates the path helpers fn::basename, fn::dirname and fn::fileExtension,
+// which apply fn to a single string argument.
+func (e *programEvaluator) evaluateBuiltinPath(v ast.BuiltinExpr, path ast.Expr, fn func(string) string) (interface{}, bool) {
+	return e.evaluateBuiltinStrings(v, []ast.Expr{path}, func(args ...string) interface{} {
+		return fn(args[0])
+	})
+}
+
+// evaluateBuiltinStrings evaluates a builtin whose arguments must all be strings, applying fn once every argument
+// is known.
+func (e *programEvaluator) evaluateBuiltinStrings(v ast.BuiltinExpr, exprs []ast.Expr, fn func(args ...string) interface{}) (interface{}, bool) {
+	values := make([]interface{}, len(exprs))
+	for i, expr := range exprs {
+		value, ok := e.evaluateExpr(expr)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+	apply := e.lift(func(args ...interface{}) (interface{}, bool) {
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			s, ok := arg.(string)
+			if !ok {
+				return e.error(exprs[i], fmt.Sprintf("expected argument to %s to be a string, got %v", v.Name().Value, typeString(arg)))
+			}
+			strs[i] = s
+		}
+		return fn(strs...), true
+	})
+	return apply(values...)
+}
+
+func (e *programEvaluator) evaluateBuiltinDefaults(v *ast.DefaultsExpr) (interface{}, bool) {
+	target, targetOk := e.evaluateExpr(v.Target)
+	defaults, defaultsOk := e.evaluateExpr(v.Defaults)
+	if !targetOk || !defaultsOk {
+		return nil, false
+	}
+
+	apply := e.lift(func(args ...interface{}) (interface{}, bool) {
+		t, targetOk := args[0].(map[string]interface{})
+		if !targetOk {
+			e.error(v.Target, fmt.Sprintf("the first argument to fn::defaults must be an object, not %v", typeString(args[0])))
+		}
+		d, defaultsOk := args[1].(map[string]interface{})
+		if !defaultsOk {
+			e.error(v.Defaults, fmt.Sprintf("the second argument to fn::defaults must be an object, not %v", typeString(args[1])))
+		}
+		if !targetOk || !defaultsOk {
+			return nil, false
+		}
+
+		result := make(map[string]interface{}, len(t)+len(d))
+		for k, v := range d {
+			result[k] = v
+		}
+		for k, v := range t {
+			result[k] = v
+		}
+		return result, true
+	})
+	return apply(target, defaults)
+}
+
+func (e *programEvaluator) evaluateBuiltinCoalesceList(v *ast.CoalesceListExpr) (interface{}, bool) {
+	lists := make([]interface{}, len(v.Values.Elements))
+	for i, elem := range v.Values.Elements {
+		l, ok := e.evaluateExpr(elem)
+		if !ok {
+			return nil, false
+		}
+		lists[i] = l
+	}
+
+	apply := e.lift(func(args ...interface{}) (interface{}, bool) {
+		for i, arg := range args {
+			if arg == nil {
+				continue
+			}
+			l, ok := arg.([]interface{})
+			if !ok {
+				return e.error(v.Values.Elements[i], fmt.Sprintf("the arguments to fn::coalesceList must be lists, not %v", typeString(arg)))
+			}
+			if len(l) > 0 {
+				return l, true
+			}
+		}
+		return []interface{}{}, true
+	})
+	return apply(lists...)
+}
+
+func (e *programEvaluator) evaluateBuiltinCoalesceEmptyString(v *ast.CoalesceEmptyStringExpr) (interface{}, bool) {
+	values := make([]interface{}, len(v.Values.Elements))
+	for i, elem := range v.Values.Elements {
+		s, ok := e.evaluateExpr(elem)
+		if !ok {
+			return nil, false
+		}
+		values[i] = s
+	}
+
+	apply := e.lift(func(args ...interface{}) (interface{}, bool) {
+		for i, arg := range args {
+			if arg == nil {
+				continue
+			}
+			s, ok := arg.(string)
+			if !ok {
+				return e.error(v.Values.Elements[i], fmt.Sprintf("the arguments to fn::coalesceEmptyString must be strings, not %v", typeString(arg)))
+			}
+			if s != "" {
+				return s, true
+			}
+		}
+		return "", true
+	})
+	return apply(values...)
+}
+
+func (e *programEvaluator) evaluateBuiltinMerge(v *ast.MergeExpr) (interface{}, bool) {
+	objects := make([]interface{}, len(v.Values.Elements))
+	for i, elem := range v.Values.Elements {
+		o, ok := e.evaluateExpr(elem)
+		if !ok {
+			return nil, false
+		}
+		objects[i] = o
+	}
+
+	apply := e.lift(func(args ...interface{}) (interface{}, bool) {
+		result := map[string]interface{}{}
+		for i, arg := range args {
+			o, ok := arg.(map[string]interface{})
+			if !ok {
+				return e.error(v.Values.Elements[i], fmt.Sprintf("the arguments to fn::merge must be objects, not %v", typeString(arg)))
+			}
+			mergeObjects(result, o)
+		}
+		return result, true
+	})
+	return apply(objects...)
+}
+
+// mergeObjects merges src into dst, recursing into values that are objects in both. Values that are still outputs
+// are not known to be objects, so they replace whatever dst held.
+func mergeObjects(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcObj, srcOk := v.(map[string]interface{})
+		dstObj, dstOk := dst[k].(map[string]interface{})
+		if srcOk && dstOk {
+			merged := make(map[string]interface{}, len(dstObj))
+			mergeObjects(merged, dstObj)
+			mergeObjects(merged, srcObj)
+			dst[k] = merged
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+func (e *programEvaluator) evaluateBuiltinTemplate(v *ast.TextTemplateExpr) (interface{}, bool) {
+	text, textOk := e.evaluateExpr(v.Template)
+	data, dataOk := e.evaluateExpr(v.Data)
+	if !textOk || !dataOk {
+		return nil, false
+	}
+
+	render := e.lift(func(args ...interface{}) (interface{}, bool) {
+		text, ok := args[0].(string)
+		if !ok {
+			return e.error(v.Template, fmt.Sprintf("the first argument to fn::template must be a string, not %v", typeString(args[0])))
+		}
+		data, ok := args[1].(map[string]interface{})
+		if !ok {
+			return e.error(v.Data, fmt.Sprintf("the second argument to fn::template must be an object, not %v", typeString(args[1])))
+		}
+
+		tmpl, err := gotemplate.New("fn::template").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return e.error(v.Template, fmt.Sprintf("failed to parse template: %v", err))
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return e.error(v, fmt.Sprintf("failed to render template: %v", err))
+		}
+		return buf.String(), true
+	})
+	return render(text, data)
+}
+
+func (e *programEvaluator) evaluateBuiltinFormat(v *ast.FormatExpr) (interface{}, bool) {
+	exprs := append([]ast.Expr{v.Format}, v.Values...)
+	values := make([]interface{}, len(exprs))
+	for i, expr := range exprs {
+		value, ok := e.evaluateExpr(expr)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+
+	format := e.lift(func(args ...interface{}) (interface{}, bool) {
+		text, ok := args[0].(string)
+		if !ok {
+			return e.error(v.Format, fmt.Sprintf("the first argument to fn::format must be a string, not %v", typeString(args[0])))
+		}
+		values := args[1:]
+
+		// Numbers are always decoded as floats, which the integer verbs refuse to print. Pass whole numbers to those
+		// verbs as integers instead.
+		if verbs, ok := formatVerbs(text); ok {
+			for i, verb := range verbs {
+				if i >= len(values) || !strings.ContainsRune("*bcdoxXU", verb) {
+					continue
+				}
+				if f, ok := values[i].(float64); ok && f == math.Trunc(f) {
+					values[i] = int64(f)
+				}
+			}
+		}
+		return fmt.Sprintf(text, values...), true
+	})
+	return format(values...)
+}
+
+func (e *programEvaluator) evaluateBuiltinFormatNumber(v *ast.FormatNumberExpr) (interface{}, bool) {
+	value, valueOk := e.evaluateExpr(v.Value)
+	spec, specOk := e.evaluateExpr(v.Format)
+	if !valueOk || !specOk {
+		return nil, false
+	}
+
+	format := e.lift(func(args ...interface{}) (interface{}, bool) {
+		n, ok := args[0].(float64)
+		if !ok {
+			return e.error(v.Value, fmt.Sprintf("the first argument to fn::formatNumber must be a number, not %v", typeString(args[0])))
+		}
+		text, ok := args[1].(string)
+		if !ok {
+			return e.error(v.Format, fmt.Sprintf("the second argument to fn::formatNumber must be a string, not %v", typeString(args[1])))
+		}
+		f, err := parseNumberFormat(text)
+		if err != nil {
+			return e.error(v.Format, fmt.Sprintf("invalid number format %q: %v", text, err))
+		}
+		return f.format(n), true
+	})
+	return format(value, spec)
+}
+
+func (e *programEvaluator) evaluateBuiltinJSONPath(v *ast.JSONPathExpr) (interface{}, bool) {
+	value, valueOk := e.evaluateExpr(v.Value)
+	path, pathOk := e.evaluateExpr(v.Path)
+	if !valueOk || !pathOk {
+		return nil, false
+	}
+
+	query := e.lift(func(args ...interface{}) (interface{}, bool) {
+		text, ok := args[1].(string)
+		if !ok {
+			return e.error(v.Path, fmt.Sprintf("the second argument to fn::jsonPath must be a string, not %v", typeString(args[1])))
+		}
+		path, err := parseJSONPath(text)
+		if err != nil {
+			return e.error(v.Path, fmt.Sprintf("invalid JSONPath %q: %v", text, err))
+		}
+
+		matches := path.query(args[0])
+		if !path.definite() {
+			if matches == nil {
+				matches = []interface{}{}
+			}
+			return matches, true
+		}
+		if len(matches) == 0 {
+			return e.error(v, fmt.Sprintf("no value matches the JSONPath %q", text))
+		}
+		return matches[0], true
+	})
+	return query(value, path)
+}
+
+func (e *programEvaluator) evaluateBuiltinIf(v *ast.IfExpr) (interface{}, bool) {
+	condition, ok := e.evaluateExpr(v.Condition)
+	if !ok {
+		return nil, false
+	}
+
+	// When the condition is known, only the selected branch is evaluated.
+	if b, ok := condition.(bool); ok {
+		if b {
+			return e.evaluateExpr(v.Then)
+		}
+		return e.evaluateExpr(v.Else)
+	}
+	if _, ok := condition.(pulumi.Output); !ok {
+		return e.error(v.Condition, fmt.Sprintf("the condition of fn::if must be a boolean, not %v", typeString(condition)))
+	}
+
+	then, thenOk := e.evaluateExpr(v.Then)
+	els, elseOk := e.evaluateExpr(v.Else)
+	if !thenOk || !elseOk {
+		return nil, false
+	}
+	choose := e.lift(func(args ...interface{}) (interface{}, bool) {
+		b, ok := args[0].(bool)
+		if !ok {
+			return e.error(v.Condition, fmt.Sprintf("the condition of fn::if must be a boolean, not %v", typeString(args[0])))
+		}
+		if b {
+			return args[1], true
+		}
+		return args[2], true
+	})
+	return choose(condition, then, els)
+}
+
+func (e *programEvaluator) evaluateBuiltinEquals(v *ast.EqualsExpr) (interface{}, bool) {
+	left, leftOk := e.evaluateExpr(v.Left)
+	right, rightOk := e.evaluateExpr(v.Right)
+	if !leftOk || !rightOk {
+		return nil, false
+	}
+
+	equals := e.lift(func(args ...interface{}) (interface{}, bool) {
+		return reflect.DeepEqual(args[0], args[1]), true
+	})
+	return equals(left, right)
+}
+
+func (e *programEvaluator) evaluateBuiltinContains(v *ast.ContainsExpr) (interface{}, bool) {
+	collection, collectionOk := e.evaluateExpr(v.Collection)
+	value, valueOk := e.evaluateExpr(v.Value)
+	if !collectionOk || !valueOk {
+		return nil, false
+	}
+
+	contains := e.lift(func(args ...interface{}) (interface{}, bool) {
+		if elems, ok := listElements(args[0]); ok {
+			for _, elem := range elems {
+				if valuesEqual(elem, args[1]) {
+					return true, true
+				}
+			}
+			return false, true
+		}
+		m := reflect.ValueOf(args[0])
+		if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+			return e.error(v.Collection, fmt.Sprintf("the collection given to fn::contains must be a list or an object, not %v",
+				typeString(args[0])))
+		}
+		key, ok := args[1].(string)
+		if !ok {
+			return e.error(v.Value, fmt.Sprintf("the key given to fn::contains must be a string, not %v", typeString(args[1])))
+		}
+		return m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key())).IsValid(), true
+	})
+	return contains(collection, value)
+}
+
+func (e *programEvaluator) evaluateBuiltinNot(v *ast.NotExpr) (interface{}, bool) {
+	value, ok := e.evaluateExpr(v.Value)
+	if !ok {
+		return nil, false
+	}
+
+	not := e.lift(func(args ...interface{}) (interface{}, bool) {
+		b, ok := args[0].(bool)
+		if !ok {
+			return e.error(v.Value, fmt.Sprintf("the argument to fn::not must be a boolean, not %v", typeString(args[0])))
+		}
+		return !b, true
+	})
+	return not(value)
+}
+
+// evaluateBuiltinLogical evaluates fn::and (when short is false) and fn::or (when short is true). Evaluation stops at
+// the first known value equal to short. Values that are outputs are combined once they resolve.
+func (e *programEvaluator) evaluateBuiltinLogical(v ast.BuiltinExpr, values *ast.ListExpr, short bool) (interface{}, bool) {
+	var outputs []interface{}
+	var exprs []ast.Expr
+	for _, expr := range values.Elements {
+		value, ok := e.evaluateExpr(expr)
+		if !ok {
+			return nil, false
+		}
+		if _, ok := value.(pulumi.Output); ok {
+			outputs = append(outputs, value)
+			exprs = append(exprs, expr)
+			continue
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return e.error(expr, fmt.Sprintf("the arguments to %s must be booleans, not %v", v.Name().Value, typeString(value)))
+		}
+		if b == short {
+			return short, true
+		}
+	}
+	if len(outputs) == 0 {
+		return !short, true
+	}
+
+	combine := e.lift(func(args ...interface{}) (interface{}, bool) {
+		for i, arg := range args {
+			b, ok := arg.(bool)
+			if !ok {
+				return e.error(exprs[i], fmt.Sprintf("the arguments to %s must be booleans, not %v", v.Name().Value, typeString(arg)))
+			}
+			if b == short {
+				return short, true
+			}
+		}
+		return !short, true
+	})
+	return combine(outputs...)
+}
+
+// formatVerbs returns the verbs in a format string in the order they consume arguments. A '*' width or precision
+// is returned as a verb of its own. The second return value is false if the format string uses explicit argument
+// indexes, in which case the arguments cannot be matched to verbs in order.
+func formatVerbs(format string) ([]rune, bool) {
+	var verbs []rune
+	runes := []rune(format)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' {
+			continue
+		}
+		for i++; i < len(runes); i++ {
+			r := runes[i]
+			if r == '[' {
+				return nil, false
+			}
+			if r == '*' {
+				verbs = append(verbs, r)
+				continue
+			}
+			if strings.ContainsRune("+-# 0.", r) || (r >= '0' && r <= '9') {
+				continue
+			}
+			if r != '%' {
+				verbs = append(verbs, r)
+			}
+			break
+		}
+	}
+	return verbs, true
+}
+
+// A numberFormat is a parsed fn::formatNumber spec: an optional ',' to group thousands, followed by an optional
+// '.N' to print exactly N decimal places.
+type numberFormat struct {
+	grouping  bool
+	precision int
+}
+
+func parseNumberFormat(spec string) (numberFormat, error) {
+	f := numberFormat{precision: -1}
+	rest := spec
+	if strings.HasPrefix(rest, ",") {
+		f.grouping = true
+		rest = rest[1:]
+	}
+	if strings.HasPrefix(rest, ".") {
+		precision, err := strconv.Atoi(rest[1:])
+		if err != nil || precision < 0 {
+			return numberFormat{}, fmt.Errorf("expected a number of decimal places after '.'")
+		}
+		f.precision = precision
+		rest = ""
+	}
+	if rest != "" {
+		return numberFormat{}, fmt.Errorf("expected an optional ',' followed by an optional '.N'")
+	}
+	return f, nil
+}
+
+func (f numberFormat) format(n float64) string {
+	text := strconv.FormatFloat(n, 'f', f.precision, 64)
+	if !f.grouping {
+		return text
+	}
+
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, frac := text, ""
+	if i := strings.IndexByte(text, '.'); i != -1 {
+		whole, frac = text[:i], text[i:]
+	}
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + frac
+}
+
 func (e *programEvaluator) evaluateBuiltinAssetArchive(v *ast.AssetArchiveExpr) (interface{}, bool) {
-	m := map[string]interface{}{}
 	keys := make([]string, len(v.AssetOrArchives))
 	i := 0
 	for k := range v.AssetOrArchives {
@@ -2076,13 +3838,13 @@ func (e *programEvaluator) evaluateBuiltinAssetArchive(v *ast.AssetArchiveExpr)
 
 	overallOk := true
 
-	for _, k := range keys {
-		v := v.AssetOrArchives[k]
-		assetOrArchive, ok := e.evaluateExpr(v)
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		assetOrArchive, ok := e.evaluateExpr(v.AssetOrArchives[k])
 		if !ok {
 			overallOk = false
 		} else {
-			m[k] = assetOrArchive
+			values[i] = assetOrArchive
 		}
 	}
 
@@ -2090,7 +3852,93 @@ func (e *programEvaluator) evaluateBuiltinAssetArchive(v *ast.AssetArchiveExpr)
 		return nil, false
 	}
 
-	return pulumi.NewAssetArchive(m), true
+	// Entries whose sources are outputs evaluate to outputs, so the archive can only be built once they resolve.
+	createArchiveF := e.lift(func(args ...interface{}) (interface{}, bool) {
+		m := map[string]interface{}{}
+		ok := true
+		for i, k := range keys {
+			value, entryOk := e.checkAssetArchiveEntry(k, v.AssetOrArchives[k], args[i])
+			ok = ok && entryOk
+			m[k] = value
+		}
+		if !ok {
+			return nil, false
+		}
+		return pulumi.NewAssetArchive(m), true
+	})
+	return createArchiveF(values...)
+}
+
+// errAssetArchiveReference is reported by both the type checker and the evaluator for an fn::assetArchive entry that
+// refers to a value that is neither an asset nor an archive.
+const errAssetArchiveReference = "fn::assetArchive entry %q must refer to an asset or an archive, not %s"
+
+// checkAssetArchiveEntry checks that the value of an fn::assetArchive entry is the kind its expression declares: an
+// asset for fn::fileAsset, fn::stringAsset and fn::remoteAsset, and an archive otherwise. A reference to another
+// value may refer to either.
+func (e *programEvaluator) checkAssetArchiveEntry(key string, expr ast.Expr, value interface{}) (interface{}, bool) {
+	_, isAsset := value.(pulumi.Asset)
+	_, isArchive := value.(pulumi.Archive)
+	switch expr.(type) {
+	case *ast.FileAssetExpr, *ast.StringAssetExpr, *ast.RemoteAssetExpr:
+		if !isAsset {
+			return e.errorf(expr, "fn::assetArchive entry %q must be an asset, not %s", key, assetOrArchiveKind(value))
+		}
+	case *ast.SymbolExpr:
+		if !isAsset && !isArchive {
+			return e.errorf(expr, errAssetArchiveReference, key, assetOrArchiveKind(value))
+		}
+	default:
+		if !isArchive {
+			return e.errorf(expr, "fn::assetArchive entry %q must be an archive, not %s", key, assetOrArchiveKind(value))
+		}
+	}
+	return value, true
+}
+
+// assetOrArchiveKind describes a value for diagnostics, distinguishing assets from archives.
+func assetOrArchiveKind(v interface{}) string {
+	switch v.(type) {
+	case pulumi.Asset:
+		return "an asset"
+	case pulumi.Archive:
+		return "an archive"
+	default:
+		return typeString(v)
+	}
+}
+
+// evaluateGlobArchive builds an archive of the files matching a glob passed to fn::fileArchive. The archive is keyed
+// by each file's path relative to the directory the glob starts from, so "./site/**" archives "./site/index.html" as
+// "index.html". A glob that matches no files is a warning, and evaluates to an empty archive.
+func (e *programEvaluator) evaluateGlobArchive(s ast.Expr, glob string, isConstant bool) (interface{}, bool) {
+	base, pattern := splitGlob(glob)
+	dir, err := e.sanitizePath(base, isConstant)
+	if errors.Is(err, os.ErrNotExist) {
+		// A directory that doesn't exist must still be one that files may be read from.
+		dir, err = e.sanitizePathWith(base, isConstant, false)
+	}
+	if err != nil {
+		return e.error(s, err.Error())
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		e.addDiag(syntax.Warning(s.Syntax().Syntax().Range(), fmt.Sprintf("no files match %v", glob),
+			fmt.Sprintf("%v is not a directory", base)))
+		return pulumi.NewAssetArchive(map[string]interface{}{}), true
+	}
+	files, err := globFiles(dir, pattern)
+	if err != nil {
+		return e.error(s, fmt.Sprintf("Error reading files matching %v: %v", glob, err))
+	}
+	if len(files) == 0 {
+		e.addDiag(syntax.Warning(s.Syntax().Syntax().Range(), fmt.Sprintf("no files match %v", glob), ""))
+	}
+
+	assets := make(map[string]interface{}, len(files))
+	for rel, path := range files {
+		assets[rel] = pulumi.NewFileAsset(path)
+	}
+	return pulumi.NewAssetArchive(assets), true
 }
 
 func (e *programEvaluator) evaluateBuiltinStackReference(v *ast.StackReferenceExpr) (interface{}, bool) {
@@ -2130,6 +3978,21 @@ func (e *programEvaluator) evaluateBuiltinSecret(s *ast.SecretExpr) (interface{}
 	return pulumi.ToSecret(expr), true
 }
 
+func (e *programEvaluator) evaluateBuiltinUnsecret(s *ast.UnsecretExpr) (interface{}, bool) {
+	expr, ok := e.evaluateExpr(s.Value)
+	if !ok {
+		return nil, false
+	}
+	if out, ok := expr.(pulumi.Output); ok {
+		return pulumi.Unsecret(out), true
+	}
+	// Lists and objects may hold secret outputs, which are only cleared once they're combined into one output.
+	if hasOutputs(expr) {
+		return pulumi.Unsecret(pulumi.ToOutput(expr)), true
+	}
+	return expr, true
+}
+
 func (e *programEvaluator) evaluateInterpolatedBuiltinAssetArchive(x, s ast.Expr) (interface{}, bool) {
 	_, isConstant := s.(*ast.StringExpr)
 	v, b := e.evaluateExpr(s)
@@ -2148,6 +4011,10 @@ func (e *programEvaluator) evaluateInterpolatedBuiltinAssetArchive(x, s ast.Expr
 			return pulumi.NewStringAsset(value), true
 		case *ast.FileArchiveExpr:
 			path, err := e.sanitizePath(value, isConstant)
+			if isGlob(value) && (err != nil || !pathExists(path)) {
+				// A path that exists is archived as is, even if its name contains glob characters.
+				return e.evaluateGlobArchive(s, value, isConstant)
+			}
 			if err != nil {
 				return e.error(s, err.Error())
 			}
@@ -2157,6 +4024,11 @@ func (e *programEvaluator) evaluateInterpolatedBuiltinAssetArchive(x, s ast.Expr
 			if err != nil {
 				return e.error(s, err.Error())
 			}
+			// The engine only reads the file when the resource using it is registered, so check for it here to
+			// report a missing file against the template.
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+				return e.error(x, fmt.Sprintf("file asset not found at path %v", path))
+			}
 			return pulumi.NewFileAsset(path), true
 		case *ast.RemoteArchiveExpr:
 			if !isConstant {
@@ -2177,10 +4049,16 @@ func (e *programEvaluator) evaluateInterpolatedBuiltinAssetArchive(x, s ast.Expr
 }
 
 func (e *programEvaluator) sanitizePath(path string, isConstant bool) (string, error) {
+	return e.sanitizePathWith(path, isConstant, !e.pulumiCtx.DryRun())
+}
+
+// sanitizePathWith is sanitizePath, but only follows symlinks if evalSymlinks is set. Symlinks can only be followed
+// for paths that exist.
+func (e *programEvaluator) sanitizePathWith(path string, isConstant, evalSymlinks bool) (string, error) {
 	path = filepath.Clean(path)
 	isAbsolute := filepath.IsAbs(path)
 	var err error
-	if !e.pulumiCtx.DryRun() {
+	if evalSymlinks {
 		path, err = filepath.EvalSymlinks(path)
 		if err != nil {
 			return "", fmt.Errorf("Error reading file at path %v: %w", path, err)
@@ -2234,6 +4112,13 @@ func (e *programEvaluator) evaluateBuiltinReadFile(s *ast.ReadFileExpr) (interfa
 	if !ok {
 		return nil, false
 	}
+	var maxBytes interface{}
+	if s.MaxBytes != nil {
+		maxBytes, ok = e.evaluateExpr(s.MaxBytes)
+		if !ok {
+			return nil, false
+		}
+	}
 
 	_, isConstant := s.Path.(*ast.StringExpr)
 
@@ -2246,14 +4131,240 @@ func (e *programEvaluator) evaluateBuiltinReadFile(s *ast.ReadFileExpr) (interfa
 		if err != nil {
 			return e.error(s, err.Error())
 		}
+		if args[1] != nil {
+			limit, ok := args[1].(float64)
+			if !ok || limit < 0 || limit != math.Trunc(limit) {
+				return e.error(s.MaxBytes, fmt.Sprintf("maxBytes must be a non-negative integer, not %v", args[1]))
+			}
+			if info, err := os.Stat(path); err == nil && info.Size() > int64(limit) {
+				return e.error(s.Path, fmt.Sprintf("file at path %v is %d bytes, which exceeds maxBytes (%d)",
+					path, info.Size(), int64(limit)))
+			}
+		}
 		data, err := os.ReadFile(path)
 		if err != nil {
 			e.error(s.Path, fmt.Sprintf("Error reading file at path %v: %v", path, err))
 		}
+		if !utf8.Valid(data) {
+			e.addDiag(syntax.Warning(s.Path.Syntax().Syntax().Range(), fmt.Sprintf("file at path %v is not valid UTF-8 text", path),
+				"fn::readFile reads text files. To use binary content, encode it with base64 first, "+
+					"or use fn::fileAsset to upload it as an asset."))
+		}
 		return string(data), true
 	})
 
-	return readFileF(expr)
+	return readFileF(expr, maxBytes)
+}
+
+// evaluateBuiltinCidr evaluates fn::cidrSubnet and fn::cidrHost, which compute an address from a CIDR prefix and
+// some integers.
+func (e *programEvaluator) evaluateBuiltinCidr(x ast.BuiltinExpr, prefix ast.Expr, numbers []ast.Expr,
+	compute func(prefix string, args []int) (string, error),
+) (interface{}, bool) {
+	exprs := append([]ast.Expr{prefix}, numbers...)
+	values := make([]interface{}, len(exprs))
+	for i, expr := range exprs {
+		v, ok := e.evaluateExpr(expr)
+		if !ok {
+			return nil, false
+		}
+		values[i] = v
+	}
+
+	cidrF := e.lift(func(args ...interface{}) (interface{}, bool) {
+		p, ok := args[0].(string)
+		if !ok {
+			return e.error(prefix, fmt.Sprintf("the prefix given to %s must be a string, not %v", x.Name().Value, typeString(args[0])))
+		}
+		ints := make([]int, len(numbers))
+		for i, arg := range args[1:] {
+			switch n := arg.(type) {
+			case int:
+				ints[i] = n
+			case float64:
+				if float64(int(n)) != n {
+					return e.error(numbers[i], fmt.Sprintf("the arguments to %s must be integers, not %s",
+						x.Name().Value, strconv.FormatFloat(n, 'f', -1, 64)))
+				}
+				ints[i] = int(n)
+			default:
+				return e.error(numbers[i], fmt.Sprintf("the arguments to %s must be integers, not %v", x.Name().Value, typeString(arg)))
+			}
+		}
+		address, err := compute(p, ints)
+		if err != nil {
+			return e.error(x, err.Error())
+		}
+		return address, true
+	})
+
+	return cidrF(values...)
+}
+
+// maxRangeLength is the largest number of elements that fn::range will generate.
+const maxRangeLength = 1024
+
+// numberRange returns the numbers from start up to, but not including, end. The step defaults to 1, or to -1 when end
+// is less than start.
+func numberRange(start, end float64, step *float64) ([]interface{}, error) {
+	inc := 1.0
+	if end < start {
+		inc = -1
+	}
+	if step != nil {
+		inc = *step
+	}
+	switch {
+	case inc == 0:
+		return nil, fmt.Errorf("the step of fn::range must not be zero")
+	case start < end && inc < 0:
+		return nil, fmt.Errorf("the step of fn::range must be positive when the range counts up, not %s",
+			strconv.FormatFloat(inc, 'f', -1, 64))
+	case start > end && inc > 0:
+		return nil, fmt.Errorf("the step of fn::range must be negative when the range counts down, not %s",
+			strconv.FormatFloat(inc, 'f', -1, 64))
+	}
+
+	// The comparison is negated so that NaN bounds are rejected too.
+	if n := math.Ceil((end - start) / inc); !(n <= maxRangeLength) {
+		return nil, fmt.Errorf("fn::range would generate %s numbers, but it generates at most %d",
+			strconv.FormatFloat(n, 'f', -1, 64), maxRangeLength)
+	}
+	values := []interface{}{}
+	for i := 0; ; i++ {
+		v := start + float64(i)*inc
+		if (inc > 0 && v >= end) || (inc < 0 && v <= end) {
+			return values, nil
+		}
+		values = append(values, v)
+	}
+}
+
+// evaluateBuiltinRange generates a list of numbers. If any of the bounds is an output, so is the list.
+func (e *programEvaluator) evaluateBuiltinRange(v *ast.RangeExpr) (interface{}, bool) {
+	exprs := []ast.Expr{v.Start, v.End}
+	if v.Step != nil {
+		exprs = append(exprs, v.Step)
+	}
+	values := make([]interface{}, len(exprs))
+	for i, expr := range exprs {
+		value, ok := e.evaluateExpr(expr)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+
+	rangeF := e.lift(func(args ...interface{}) (interface{}, bool) {
+		numbers := make([]float64, len(args))
+		for i, arg := range args {
+			switch n := arg.(type) {
+			case float64:
+				numbers[i] = n
+			case int:
+				numbers[i] = float64(n)
+			default:
+				return e.error(exprs[i], fmt.Sprintf("the arguments to fn::range must be numbers, not %v", typeString(arg)))
+			}
+		}
+		var step *float64
+		if len(numbers) == 3 {
+			step = &numbers[2]
+		}
+		list, err := numberRange(numbers[0], numbers[1], step)
+		if err != nil {
+			return e.error(v, err.Error())
+		}
+		return list, true
+	})
+
+	return rangeF(values...)
+}
+
+// evaluateBuiltinGetEnv returns the value of an environment variable, or the default if it is unset.
+func (e *programEvaluator) evaluateBuiltinGetEnv(s *ast.GetEnvExpr) (interface{}, bool) {
+	name, ok := e.evaluateExpr(s.Variable)
+	if !ok {
+		return nil, false
+	}
+	var def interface{}
+	if s.Default != nil {
+		def, ok = e.evaluateExpr(s.Default)
+		if !ok {
+			return nil, false
+		}
+	}
+
+	getEnvF := e.lift(func(args ...interface{}) (interface{}, bool) {
+		name, ok := args[0].(string)
+		if !ok {
+			return e.error(s.Variable, fmt.Sprintf("the name given to fn::getEnv must be a string, not %v", typeString(args[0])))
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		if s.Default == nil {
+			return e.error(s, fmt.Sprintf("environment variable %v is not set, and fn::getEnv has no default", name))
+		}
+		def, ok := args[1].(string)
+		if !ok {
+			return e.error(s.Default, fmt.Sprintf("the default given to fn::getEnv must be a string, not %v", typeString(args[1])))
+		}
+		return def, true
+	})
+
+	return getEnvF(name, def)
+}
+
+// evaluateBuiltinGenerated evaluates a builtin whose value is generated anew each time it is evaluated, such as
+// fn::uuid. Its value is unknown during a preview, so that previews don't show a change every time they run.
+func (e *programEvaluator) evaluateBuiltinGenerated(generate func() string) (interface{}, bool) {
+	if e.pulumiCtx.DryRun() {
+		return unknownOutput(), true
+	}
+	return generate(), true
+}
+
+// evaluateBuiltinReadDir returns the sorted names of the files in a directory. Subdirectories are not listed.
+func (e *programEvaluator) evaluateBuiltinReadDir(s *ast.ReadDirExpr) (interface{}, bool) {
+	expr, ok := e.evaluateExpr(s.Path)
+	if !ok {
+		return nil, false
+	}
+
+	_, isConstant := s.Path.(*ast.StringExpr)
+
+	readDirF := e.lift(func(args ...interface{}) (interface{}, bool) {
+		path, ok := args[0].(string)
+		if !ok {
+			return e.error(s.Path, fmt.Sprintf("Argument to fn::readDir must be a string, got %v", reflect.TypeOf(args[0])))
+		}
+		path, err := e.sanitizePath(path, isConstant)
+		if err != nil {
+			return e.error(s, err.Error())
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return e.error(s.Path, fmt.Sprintf("Error reading directory at path %v: %v", path, err))
+		}
+		if !info.IsDir() {
+			return e.error(s.Path, fmt.Sprintf("path %v is a file, not a directory; use fn::readFile to read its contents", path))
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return e.error(s.Path, fmt.Sprintf("Error reading directory at path %v: %v", path, err))
+		}
+		// os.ReadDir returns entries sorted by name.
+		names := []interface{}{}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+		return names, true
+	})
+
+	return readDirF(expr)
 }
 
 func hasOutputs(v interface{}) bool {
@@ -2330,6 +4441,45 @@ func typeString(v interface{}) string {
 	}
 }
 
+// listElements returns the elements of a list. Typed config values are typed slices, such as the []string of a
+// List<String>, so any slice is accepted.
+func listElements(v interface{}) ([]interface{}, bool) {
+	if elems, ok := v.([]interface{}); ok {
+		return elems, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	elems := make([]interface{}, rv.Len())
+	for i := range elems {
+		elems[i] = rv.Index(i).Interface()
+	}
+	return elems, true
+}
+
+// valuesEqual reports whether two values are deeply equal. Integer config values are ints, while numbers in the
+// template are float64s, so numbers are compared by value.
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := numberValue(a); ok {
+		y, ok := numberValue(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// numberValue returns the value of an int or float64 as a float64.
+func numberValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
 func listStrings(v *ast.StringListDecl) []string {
 	a := make([]string, len(v.Elements))
 	for i, s := range v.Elements {
@@ -12,6 +12,7 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -47,6 +48,217 @@ resources:
 	requireNoErrors(t, tmpl, diags)
 }
 
+func TestInvokeNestedOutputs(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  res-a:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  found:
+    fn::invoke:
+      function: test:invoke:nested
+      arguments:
+        filters:
+          - name: ${res-a.id}
+            values: ["${res-a.out}", literal]
+        tags:
+          owner: ${res-a.bar}
+      return: retval
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {})
+	requireNoErrors(t, tmpl, diags)
+}
+
+func TestInvokeSchemaSecretOutputs(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  password:
+    fn::invoke:
+      function: test:invoke:secret
+      return: password
+  name:
+    fn::invoke:
+      function: test:invoke:secret
+      return: name
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {
+		assert.Equal(t, "admin", r.variables["name"])
+
+		password, ok := r.variables["password"].(pulumi.Output)
+		require.True(t, ok, "expected password to be an output, got %v", r.variables["password"])
+		assert.True(t, pulumi.IsSecret(password))
+	})
+	requireNoErrors(t, tmpl, diags)
+}
+
+func TestInvokeNestedReturn(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  second:
+    fn::invoke:
+      function: test:invoke:result
+      return: result.items[1]
+  count:
+    fn::invoke:
+      function: test:invoke:result
+      return: result.count
+outputs:
+  second: ${second}
+  count: ${count}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {
+		assert.Equal(t, "b", r.variables["second"])
+		assert.Equal(t, 2.0, r.variables["count"])
+	})
+	requireNoErrors(t, tmpl, diags)
+
+	typing, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, "string", displayType(typing.TypeExpr(tmpl.Variables.Entries[0].Value)))
+	assert.Equal(t, "integer", displayType(typing.TypeExpr(tmpl.Variables.Entries[1].Value)))
+}
+
+func TestInvokeNestedReturnMissingField(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  missing:
+    fn::invoke:
+      function: test:invoke:result
+      return: result.names[0]
+  missingRoot:
+    fn::invoke:
+      function: test:invoke:result
+      return: results.count
+  notAnObject:
+    fn::invoke:
+      function: test:invoke:result
+      return: result.count.value
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheckAll(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:7:22: names does not exist on result; Existing properties are: items, count",
+		"<stdin>:11:15: results does not exist on test:invoke:result; Existing properties are: result",
+		"<stdin>:15:28: cannot access a property on 'result.count' (type integer); " +
+			"Property access is only allowed on Resources and Objects",
+	}, diagStrings)
+}
+
+func TestInvokeCollect(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  names:
+    fn::invoke:
+      function: test:invoke:paged
+      arguments:
+        filter: web
+      collect:
+        items: names
+        token: nextPageToken
+        argument: pageToken
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {
+		assert.Equal(t, []interface{}{"a", "b", "c", "d"}, r.variables["names"])
+	})
+	requireNoErrors(t, tmpl, diags)
+}
+
+func TestInvokeCollectType(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  names:
+    fn::invoke:
+      function: test:invoke:paged
+      arguments:
+        filter: web
+      collect:
+        items: names
+        token: nextToken
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:11:16: nextToken does not exist on test:invoke:paged; Existing properties are: nextPageToken, names",
+	}, diagStrings)
+
+	tmpl = yamlTemplate(t, strings.TrimSpace(strings.Replace(text, "nextToken", "nextPageToken\n        argument: pageToken", 1)))
+	tc, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, "List<string>", displayType(tc.TypeVariable("names")))
+}
+
+func TestInvokeCollectForbidsReturn(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  names:
+    fn::invoke:
+      function: test:invoke:paged
+      collect:
+        items: names
+        token: nextPageToken
+      return: names
+`
+
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:10:15: the return directive cannot be used with collect; " +
+			"A collecting invoke returns the list of items from every page of results",
+	}, diagStrings)
+}
+
 func TestInvokeWithOptsOutputs(t *testing.T) {
 	t.Parallel()
 
@@ -215,6 +427,221 @@ runtime: yaml
 	requireNoErrors(t, tmpl, diags)
 }
 
+func TestInvokeThen(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  foo:
+    fn::invoke:
+      function: test:invoke:type
+      arguments:
+        quux: tuo
+      return: retval
+      then:
+        - fn::split: ["o"]
+        - fn::select: [2]
+  encoded:
+    fn::invoke:
+      function: test:invoke:type
+      arguments:
+        quux: tuo
+      return: retval
+      then:
+        - fn::toBase64: {}
+  json:
+    fn::invoke:
+      function: test:invoke:type
+      arguments:
+        quux: tuo
+      return: retval
+      then:
+        - fn::toJSON: {}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {
+		assert.Equal(t, "f", r.variables["foo"])
+		assert.Equal(t, "b29m", r.variables["encoded"])
+		// {} is the placeholder for the previous result, not an empty object.
+		assert.Equal(t, `"oof"`, r.variables["json"])
+	})
+	requireNoErrors(t, tmpl, diags)
+}
+
+func TestInvokeThenDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  foo:
+    fn::invoke:
+      function: test:invoke:type
+      arguments:
+        quux: tuo
+      return: retval
+      then:
+        - fn::toBase64: {}
+        - fn::split: ["o", "p"]
+        - widget
+`
+
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	assert.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{"<stdin>:12:22: The argument to fn::split must be a two-values list"}, diagStrings)
+}
+
+func TestInvokePositionalArgs(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  foo:
+    fn::invoke:
+      function: test:fn
+      arguments: [yes, such]
+      return: outString
+`
+
+	// The arguments are matched to the inputs in their declared order: yesArg, then someSuchArg.
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	diags = testInvokeDiags(t, tmpl, func(r *Runner) {
+		assert.Equal(t, "yes-such", r.variables["foo"])
+	})
+	requireNoErrors(t, tmpl, diags)
+}
+
+func TestInvokePositionalArgsDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  tooMany:
+    fn::invoke:
+      function: test:fn
+      arguments: [yes, such, extra]
+  wrongType:
+    fn::invoke:
+      function: test:fn
+      arguments:
+        - [not, a, string]
+  unordered:
+    fn::invoke:
+      function: test:invoke:paged
+      arguments: [web]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:7:30: too many arguments to test:fn: expected at most 2, got 3",
+		"<stdin>:12:11: string is not assignable from List<string>; Cannot assign 'List<string>' to 'string'",
+		"<stdin>:16:18: test:invoke:paged does not declare an order for its inputs, so its arguments must be given by name; " +
+			"Positional arguments are only supported for functions whose schema sets multiArgumentInputs",
+	}, diagStrings)
+}
+
+func TestInvokeArgumentsReference(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  res-a:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  outputArgs:
+    quux: ${res-a.out}
+  fromOutput:
+    fn::invoke:
+      function: test:invoke:type
+      arguments: ${outputArgs}
+      return: retval
+  plainArgs:
+    yesArg: yes
+    someSuchArg: such
+  fromPlain:
+    fn::invoke:
+      function: test:fn
+      arguments: ${plainArgs}
+      return: outString
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	var fromOutput interface{}
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {
+		assert.Equal(t, "yes-such", r.variables["fromPlain"])
+
+		out, ok := r.variables["fromOutput"].(pulumi.Output)
+		require.True(t, ok, "expected an output, got %T", r.variables["fromOutput"])
+		out.ApplyT(func(x interface{}) (interface{}, error) {
+			fromOutput = x
+			return x, nil
+		})
+	})
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, "oof", fromOutput)
+}
+
+func TestInvokeArgumentsReferenceDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  str: not-an-object
+  list: [a, b]
+  obj:
+    yesArg: yes
+  fromObject:
+    fn::invoke:
+      function: test:fn
+      arguments: ${obj}
+  fromString:
+    fn::invoke:
+      function: test:fn
+      arguments: ${str}
+  fromList:
+    fn::invoke:
+      function: test:fn
+      arguments: ${list}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:15:18: function arguments ('arguments') must be an object, not string",
+		"<stdin>:19:18: function arguments ('arguments') must be an object, not List<string>",
+	}, diagStrings)
+}
+
 func testInvokeDiags(t *testing.T, template *ast.TemplateDecl, callback func(*Runner)) syntax.Diagnostics {
 	mocks := &testMonitor{
 		CallF: func(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
@@ -235,6 +662,59 @@ func testInvokeDiags(t *testing.T, template *ast.TemplateDecl, callback func(*Ru
 				return resource.PropertyMap{
 					"retval": resource.NewStringProperty("oof"),
 				}, nil
+			case "test:invoke:nested":
+				assert.Equal(t, resource.NewPropertyMapFromMap(map[string]interface{}{
+					"filters": []interface{}{
+						map[string]interface{}{
+							"name":   "not-tested-here",
+							"values": []interface{}{"tuo", "literal"},
+						},
+					},
+					"tags": map[string]interface{}{
+						"owner": "oof",
+					},
+				}), args.Args)
+				return resource.PropertyMap{
+					"retval": resource.NewStringProperty("found"),
+				}, nil
+			case "test:fn":
+				return resource.PropertyMap{
+					"outString": resource.NewStringProperty(
+						args.Args["yesArg"].StringValue() + "-" + args.Args["someSuchArg"].StringValue()),
+				}, nil
+			case "test:invoke:secret":
+				return resource.PropertyMap{
+					"name":     resource.NewStringProperty("admin"),
+					"password": resource.NewStringProperty("hunter2"),
+				}, nil
+			case "test:invoke:paged":
+				assert.Equal(t, "web", args.Args["filter"].StringValue())
+				pages := map[string]resource.PropertyMap{
+					"": {
+						"names":         resource.NewPropertyValue([]interface{}{"a", "b"}),
+						"nextPageToken": resource.NewStringProperty("page-2"),
+					},
+					"page-2": {
+						"names":         resource.NewPropertyValue([]interface{}{"c"}),
+						"nextPageToken": resource.NewStringProperty("page-3"),
+					},
+					"page-3": {
+						"names":         resource.NewPropertyValue([]interface{}{"d"}),
+						"nextPageToken": resource.NewStringProperty(""),
+					},
+				}
+				token := ""
+				if v, ok := args.Args["pageToken"]; ok {
+					token = v.StringValue()
+				}
+				return pages[token], nil
+			case "test:invoke:result":
+				return resource.PropertyMap{
+					"result": resource.NewPropertyValue(map[string]interface{}{
+						"items": []interface{}{"a", "b"},
+						"count": 2,
+					}),
+				}, nil
 			case "test:invoke:empty":
 				return nil, nil
 			case "test:invoke:poison":
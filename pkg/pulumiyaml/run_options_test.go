@@ -183,3 +183,126 @@ variables:
 	}
 	assert.NoError(t, err)
 }
+
+func TestConditionalResourceOptions(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+configuration:
+  retain:
+    default: true
+    type: boolean
+resources:
+  res-a:
+    type: test:resource:trivial
+    options:
+      retainOnDelete: ${retain}
+      deleteBeforeReplace: ${retain}
+      protect: false
+`
+	template := yamlTemplate(t, strings.TrimSpace(text))
+
+	registered := false
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			switch args.TypeToken {
+			case "test:resource:trivial":
+				registered = true
+				assert.True(t, args.RegisterRPC.GetRetainOnDelete())
+				assert.True(t, args.RegisterRPC.GetDeleteBeforeReplace())
+				assert.False(t, args.RegisterRPC.GetProtect())
+				return "resourceId", resource.PropertyMap{}, nil
+			}
+			return "", resource.PropertyMap{}, fmt.Errorf("Unexpected resource type %s", args.TypeToken)
+		},
+	}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		runner := newRunner(template, newMockPackageMap())
+		_, diags := TypeCheck(runner)
+		requireNoErrors(t, template, diags)
+		diags = runner.Evaluate(ctx)
+		requireNoErrors(t, template, diags)
+		return nil
+	}, pulumi.WithMocks("projectFoo", "stackDev", mocks))
+	if diags, ok := HasDiagnostics(err); ok {
+		requireNoErrors(t, template, diags)
+	}
+	assert.NoError(t, err)
+	assert.True(t, registered)
+}
+
+func TestBooleanResourceOptionsTypeCheck(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+configuration:
+  retain:
+    default: yes please
+    type: string
+resources:
+  res-a:
+    type: test:resource:trivial
+    options:
+      retainOnDelete: ${retain}
+`
+	template := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, template, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:11:23: boolean is not assignable from string; Cannot assign type 'string' to type 'boolean'",
+	}, diagStrings)
+}
+
+func TestComponentParent(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  comp:
+    type: test:component:type
+    properties:
+      foo: oof
+  child:
+    type: test:resource:trivial
+    options:
+      parent: ${comp}
+`
+	template := yamlTemplate(t, strings.TrimSpace(text))
+
+	registered := false
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			switch args.TypeToken {
+			case testComponentToken:
+				return "", resource.PropertyMap{}, nil
+			case "test:resource:trivial":
+				registered = true
+				assert.Equal(t, "urn:pulumi:stackDev::projectFoo::test:component:type::comp", args.RegisterRPC.Parent)
+				return "resourceId", resource.PropertyMap{}, nil
+			}
+			return "", resource.PropertyMap{}, fmt.Errorf("Unexpected resource type %s", args.TypeToken)
+		},
+	}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		runner := newRunner(template, newMockPackageMap())
+		_, diags := TypeCheck(runner)
+		requireNoErrors(t, template, diags)
+		diags = runner.Evaluate(ctx)
+		requireNoErrors(t, template, diags)
+		return nil
+	}, pulumi.WithMocks("projectFoo", "stackDev", mocks))
+	if diags, ok := HasDiagnostics(err); ok {
+		requireNoErrors(t, template, diags)
+	}
+	assert.NoError(t, err)
+	assert.True(t, registered)
+}
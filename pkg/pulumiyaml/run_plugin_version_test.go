@@ -233,3 +233,143 @@ resources:
 	assert.Contains(t, diagString(diags[1]), "<stdin>:14:26: Provider test already declared with a conflicting plugin download URL: https://example.com")
 	assert.Empty(t, plugins)
 }
+
+func TestVersionConflictsTypeCheck(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  res-a:
+    type: test:resource:type
+    options:
+      version: 1.23.425-beta.6
+    properties:
+      foo: oof
+  res-b:
+    type: test:resource:type
+    options:
+      version: '2.0'
+    properties:
+      foo: oof
+  res-c:
+    type: test:resource:type
+    options:
+      version: 1.23.425-beta.6
+    properties:
+      foo: oof
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:13:16: Provider test already declared with a conflicting version: 1.23.425-beta.6",
+	}, diagStrings)
+}
+
+func TestDeclaredPlugins(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+plugins:
+  - package: random
+    version: 4.8.0
+  - package: test
+    downloadURL: https://example.com
+resources:
+  res-a:
+    type: test:resource:type
+    options:
+      version: 1.23.425-beta.6
+    properties: {}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	plugins, diags := GetReferencedPlugins(tmpl)
+	requireNoErrors(t, tmpl, diags)
+
+	assert.Equal(t, []Plugin{
+		{Package: "random", Version: "4.8.0"},
+		{Package: "test", Version: "1.23.425-beta.6", PluginDownloadURL: "https://example.com"},
+	}, plugins)
+}
+
+func TestDeclaredPluginConflicts(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+plugins:
+  - package: test
+    version: 1.0.0
+resources:
+  res-a:
+    type: test:resource:type
+    options:
+      version: 1.23.425-beta.6
+    properties: {}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	plugins, diags := GetReferencedPlugins(tmpl)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:10:16: Provider test already declared with a conflicting version: 1.0.0",
+	}, diagStrings)
+	assert.Empty(t, plugins)
+}
+
+func TestDeclaredPluginWithoutPackage(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+plugins:
+  - version: 2.0.0
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	plugins, diags := GetReferencedPlugins(tmpl)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:4:5: Plugin declared without a 'package'",
+	}, diagStrings)
+	assert.Empty(t, plugins)
+}
+
+func TestProjectPluginsAreIgnored(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+plugins:
+  providers:
+    - name: test
+      path: ./bin
+resources:
+  res-a:
+    type: test:resource:type
+    properties: {}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	plugins, diags := GetReferencedPlugins(tmpl)
+	requireNoErrors(t, tmpl, diags)
+	assert.Equal(t, []Plugin{{Package: "test"}}, plugins)
+}
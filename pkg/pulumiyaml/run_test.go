@@ -3,18 +3,23 @@
 package pulumiyaml
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	b64 "encoding/base64"
 
 	"github.com/blang/semver"
+	"github.com/google/uuid"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/pulumi/pulumi/pkg/v3/codegen/schema"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -59,6 +64,8 @@ type MockPackage struct {
 	resolveFunction  func(typeName string) (FunctionTypeToken, error)
 	resourceTypeHint func(typeName string) *schema.ResourceType
 	functionTypeHint func(typeName string) *schema.Function
+	listResources    func() []string
+	listFunctions    func() []string
 }
 
 func (m MockPackage) ResolveResource(typeName string) (ResourceTypeToken, error) {
@@ -75,6 +82,20 @@ func (m MockPackage) ResolveFunction(typeName string) (FunctionTypeToken, error)
 	return FunctionTypeToken(typeName), nil
 }
 
+func (m MockPackage) ListResources() []string {
+	if m.listResources != nil {
+		return m.listResources()
+	}
+	return nil
+}
+
+func (m MockPackage) ListFunctions() []string {
+	if m.listFunctions != nil {
+		return m.listFunctions()
+	}
+	return nil
+}
+
 func (m MockPackage) IsComponent(typeName ResourceTypeToken) (bool, error) {
 	return m.isComponent(typeName.String())
 }
@@ -187,6 +208,19 @@ func newMockPackageMap() PackageLoader {
 							Type:   schema.StringType,
 							Secret: true,
 						})
+					case "test:resource:with-output":
+						return &schema.ResourceType{
+							Resource: &schema.Resource{
+								Token: typeName,
+								InputProperties: []*schema.Property{
+									{Name: "foo", Type: &schema.OptionalType{ElementType: schema.StringType}},
+								},
+								Properties: []*schema.Property{
+									{Name: "foo", Type: schema.StringType},
+									{Name: "arn", Type: schema.StringType},
+								},
+							},
+						}
 					case "test:resource:with-alias":
 						return &schema.ResourceType{
 							Resource: &schema.Resource{
@@ -204,7 +238,7 @@ func newMockPackageMap() PackageLoader {
 				functionTypeHint: func(typeName string) *schema.Function {
 					switch typeName {
 					case "test:fn":
-						return function(typeName,
+						fn := function(typeName,
 							[]schema.Property{
 								{Name: "yesArg", Type: schema.StringType},
 								{Name: "someSuchArg", Type: &schema.OptionalType{ElementType: schema.StringType}},
@@ -212,6 +246,36 @@ func newMockPackageMap() PackageLoader {
 							[]schema.Property{
 								{Name: "outString", Type: schema.StringType},
 							})
+						// The inputs are listed in the order declared by multiArgumentInputs.
+						fn.MultiArgumentInputs = true
+						return fn
+					case "test:invoke:secret":
+						return function(typeName, nil,
+							[]schema.Property{
+								{Name: "name", Type: schema.StringType},
+								{Name: "password", Type: schema.StringType, Secret: true},
+							})
+					case "test:invoke:paged":
+						return function(typeName,
+							[]schema.Property{
+								{Name: "filter", Type: schema.StringType},
+								{Name: "pageToken", Type: &schema.OptionalType{ElementType: schema.StringType}},
+							},
+							[]schema.Property{
+								{Name: "names", Type: &schema.ArrayType{ElementType: schema.StringType}},
+								{Name: "nextPageToken", Type: schema.StringType},
+							})
+					case "test:invoke:result":
+						return function(typeName, nil,
+							[]schema.Property{
+								{Name: "result", Type: &schema.ObjectType{
+									Token: "test:index:Result",
+									Properties: []*schema.Property{
+										{Name: "items", Type: &schema.ArrayType{ElementType: schema.StringType}},
+										{Name: "count", Type: schema.IntType},
+									},
+								}},
+							})
 					case "test:invoke:poison":
 						return function("test:invoke:poison",
 							[]schema.Property{{Name: "foo", Type: schema.StringType}},
@@ -262,7 +326,15 @@ func projectConfigKey(k string) resource.PropertyKey {
 }
 
 func setConfig(t *testing.T, m resource.PropertyMap) {
-	config := m.Mappable()
+	// The engine passes secret config to programs decrypted, listing the secret keys separately.
+	plain := resource.PropertyMap{}
+	for k, v := range m {
+		if v.IsSecret() {
+			v = v.SecretValue().Element
+		}
+		plain[k] = v
+	}
+	config := plain.Mappable()
 	b, err := json.Marshal(config)
 	require.NoError(t, err, "Failed to marshal the map")
 	t.Setenv(pulumi.EnvConfig, string(b))
@@ -446,6 +518,156 @@ variables:
 	})
 }
 
+func TestAssetArchiveOutputEntry(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+resources:
+  res-a:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  dir:
+    fn::assetArchive:
+      greeting:
+        fn::stringAsset: hello ${res-a.out}
+`
+	tmpl := yamlTemplate(t, text)
+	var resolved interface{}
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		dir, ok := e.variables["dir"].(pulumi.Output)
+		require.True(t, ok, "expected an output, got %T", e.variables["dir"])
+		out := dir.ApplyT(func(x interface{}) (interface{}, error) {
+			resolved = x
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	archive, ok := resolved.(pulumi.Archive)
+	require.True(t, ok, "expected an archive, got %T", resolved)
+	assert.Equal(t, "hello tuo", archive.Assets()["greeting"].(pulumi.Asset).Text())
+}
+
+func TestAssetArchiveEntryKind(t *testing.T) {
+	t.Parallel()
+
+	tmpl := template(t, &Template{})
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		asset, archive := pulumi.NewStringAsset("text"), pulumi.NewRemoteArchive("example.org/docs")
+
+		_, ok := e.checkAssetArchiveEntry("page", &ast.FileAssetExpr{}, asset)
+		assert.True(t, ok)
+		_, ok = e.checkAssetArchiveEntry("page", &ast.FileAssetExpr{}, archive)
+		assert.False(t, ok)
+		_, ok = e.checkAssetArchiveEntry("docs", &ast.RemoteArchiveExpr{}, asset)
+		assert.False(t, ok)
+		_, ok = e.checkAssetArchiveEntry("docs", &ast.RemoteArchiveExpr{}, "example.org/docs")
+		assert.False(t, ok)
+
+		var summaries []string
+		for _, d := range e.sdiags.diags {
+			summaries = append(summaries, d.Summary)
+		}
+		assert.Equal(t, []string{
+			`fn::assetArchive entry "page" must be an asset, not an archive`,
+			`fn::assetArchive entry "docs" must be an archive, not an asset`,
+			`fn::assetArchive entry "docs" must be an archive, not a string`,
+		}, summaries)
+	})
+}
+
+func TestFileAssetMissing(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+variables:
+  missing:
+    fn::fileAsset: ./does-not-exist.txt
+`
+	tmpl := yamlTemplate(t, text)
+	path, err := filepath.Abs("does-not-exist.txt")
+	require.NoError(t, err)
+
+	// During a preview, the file would otherwise only be found missing by the engine.
+	err = pulumi.RunErr(func(ctx *pulumi.Context) error {
+		return newRunner(tmpl, newMockPackageMap()).Evaluate(ctx)
+	}, pulumi.WithMocks(testProject, "dev", &testMonitor{}), func(ri *pulumi.RunInfo) {
+		ri.DryRun = true
+	})
+	diags, ok := HasDiagnostics(err)
+	require.True(t, ok, "expected diagnostics, got %v", err)
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, []string{fmt.Sprintf("<stdin>:5:5: file asset not found at path %v", path)}, diagStrings)
+}
+
+func TestAssetArchiveReference(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+variables:
+  docs:
+    fn::remoteArchive: example.org/docs
+  page:
+    fn::stringAsset: about us
+  dir:
+    fn::assetArchive:
+      index:
+        fn::stringAsset: this is home
+      folder: ${docs}
+      about: ${page}
+`
+	tmpl := yamlTemplate(t, text)
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		dir, ok := e.variables["dir"].(pulumi.Archive)
+		require.True(t, ok)
+
+		assets := dir.Assets()
+		assert.Equal(t, "this is home", assets["index"].(pulumi.Asset).Text())
+		assert.Equal(t, "example.org/docs", assets["folder"].(pulumi.Archive).URI())
+		assert.Equal(t, "about us", assets["about"].(pulumi.Asset).Text())
+	})
+}
+
+func TestAssetArchiveReferenceDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+variables:
+  name: docs
+  dir:
+    fn::assetArchive:
+      other: ${name}
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	expected := []string{
+		`<stdin>:6:14: fn::assetArchive entry "other" must refer to an asset or an archive, not a string`,
+	}
+
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, expected, diagStrings)
+
+	// The evaluator reports the same errors for templates that were not type checked.
+	diags = testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	diagStrings = nil
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, expected, diagStrings)
+}
+
 func TestPropertiesAbsent(t *testing.T) {
 	t.Parallel()
 
@@ -459,9 +681,17 @@ resources:
 	tmpl := yamlTemplate(t, text)
 	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
 	require.Len(t, diags, 0)
-	// Consider warning on this?
-	// require.True(t, diags.HasErrors())
-	// assert.Equal(t, "<stdin>:4:3: resource res-a passed has an empty properties value", diagString(diags[0]))
+
+	_, diags = TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	require.True(t, diags.HasErrors())
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:4:3: Resource res-a was declared without any properties; test:resource:type requires foo",
+		"<stdin>:4:3: Resource res-a is missing required property foo; foo is a required input of test:resource:type",
+	}, diagStrings)
 }
 
 func TestYAMLDiags(t *testing.T) {
@@ -485,6 +715,175 @@ outputs:
 	assert.Equal(t, `<stdin>:9:8: resource or variable named "res-b" could not be found`, diagString(diags[0]))
 }
 
+func TestMultiDocumentYAML(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+resources:
+  res-a:
+    type: test:resource:type
+    properties:
+      foo: oof
+---
+variables:
+  foo: ${res-a.foo}
+outputs:
+  out: ${res-b}
+`
+
+	tmpl := yamlTemplate(t, text)
+	assert.Equal(t, "test-yaml", tmpl.Name.Value)
+	require.Len(t, tmpl.Resources.Entries, 1)
+	require.Len(t, tmpl.Variables.Entries, 1)
+	require.Len(t, tmpl.Outputs.Entries, 1)
+
+	// Diagnostics point into the document that declared the failing expression.
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {})
+	require.True(t, diags.HasErrors())
+	assert.Len(t, diags, 1)
+	assert.Equal(t, `<stdin>:12:8: resource or variable named "res-b" could not be found`, diagString(diags[0]))
+}
+
+func TestMultiDocumentYAMLConflicts(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+resources:
+  res-a:
+    type: test:resource:type
+---
+name: other-name
+resources:
+  res-a:
+    type: test:resource:type
+`
+
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(text))
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:7:7: conflicting template name "other-name", already declared as "test-yaml"`,
+		`<stdin>:9:3: resource "res-a" is declared in more than one document`,
+	}, diagStrings)
+}
+
+func TestMultiDocumentYAMLEmptyDocuments(t *testing.T) {
+	t.Parallel()
+
+	const template = `name: test-yaml
+runtime: yaml
+resources:
+  res-a:
+    type: test:resource:type
+`
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "trailing separator", text: template + "---\n"},
+		{name: "comment only document", text: template + "---\n# nothing to see here\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, diags, err := LoadYAMLBytes("<stdin>", []byte(tt.text))
+			require.NoError(t, err)
+			requireNoErrors(t, tmpl, diags)
+			assert.Equal(t, "test-yaml", tmpl.Name.Value)
+			assert.Len(t, tmpl.Resources.Entries, 1)
+		})
+	}
+}
+
+func TestConditionalOutputs(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  enabled:
+    type: boolean
+    default: false
+outputs:
+  disabled:
+    fn::output:
+      value:
+        fn::fromBase64: "%%%"
+      condition: ${enabled}
+  enabled:
+    fn::output:
+      value:
+        fn::fromBase64: "%%%"
+      condition: true
+  notBoolean:
+    fn::output:
+      value: foo
+      condition: "yes"
+  # An object that happens to have these keys is an ordinary output.
+  plain:
+    value: foo
+    condition: "yes"
+`
+
+	tmpl := yamlTemplate(t, text)
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	// Only the enabled output is evaluated.
+	assert.ElementsMatch(t, []string{
+		"<stdin>:16:25: fn::fromBase64 unable to decode %%%, error: illegal base64 data at input byte 0",
+		"<stdin>:21:18: condition must be a boolean value, not a string",
+	}, diagStrings)
+
+	_, diags = TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	diagStrings = nil
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:21:18: boolean is not assignable from string; Cannot assign type 'string' to type 'boolean'",
+	}, diagStrings)
+}
+
+func TestConditionalOutputOutsideOutputs(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+variables:
+  misplaced:
+    fn::output:
+      value: foo
+      condition: true
+outputs:
+  nested:
+    list:
+      - fn::output:
+          value: foo
+          condition: true
+`
+
+	tmpl := yamlTemplate(t, text)
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:5: fn::output may only be used as the value of an output",
+		"<stdin>:11:9: fn::output may only be used as the value of an output",
+	}, diagStrings)
+}
+
 func TestConfigTypes(t *testing.T) {
 	t.Parallel()
 
@@ -519,72 +918,335 @@ configuration:
 	require.True(t, diags.HasErrors())
 }
 
-func TestConfigSecrets(t *testing.T) { //nolint:paralleltest
+func TestConfigListTypes(t *testing.T) { //nolint:paralleltest
 	const text = `name: test-yaml
 runtime: yaml
 configuration:
-  foo:
-    secret: true
-    type: Number
-  bar:
-    type: String
-  fizz:
+  count:
+    type: Integer
     default: 42
-  buzz:
-    default: 42
-    secret: true
+  ports:
+    type: List<Integer>
+  weights:
+    type: List<Number>
+    default: [ 1, 2.5 ]
+  flags:
+    type: List<Boolean>
+  sizes:
+    type: List<Integer>
+    default: [ 1, 2 ]
 `
 
 	tmpl := yamlTemplate(t, text)
 	setConfig(t,
 		resource.PropertyMap{
-			projectConfigKey("foo"): resource.NewStringProperty("42.0"),
-			projectConfigKey("bar"): resource.MakeSecret(resource.NewStringProperty("the answer")),
+			projectConfigKey("ports"): resource.NewStringProperty("[80, 443]"),
+			projectConfigKey("flags"): resource.NewStringProperty("[true, false]"),
 		})
 	testRan := false
-	err := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
-		// Secret because declared secret in configuration
-		assert.True(t, pulumi.IsSecret(e.config["foo"].(pulumi.Output)))
-		// Secret because declared secret in in config
-		assert.True(t, pulumi.IsSecret(e.config["bar"].(pulumi.Output)))
-		// Secret because declared secret in configuration (& default)
-		assert.True(t, pulumi.IsSecret(e.config["buzz"].(pulumi.Output)))
-		// not secret
-		assert.Equal(t, 42.0, e.config["fizz"])
-
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, 42, e.config["count"])
+		assert.Equal(t, []int{80, 443}, e.config["ports"])
+		assert.Equal(t, []interface{}{1.0, 2.5}, e.config["weights"])
+		assert.Equal(t, []bool{true, false}, e.config["flags"])
+		assert.Equal(t, []interface{}{1, 2}, e.config["sizes"])
 		testRan = true
 	})
+	requireNoErrors(t, tmpl, diags)
 	assert.True(t, testRan, "Our tests didn't run")
-	diags, found := HasDiagnostics(err)
-	assert.False(t, found, "We should not get any errors: '%s'", diags)
 }
 
-func TestConfigNames(t *testing.T) { //nolint:paralleltest
+func TestConfigListDefaultMismatch(t *testing.T) {
+	t.Parallel()
+
 	const text = `name: test-yaml
 runtime: yaml
 configuration:
-  foo:
-    type: String
-    name: logicalFoo
-  bar:
-    type: String
+  ports:
+    type: List<Integer>
+    default: [ 80, 443, "8080" ]
 `
 
 	tmpl := yamlTemplate(t, text)
-	fooValue := "value from logicalName"
-	barValue := "value from config"
-	setConfig(t,
-		resource.PropertyMap{
-			projectConfigKey("logicalFoo"): resource.NewStringProperty(fooValue),
-			projectConfigKey("bar"):        resource.NewStringProperty(barValue),
-		})
-	testRan := false
-	err := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
-		assert.Equal(t, fooValue, e.config["foo"])
-		assert.Equal(t, barValue, e.config["bar"])
-
-		testRan = true
-	})
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:4:3: type mismatch: element 2 of the default value has type string but type integer was specified",
+	}, diagStrings)
+}
+
+func TestConfigMapTypes(t *testing.T) { //nolint:paralleltest
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  tags:
+    type: Map<String>
+  limits:
+    default:
+      cpu: 2
+      memory: 512
+  ports:
+    type: Map<Integer>
+  credentials:
+    type: Map<String>
+`
+
+	tmpl := yamlTemplate(t, text)
+	setConfig(t,
+		resource.PropertyMap{
+			projectConfigKey("tags"):        resource.NewStringProperty(`{"env": "prod", "team": "infra"}`),
+			projectConfigKey("ports"):       resource.NewStringProperty(`{"http": 80, "https": 443}`),
+			projectConfigKey("credentials"): resource.MakeSecret(resource.NewStringProperty(`{"user": "admin"}`)),
+		})
+	testRan := false
+	var credentials interface{}
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, map[string]interface{}{"env": "prod", "team": "infra"}, e.config["tags"])
+		assert.Equal(t, map[string]interface{}{"cpu": 2.0, "memory": 512.0}, e.config["limits"])
+		assert.Equal(t, map[string]interface{}{"http": 80, "https": 443}, e.config["ports"])
+
+		// Secret maps are converted to objects like any other.
+		out, ok := e.config["credentials"].(pulumi.Output)
+		require.True(t, ok, "secret config should be an output")
+		assert.True(t, pulumi.IsSecret(out))
+		e.pulumiCtx.Export("credentials", out.ApplyT(func(x interface{}) (interface{}, error) {
+			credentials = x
+			return x, nil
+		}))
+		testRan = true
+	})
+	requireNoErrors(t, tmpl, diags)
+	assert.True(t, testRan, "Our tests didn't run")
+	assert.Equal(t, map[string]interface{}{"user": "admin"}, credentials)
+}
+
+func TestConfigMapTypeMismatch(t *testing.T) { //nolint:paralleltest
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  tags:
+    type: Map<String>
+  labels:
+    type: Map<String>
+    default: [ "a", "b" ]
+  nested:
+    type: Map<List<String>>
+`
+
+	tmpl := yamlTemplate(t, text)
+	setConfig(t,
+		resource.PropertyMap{
+			projectConfigKey("tags"): resource.NewStringProperty("prod"),
+		})
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:4:3: type mismatch: the configured value of tags is not of type Map<string>",
+		"<stdin>:6:3: type mismatch: default value of type List<string> but type Map<string> was specified",
+		"<stdin>:10:11: unexpected configuration type 'Map<List<String>>': valid types are string, List<string>, number, List<number>, integer, List<integer>, boolean, List<boolean>, Map<string>, Map<number>, Map<integer>, Map<boolean>",
+	}, diagStrings)
+}
+
+func TestConfigConstraints(t *testing.T) { //nolint:paralleltest
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  replicas:
+    type: Integer
+    minimum: 1
+    maximum: 5
+  ratio:
+    type: Number
+    minimum: 0
+    default: -0.5
+  region:
+    type: String
+    pattern: ^[a-z]+-[a-z]+-[0-9]$
+  password:
+    type: String
+    pattern: ^.{12,}$
+  name:
+    type: String
+    pattern: ^[a-z]+$
+  pin:
+    type: Integer
+    maximum: 9999
+  apiKey:
+    type: String
+    secret: true
+    pattern: ^key-
+    default: nope
+`
+
+	tmpl := yamlTemplate(t, text)
+	setConfig(t,
+		resource.PropertyMap{
+			projectConfigKey("replicas"): resource.NewStringProperty("8"),
+			projectConfigKey("region"):   resource.NewStringProperty("us-west-2"),
+			projectConfigKey("password"): resource.MakeSecret(resource.NewStringProperty("hunter2")),
+			projectConfigKey("name"):     resource.NewStringProperty("web"),
+			projectConfigKey("pin"):      resource.MakeSecret(resource.NewStringProperty("12345")),
+		})
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:4:3: config value replicas must be at most 5",
+		"<stdin>:8:3: config value ratio must be at least 0",
+		`<stdin>:15:3: config value password must match the pattern "^.{12,}$"`,
+		"<stdin>:21:3: config value pin must be at most 9999",
+		`<stdin>:24:3: config value apiKey must match the pattern "^key-"`,
+	}, diagStrings)
+}
+
+func TestConfigAllowedValuesEnforced(t *testing.T) { //nolint:paralleltest
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  environment:
+    type: String
+    allowedValues: [ dev, staging, prod ]
+  size:
+    type: Integer
+    allowedValues: [ 1, 2, 4 ]
+  region:
+    type: String
+    allowedValues: [ us-east-1, us-west-2 ]
+  tier:
+    type: String
+    allowedValues: [ free, paid ]
+`
+
+	tmpl := yamlTemplate(t, text)
+	setConfig(t,
+		resource.PropertyMap{
+			projectConfigKey("environment"): resource.NewStringProperty("qa"),
+			projectConfigKey("size"):        resource.NewStringProperty("3"),
+			projectConfigKey("region"):      resource.NewStringProperty("us-west-2"),
+			projectConfigKey("tier"):        resource.MakeSecret(resource.NewStringProperty("gold")),
+		})
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:4:3: config value environment must be one of dev, staging, prod",
+		"<stdin>:7:3: config value size must be one of 1, 2, 4",
+		"<stdin>:13:3: config value tier must be one of free, paid",
+	}, diagStrings)
+}
+
+func TestConfigConstraintDecls(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  name:
+    type: String
+    default: web
+    minimum: 1
+  count:
+    default: 3
+    minimum: 5
+    maximum: 2
+  flag:
+    default: true
+    pattern: ^t
+  region:
+    default: us-west-2
+    pattern: "[a-z"
+`
+
+	tmpl := yamlTemplate(t, text)
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:7:14: minimum can only be used with Number or Integer config, not string",
+		"<stdin>:10:14: the minimum (5) must not be greater than the maximum (2)",
+		"<stdin>:14:14: pattern can only be used with String config, not boolean",
+		"<stdin>:17:14: invalid pattern \"[a-z\": error parsing regexp: missing closing ]: `[a-z`",
+	}, diagStrings)
+}
+
+func TestConfigSecrets(t *testing.T) { //nolint:paralleltest
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  foo:
+    secret: true
+    type: Number
+  bar:
+    type: String
+  fizz:
+    default: 42
+  buzz:
+    default: 42
+    secret: true
+`
+
+	tmpl := yamlTemplate(t, text)
+	setConfig(t,
+		resource.PropertyMap{
+			projectConfigKey("foo"): resource.NewStringProperty("42.0"),
+			projectConfigKey("bar"): resource.MakeSecret(resource.NewStringProperty("the answer")),
+		})
+	testRan := false
+	err := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		// Secret because declared secret in configuration
+		assert.True(t, pulumi.IsSecret(e.config["foo"].(pulumi.Output)))
+		// Secret because declared secret in in config
+		assert.True(t, pulumi.IsSecret(e.config["bar"].(pulumi.Output)))
+		// Secret because declared secret in configuration (& default)
+		assert.True(t, pulumi.IsSecret(e.config["buzz"].(pulumi.Output)))
+		// not secret
+		assert.Equal(t, 42.0, e.config["fizz"])
+
+		testRan = true
+	})
+	assert.True(t, testRan, "Our tests didn't run")
+	diags, found := HasDiagnostics(err)
+	assert.False(t, found, "We should not get any errors: '%s'", diags)
+}
+
+func TestConfigNames(t *testing.T) { //nolint:paralleltest
+	const text = `name: test-yaml
+runtime: yaml
+configuration:
+  foo:
+    type: String
+    name: logicalFoo
+  bar:
+    type: String
+`
+
+	tmpl := yamlTemplate(t, text)
+	fooValue := "value from logicalName"
+	barValue := "value from config"
+	setConfig(t,
+		resource.PropertyMap{
+			projectConfigKey("logicalFoo"): resource.NewStringProperty(fooValue),
+			projectConfigKey("bar"):        resource.NewStringProperty(barValue),
+		})
+	testRan := false
+	err := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, fooValue, e.config["foo"])
+		assert.Equal(t, barValue, e.config["bar"])
+
+		testRan = true
+	})
 	assert.True(t, testRan, "Our tests didn't run")
 	diags, found := HasDiagnostics(err)
 	assert.False(t, found, "We should not get any errors: '%s'", diags)
@@ -976,6 +1638,92 @@ func TestSplit(t *testing.T) {
 	}
 }
 
+func TestSplitEmptyDelimiter(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-split
+runtime: yaml
+variables:
+  empty: ""
+  constant:
+    fn::split:
+      - ""
+      - abc
+  dynamic:
+    fn::split:
+      - ${empty}
+      - abc
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{"<stdin>:7:9: The delimiter of fn::split must not be empty"}, diagStrings)
+
+	diags = testTemplateSyntaxDiags(t, tmpl, nil)
+	diagStrings = nil
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:7:9: The delimiter of fn::split must not be empty",
+		"<stdin>:11:9: The delimiter of fn::split must not be empty",
+	}, diagStrings)
+}
+
+func TestSplitRegex(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-split
+runtime: yaml
+variables:
+  separator: "[,;]"
+  words:
+    fn::splitRegex:
+      - \s+
+      - "a  b\tc"
+  mixed:
+    fn::splitRegex:
+      - ${separator}
+      - a,b;c
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []string{"a", "b", "c"}, e.variables["words"])
+		assert.Equal(t, []string{"a", "b", "c"}, e.variables["mixed"])
+	})
+}
+
+func TestSplitRegexInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-split
+runtime: yaml
+variables:
+  invalid:
+    fn::splitRegex:
+      - "a("
+      - abc
+`
+
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:6:9: invalid regular expression \"a(\": error parsing regexp: missing closing ): `a(`",
+	}, diagStrings)
+}
+
 func TestToJSON(t *testing.T) {
 	t.Parallel()
 
@@ -1077,6 +1825,41 @@ func TestToJSON(t *testing.T) {
 	}
 }
 
+func TestToYAML(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-to-yaml
+runtime: yaml
+variables:
+  data:
+    fn::toYAML:
+      zone: b
+      name: web
+      ports: [80, 443]
+  secretData:
+    fn::toYAML:
+      name: web
+      password:
+        fn::secret: hunter2
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "name: web\nports:\n    - 80\n    - 443\nzone: b\n", e.variables["data"])
+
+		s := e.variables["secretData"].(pulumi.Output)
+		require.True(t, pulumi.IsSecret(s))
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "name: web\npassword: hunter2\n", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
 func TestSelect(t *testing.T) {
 	t.Parallel()
 
@@ -1207,803 +1990,3737 @@ func TestSelect(t *testing.T) {
 	}
 }
 
-func TestFromBase64ErrorOnInvalidUTF8(t *testing.T) {
+func TestSelectDefault(t *testing.T) {
 	t.Parallel()
 
-	tests := []struct {
-		input *ast.FromBase64Expr
-		name  string
-		valid bool
-	}{
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("a"))),
-			},
-			name:  "Valid ASCII",
-			valid: true,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xc3\xb1"))),
-			},
-			name:  "Valid 2 Octet Sequence",
-			valid: true,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xe2\x82\xa1"))),
-			},
-			name:  "Valid 3 Octet Sequence",
-			valid: true,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x90\x8c\xbc"))),
-			},
-			name:  "Valid 4 Octet Sequence",
-			valid: true,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf8\xa1\xa1\xa1\xa1"))),
-			},
-			name:  "Valid 5 Octet Sequence (but not Unicode!)",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xfc\xa1\xa1\xa1\xa1\xa1"))),
-			},
-			name:  "Valid 6 Octet Sequence (but not Unicode!)",
-			valid: false,
-		},
+	const text = `
+name: test-select
+runtime: yaml
+variables:
+  names: [first, second]
+  inRange:
+    fn::select: [1, "${names}", none]
+  outOfRange:
+    fn::select: [2, "${names}", none]
+  computed:
+    fn::select:
+      - 5
+      - fn::secret: [first]
+      - none
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "second", e.variables["inRange"])
+		assert.Equal(t, "none", e.variables["outOfRange"])
 
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xfc\xa1\xa1\xa1\xa1\xa1"))),
-			},
-			name:  "Valid 6 Octet Sequence (but not Unicode!)",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xc3\x28"))),
-			},
-			name:  "Invalid 2 Octet Sequence",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xa0\xa1"))),
-			},
-			name:  "Invalid Sequence Identifier",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xe2\x28\xa1"))),
-			},
-			name:  "Invalid 3 Octet Sequence (in 2nd Octet)",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xe2\x82\x28"))),
-			},
-			name:  "Invalid 3 Octet Sequence (in 3rd Octet)",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x28\x8c\xbc"))),
-			},
-			name:  "Invalid 4 Octet Sequence (in 2nd Octet)",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x90\x28\xbc"))),
-			},
-			name:  "Invalid 4 Octet Sequence (in 3rd Octet)",
-			valid: false,
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x28\x8c\x28"))),
-			},
-			name:  "Invalid 4 Octet Sequence (in 4th Octet)",
-			valid: false,
-		},
-	}
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "none", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
 
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
+func TestSelectNegativeIndex(t *testing.T) {
+	t.Parallel()
 
-			tmpl := template(t, &Template{
-				Resources: map[string]*Resource{},
-			})
-			testTemplate(t, tmpl, func(e *programEvaluator) {
-				_, ok := e.evaluateBuiltinFromBase64(tt.input)
-				assert.Equal(t, tt.valid, ok)
-			})
-		})
-	}
+	const text = `
+name: test-select
+runtime: yaml
+variables:
+  names: [first, second, third]
+  last:
+    fn::select: [-1, "${names}"]
+  first:
+    fn::select: [-3, "${names}"]
+  fallback:
+    fn::select: [-4, "${names}", none]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "third", e.variables["last"])
+		assert.Equal(t, "first", e.variables["first"])
+		assert.Equal(t, "none", e.variables["fallback"])
+	})
 }
 
-func TestBase64Roundtrip(t *testing.T) {
+func TestSelectNegativeIndexOutOfRange(t *testing.T) {
 	t.Parallel()
 
-	tToFrom := struct {
-		input    *ast.ToBase64Expr
-		expected string
-	}{
-		input: &ast.ToBase64Expr{
-			Value: &ast.FromBase64Expr{
-				Value: ast.String("SGVsbG8sIFdvcmxk"),
-			},
-		},
-		expected: "SGVsbG8sIFdvcmxk",
+	const text = `
+name: test-select
+runtime: yaml
+variables:
+  outOfRange:
+    fn::select: [-4, [first, second, third]]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
 	}
+	assert.Equal(t, []string{"<stdin>:5:18: list index -4 out-of-bounds for list of length 3"}, diagStrings)
+}
 
-	t.Run(tToFrom.expected, func(t *testing.T) {
-		t.Parallel()
+func TestSelectWithoutDefaultErrors(t *testing.T) {
+	t.Parallel()
 
-		tmpl := template(t, &Template{
-			Resources: map[string]*Resource{},
-		})
-		testTemplate(t, tmpl, func(e *programEvaluator) {
-			v, ok := e.evaluateBuiltinToBase64(tToFrom.input)
-			assert.True(t, ok)
-			assert.Equal(t, tToFrom.expected, v)
-		})
-	})
+	const text = `
+name: test-select
+runtime: yaml
+variables:
+  outOfRange:
+    fn::select: [2, [first, second]]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	assert.True(t, diags.HasErrors())
+}
 
-	tFromTo := struct {
-		input    *ast.FromBase64Expr
-		expected string
-	}{
-		input: &ast.FromBase64Expr{
-			Value: &ast.ToBase64Expr{
-				Value: ast.String("Hello, World!"),
-			},
-		},
-		expected: "Hello, World!",
+func TestSelectArgumentCount(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-select
+runtime: yaml
+variables:
+  tooMany:
+    fn::select: [0, [first], fallback, extra]
+`
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	assert.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
 	}
+	assert.Equal(t, []string{
+		"<stdin>:5:17: the argument to fn::select must be a two- or three-valued list; " +
+			"A third value may be given as the default when the index is out of range",
+	}, diagStrings)
+}
 
-	t.Run(tFromTo.expected, func(t *testing.T) {
-		t.Parallel()
+func TestSlice(t *testing.T) {
+	t.Parallel()
 
-		tmpl := template(t, &Template{
-			Resources: map[string]*Resource{},
-		})
-		testTemplate(t, tmpl, func(e *programEvaluator) {
-			v, ok := e.evaluateBuiltinFromBase64(tFromTo.input)
-			assert.True(t, ok)
-			assert.Equal(t, tFromTo.expected, v)
+	const text = `
+name: test-slice
+runtime: yaml
+variables:
+  names: [first, second, third]
+  head:
+    fn::slice: ["${names}", 0, 2]
+  tail:
+    fn::slice: ["${names}", 1]
+  clamped:
+    fn::slice: ["${names}", -5, 10]
+  empty:
+    fn::slice: ["${names}", 5]
+  computed:
+    fn::slice:
+      - fn::secret: [first, second, third]
+      - 1
+      - 2
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"first", "second"}, e.variables["head"])
+		assert.Equal(t, []interface{}{"second", "third"}, e.variables["tail"])
+		assert.Equal(t, []interface{}{"first", "second", "third"}, e.variables["clamped"])
+		assert.Equal(t, []interface{}{}, e.variables["empty"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, []interface{}{"second"}, x)
+			return nil, nil
 		})
+		e.pulumiCtx.Export("out", out)
 	})
+	assert.True(t, hasRun)
 }
 
-func TestFromBase64(t *testing.T) {
+func TestSliceArgumentCount(t *testing.T) {
 	t.Parallel()
 
-	tests := []struct {
-		input    *ast.FromBase64Expr
-		expected string
-		isOutput bool
-	}{
-		{
-			input: &ast.FromBase64Expr{
-				Value: ast.String("dGhpcyBpcyBhIHRlc3Q="),
-			},
-			expected: "this is a test",
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: &ast.JoinExpr{
-					Delimiter: ast.String(""),
-					Values: ast.List(
-						ast.String("My4xN"),
-						ast.String("DE1OTI="),
-					),
-				},
-			},
-			expected: "3.141592",
-		},
-		{
-			input: &ast.FromBase64Expr{
-				Value: &ast.ToBase64Expr{
-					Value: ast.String("test"),
-				},
-			},
-			expected: "test",
-		},
+	const text = `
+name: test-slice
+runtime: yaml
+variables:
+  tooMany:
+    fn::slice: [[a, b], 0, 1, 2]
+`
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	assert.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
 	}
+	assert.Equal(t, []string{
+		"<stdin>:5:16: the argument to fn::slice must be a two- or three-valued list; " +
+			"A third value may be given as the end index of the slice",
+	}, diagStrings)
+}
 
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.expected, func(t *testing.T) {
-			t.Parallel()
+func TestSliceStartAfterEnd(t *testing.T) {
+	t.Parallel()
 
-			tmpl := template(t, &Template{
-				Resources: map[string]*Resource{
-					"resA": {
-						Type: "test:resource:type",
-						Properties: map[string]interface{}{
-							"foo": "oof",
-						},
-					},
-				},
-			})
-			testTemplate(t, tmpl, func(e *programEvaluator) {
-				v, ok := e.evaluateBuiltinFromBase64(tt.input)
-				assert.True(t, ok)
-				if tt.isOutput {
-					out := v.(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
-						s := b64.StdEncoding.EncodeToString([]byte(tt.expected))
-						assert.Equal(t, s, v)
-						return nil, nil
-					})
-					e.pulumiCtx.Export("out", out)
-				} else {
-					assert.Equal(t, tt.expected, v)
-				}
-			})
+	const text = `
+name: test-slice
+runtime: yaml
+variables:
+  names: [first, second, third]
+  reversed:
+    fn::slice: ["${names}", 2, 1]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Contains(t, diagStrings,
+		"<stdin>:6:5: the start index of fn::slice (2) must not be greater than its end index (1)")
+}
+
+func TestKeysValues(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-keys
+runtime: yaml
+variables:
+  tags:
+    zone: a
+    env: prod
+    app: web
+  keys:
+    fn::keys: ${tags}
+  values:
+    fn::values: ${tags}
+  secretKeys:
+    fn::keys:
+      fn::secret:
+        b: 2
+        a: 1
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"app", "env", "zone"}, e.variables["keys"])
+		assert.Equal(t, []interface{}{"web", "prod", "a"}, e.variables["values"])
+
+		out := e.variables["secretKeys"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, []interface{}{"a", "b"}, x)
+			return nil, nil
 		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestKeysRequiresObject(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-keys
+runtime: yaml
+variables:
+  keys:
+    fn::keys: [a, b]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	assert.True(t, diags.HasErrors())
+}
+
+func TestToString(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-to-string
+runtime: yaml
+resources:
+  res:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  integer:
+    fn::toString: 3
+  fraction:
+    fn::toString: 2.5
+  large:
+    fn::toString: 10000000000000000000000
+  boolean:
+    fn::toString: true
+  urn:
+    fn::toString: ${res}
+  computed:
+    fn::toString:
+      fn::secret: 42
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	var urn, computed interface{}
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "3", e.variables["integer"])
+		assert.Equal(t, "2.5", e.variables["fraction"])
+		assert.Equal(t, "10000000000000000000000", e.variables["large"])
+		assert.Equal(t, "true", e.variables["boolean"])
+
+		e.pulumiCtx.Export("urn", e.variables["urn"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			urn = x
+			return nil, nil
+		}))
+		e.pulumiCtx.Export("computed", e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			computed = x
+			return nil, nil
+		}))
+	})
+	assert.Equal(t, "urn:pulumi:dev::foo::test:resource:type::res", urn)
+	assert.Equal(t, "42", computed)
+}
+
+func TestToStringRejectsLists(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-to-string
+runtime: yaml
+variables:
+  list:
+    fn::toString: [1, 2]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
 	}
+	assert.Equal(t, []string{
+		"<stdin>:5:19: fn::toString cannot convert List<number> to a string; use fn::toJSON instead",
+	}, diagStrings)
 }
 
-func TestToBase64(t *testing.T) {
+func TestToNumber(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-to-number
+runtime: yaml
+variables:
+  integer:
+    fn::toNumber: "3"
+  fraction:
+    fn::toNumber: " 2.5 "
+  number:
+    fn::toNumber: 7
+  computed:
+    fn::toNumber:
+      fn::secret: "42"
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	var computed interface{}
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, 3.0, e.variables["integer"])
+		assert.Equal(t, 2.5, e.variables["fraction"])
+		assert.Equal(t, 7.0, e.variables["number"])
+
+		e.pulumiCtx.Export("computed", e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			computed = x
+			return nil, nil
+		}))
+	})
+	assert.Equal(t, 42.0, computed)
+}
+
+func TestToNumberInvalid(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-to-number
+runtime: yaml
+variables:
+  constant:
+    fn::toNumber: three
+  computed:
+    fn::toNumber:
+      fn::join: ["", [four]]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:5:19: fn::toNumber could not parse "three" as a number`,
+	}, diagStrings)
+
+	tmpl = yamlTemplate(t, strings.TrimSpace(strings.Replace(text, "fn::toNumber: three", "fn::toNumber: \"3\"", 1)))
+	diags = testTemplateDiags(t, tmpl, nil)
+	diagStrings = nil
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:8:7: fn::toNumber could not parse "four" as a number`,
+	}, diagStrings)
+}
+
+func TestUnsecret(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-unsecret
+runtime: yaml
+variables:
+  password:
+    fn::secret: hunter2
+  length:
+    fn::unsecret:
+      value:
+        fn::toString: ${password}
+      reason: the length of the password is not sensitive
+  plain:
+    fn::unsecret:
+      value: hello
+      reason: never secret
+  list:
+    fn::unsecret:
+      value: ["${password}", visible]
+      reason: the list is shown in a test
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	var list interface{}
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "hello", e.variables["plain"])
+
+		length, ok := e.variables["length"].(pulumi.Output)
+		require.True(t, ok)
+		assert.False(t, pulumi.IsSecret(length))
+
+		listOut, ok := e.variables["list"].(pulumi.Output)
+		require.True(t, ok)
+		assert.False(t, pulumi.IsSecret(listOut))
+		e.pulumiCtx.Export("list", listOut.ApplyT(func(x interface{}) (interface{}, error) {
+			list = x
+			return x, nil
+		}))
+	})
+	assert.Equal(t, []interface{}{"hunter2", "visible"}, list)
+}
+
+func TestUnsecretRequiresReason(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-unsecret
+runtime: yaml
+variables:
+  bare:
+    fn::unsecret: ${password}
+  empty:
+    fn::unsecret:
+      value: ${password}
+      reason: ""
+`
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:19: fn::unsecret requires a reason; The argument to fn::unsecret must be an object with " +
+			"the 'value' to reveal and the 'reason' it is safe to do so",
+		"<stdin>:9:15: the reason given to fn::unsecret must be a non-empty string literal",
+	}, diagStrings)
+}
+
+func TestFromBase64ErrorOnInvalidUTF8(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		input    *ast.ToBase64Expr
-		expected string
-		isOutput bool
+		input *ast.FromBase64Expr
+		name  string
+		valid bool
 	}{
 		{
-			input: &ast.ToBase64Expr{
-				Value: ast.String("this is a test"),
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("a"))),
 			},
-			expected: "this is a test",
+			name:  "Valid ASCII",
+			valid: true,
 		},
 		{
-			input: &ast.ToBase64Expr{
-				Value: &ast.JoinExpr{
-					Delimiter: ast.String("."),
-					Values: ast.List(
-						ast.String("3"),
-						ast.String("141592"),
-					),
-				},
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xc3\xb1"))),
 			},
-			expected: "3.141592",
+			name:  "Valid 2 Octet Sequence",
+			valid: true,
 		},
 		{
-			input: &ast.ToBase64Expr{
-				Value: &ast.SymbolExpr{
-					Property: &ast.PropertyAccess{
-						Accessors: []ast.PropertyAccessor{
-							&ast.PropertyName{Name: "resA"},
-							&ast.PropertyName{Name: "out"},
-						},
-					},
-				},
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xe2\x82\xa1"))),
 			},
-			expected: "tuo",
-			isOutput: true,
+			name:  "Valid 3 Octet Sequence",
+			valid: true,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x90\x8c\xbc"))),
+			},
+			name:  "Valid 4 Octet Sequence",
+			valid: true,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf8\xa1\xa1\xa1\xa1"))),
+			},
+			name:  "Valid 5 Octet Sequence (but not Unicode!)",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xfc\xa1\xa1\xa1\xa1\xa1"))),
+			},
+			name:  "Valid 6 Octet Sequence (but not Unicode!)",
+			valid: false,
 		},
-	}
-
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.expected, func(t *testing.T) {
-			t.Parallel()
 
-			tmpl := template(t, &Template{
-				Resources: map[string]*Resource{
-					"resA": {
-						Type: "test:resource:type",
-						Properties: map[string]interface{}{
-							"foo": "oof",
-						},
-					},
-				},
-			})
-			testTemplate(t, tmpl, func(e *programEvaluator) {
-				v, ok := e.evaluateBuiltinToBase64(tt.input)
-				assert.True(t, ok)
-				if tt.isOutput {
-					out := v.(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
-						s, err := b64.StdEncoding.DecodeString(x.(string))
-						assert.NoError(t, err)
-						assert.Equal(t, tt.expected, string(s))
-						return nil, nil
-					})
-					e.pulumiCtx.Export("out", out)
-				} else {
-					s, err := b64.StdEncoding.DecodeString(v.(string))
-					assert.NoError(t, err)
-					assert.Equal(t, tt.expected, string(s))
-				}
-			})
-		})
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xfc\xa1\xa1\xa1\xa1\xa1"))),
+			},
+			name:  "Valid 6 Octet Sequence (but not Unicode!)",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xc3\x28"))),
+			},
+			name:  "Invalid 2 Octet Sequence",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xa0\xa1"))),
+			},
+			name:  "Invalid Sequence Identifier",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xe2\x28\xa1"))),
+			},
+			name:  "Invalid 3 Octet Sequence (in 2nd Octet)",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xe2\x82\x28"))),
+			},
+			name:  "Invalid 3 Octet Sequence (in 3rd Octet)",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x28\x8c\xbc"))),
+			},
+			name:  "Invalid 4 Octet Sequence (in 2nd Octet)",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x90\x28\xbc"))),
+			},
+			name:  "Invalid 4 Octet Sequence (in 3rd Octet)",
+			valid: false,
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String(b64.StdEncoding.EncodeToString([]byte("\xf0\x28\x8c\x28"))),
+			},
+			name:  "Invalid 4 Octet Sequence (in 4th Octet)",
+			valid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl := template(t, &Template{
+				Resources: map[string]*Resource{},
+			})
+			testTemplate(t, tmpl, func(e *programEvaluator) {
+				_, ok := e.evaluateBuiltinFromBase64(tt.input)
+				assert.Equal(t, tt.valid, ok)
+			})
+		})
+	}
+}
+
+func TestBase64Roundtrip(t *testing.T) {
+	t.Parallel()
+
+	tToFrom := struct {
+		input    *ast.ToBase64Expr
+		expected string
+	}{
+		input: &ast.ToBase64Expr{
+			Value: &ast.FromBase64Expr{
+				Value: ast.String("SGVsbG8sIFdvcmxk"),
+			},
+		},
+		expected: "SGVsbG8sIFdvcmxk",
+	}
+
+	t.Run(tToFrom.expected, func(t *testing.T) {
+		t.Parallel()
+
+		tmpl := template(t, &Template{
+			Resources: map[string]*Resource{},
+		})
+		testTemplate(t, tmpl, func(e *programEvaluator) {
+			v, ok := e.evaluateBuiltinToBase64(tToFrom.input)
+			assert.True(t, ok)
+			assert.Equal(t, tToFrom.expected, v)
+		})
+	})
+
+	tFromTo := struct {
+		input    *ast.FromBase64Expr
+		expected string
+	}{
+		input: &ast.FromBase64Expr{
+			Value: &ast.ToBase64Expr{
+				Value: ast.String("Hello, World!"),
+			},
+		},
+		expected: "Hello, World!",
+	}
+
+	t.Run(tFromTo.expected, func(t *testing.T) {
+		t.Parallel()
+
+		tmpl := template(t, &Template{
+			Resources: map[string]*Resource{},
+		})
+		testTemplate(t, tmpl, func(e *programEvaluator) {
+			v, ok := e.evaluateBuiltinFromBase64(tFromTo.input)
+			assert.True(t, ok)
+			assert.Equal(t, tFromTo.expected, v)
+		})
+	})
+}
+
+func TestFromBase64(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    *ast.FromBase64Expr
+		expected string
+		isOutput bool
+	}{
+		{
+			input: &ast.FromBase64Expr{
+				Value: ast.String("dGhpcyBpcyBhIHRlc3Q="),
+			},
+			expected: "this is a test",
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: &ast.JoinExpr{
+					Delimiter: ast.String(""),
+					Values: ast.List(
+						ast.String("My4xN"),
+						ast.String("DE1OTI="),
+					),
+				},
+			},
+			expected: "3.141592",
+		},
+		{
+			input: &ast.FromBase64Expr{
+				Value: &ast.ToBase64Expr{
+					Value: ast.String("test"),
+				},
+			},
+			expected: "test",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.expected, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl := template(t, &Template{
+				Resources: map[string]*Resource{
+					"resA": {
+						Type: "test:resource:type",
+						Properties: map[string]interface{}{
+							"foo": "oof",
+						},
+					},
+				},
+			})
+			testTemplate(t, tmpl, func(e *programEvaluator) {
+				v, ok := e.evaluateBuiltinFromBase64(tt.input)
+				assert.True(t, ok)
+				if tt.isOutput {
+					out := v.(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+						s := b64.StdEncoding.EncodeToString([]byte(tt.expected))
+						assert.Equal(t, s, v)
+						return nil, nil
+					})
+					e.pulumiCtx.Export("out", out)
+				} else {
+					assert.Equal(t, tt.expected, v)
+				}
+			})
+		})
+	}
+}
+
+func TestToBase64(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    *ast.ToBase64Expr
+		expected string
+		isOutput bool
+	}{
+		{
+			input: &ast.ToBase64Expr{
+				Value: ast.String("this is a test"),
+			},
+			expected: "this is a test",
+		},
+		{
+			input: &ast.ToBase64Expr{
+				Value: &ast.JoinExpr{
+					Delimiter: ast.String("."),
+					Values: ast.List(
+						ast.String("3"),
+						ast.String("141592"),
+					),
+				},
+			},
+			expected: "3.141592",
+		},
+		{
+			input: &ast.ToBase64Expr{
+				Value: &ast.SymbolExpr{
+					Property: &ast.PropertyAccess{
+						Accessors: []ast.PropertyAccessor{
+							&ast.PropertyName{Name: "resA"},
+							&ast.PropertyName{Name: "out"},
+						},
+					},
+				},
+			},
+			expected: "tuo",
+			isOutput: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.expected, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl := template(t, &Template{
+				Resources: map[string]*Resource{
+					"resA": {
+						Type: "test:resource:type",
+						Properties: map[string]interface{}{
+							"foo": "oof",
+						},
+					},
+				},
+			})
+			testTemplate(t, tmpl, func(e *programEvaluator) {
+				v, ok := e.evaluateBuiltinToBase64(tt.input)
+				assert.True(t, ok)
+				if tt.isOutput {
+					out := v.(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+						s, err := b64.StdEncoding.DecodeString(x.(string))
+						assert.NoError(t, err)
+						assert.Equal(t, tt.expected, string(s))
+						return nil, nil
+					})
+					e.pulumiCtx.Export("out", out)
+				} else {
+					s, err := b64.StdEncoding.DecodeString(v.(string))
+					assert.NoError(t, err)
+					assert.Equal(t, tt.expected, string(s))
+				}
+			})
+		})
+	}
+}
+
+func TestSub(t *testing.T) {
+	t.Parallel()
+
+	tmpl := template(t, &Template{
+		Variables: map[string]interface{}{
+			"foo": "oof",
+		},
+		Resources: map[string]*Resource{
+			"resA": {
+				Type: testResourceToken,
+				Properties: map[string]interface{}{
+					"foo": "oof",
+				},
+			},
+		},
+	})
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		v, ok := e.evaluateInterpolate(ast.MustInterpolate("Hello ${foo}!"))
+		assert.True(t, ok)
+		assert.Equal(t, "Hello oof!", v)
+
+		v, ok = e.evaluateInterpolate(ast.MustInterpolate("Hello ${resA.out} - ${resA.id}!!"))
+		assert.True(t, ok)
+		out := v.(pulumi.AnyOutput).ApplyT(func(x interface{}) (interface{}, error) {
+			assert.Equal(t, "Hello tuo - someID!!", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+}
+
+func TestEvaluateExpr(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-eval
+runtime: yaml
+variables:
+  greeting: hello
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		v, diags := e.Runner.EvaluateExpr(e.pulumiCtx, "${greeting}")
+		requireNoErrors(t, tmpl, diags)
+		assert.Equal(t, "hello", v)
+
+		v, diags = e.Runner.EvaluateExpr(e.pulumiCtx, `fn::join: ["-", [a, "${greeting}"]]`)
+		requireNoErrors(t, tmpl, diags)
+		assert.Equal(t, "a-hello", v)
+
+		v, diags = e.Runner.EvaluateExpr(e.pulumiCtx, "${resA.out}")
+		requireNoErrors(t, tmpl, diags)
+		out := v.(pulumi.AnyOutput).ApplyT(func(x interface{}) (interface{}, error) {
+			assert.Equal(t, "tuo", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+
+		_, diags = e.Runner.EvaluateExpr(e.pulumiCtx, "${missing}")
+		require.True(t, diags.HasErrors())
+		assert.Equal(t, `<expr>:1:1: resource or variable named "missing" could not be found`, diagString(diags[0]))
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-defaults
+runtime: yaml
+variables:
+  tags:
+    fn::defaults:
+      - Name: web
+      - Name: default
+        Team: infra
+  team: ${tags.Team}
+  secretTags:
+    fn::defaults:
+      - fn::secret:
+          Name: web
+      - Team: infra
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, map[string]interface{}{"Name": "web", "Team": "infra"}, e.variables["tags"])
+		assert.Equal(t, "infra", e.variables["team"])
+
+		s := e.variables["secretTags"].(pulumi.Output)
+		require.True(t, pulumi.IsSecret(s))
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, map[string]interface{}{"Name": "web", "Team": "infra"}, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestDefaultsRequiresObjects(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-defaults
+runtime: yaml
+variables:
+  tags:
+    fn::defaults:
+      - [web]
+      - Team: infra
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{"<stdin>:6:9: the first argument to fn::defaults must be an object, not a list"}, diagStrings)
+}
+
+func TestCoalesceList(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-coalesce
+runtime: yaml
+variables:
+  empty: []
+  subnets:
+    fn::coalesceList:
+      - ${empty}
+      - null
+      - [subnet-a, subnet-b]
+      - [subnet-c]
+  none:
+    fn::coalesceList:
+      - []
+  computed:
+    fn::coalesceList:
+      - []
+      - fn::secret: [subnet-d]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"subnet-a", "subnet-b"}, e.variables["subnets"])
+		assert.Equal(t, []interface{}{}, e.variables["none"])
+
+		s := e.variables["computed"].(pulumi.Output)
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, []interface{}{"subnet-d"}, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestCoalesceEmptyString(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-coalesce
+runtime: yaml
+variables:
+  unset: ""
+  region:
+    fn::coalesceEmptyString:
+      - ${unset}
+      - null
+      - us-west-2
+      - us-east-1
+  none:
+    fn::coalesceEmptyString:
+      - ""
+  computed:
+    fn::coalesceEmptyString:
+      - ""
+      - fn::secret: eu-west-1
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "us-west-2", e.variables["region"])
+		assert.Equal(t, "", e.variables["none"])
+
+		s := e.variables["computed"].(pulumi.Output)
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "eu-west-1", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestCoalesceListRequiresLists(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-coalesce
+runtime: yaml
+variables:
+  subnets:
+    fn::coalesceList:
+      - subnet-a
+      - [subnet-b]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{"<stdin>:6:9: the arguments to fn::coalesceList must be lists, not a string"}, diagStrings)
+
+	_, diags = TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	diagStrings = nil
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:6:9: List<any> is not assignable from string; Cannot assign type 'string' to type 'List<any>'",
+	}, diagStrings)
+}
+
+func TestFromJSON(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-from-json
+runtime: yaml
+variables:
+  settings:
+    fn::fromJSON: '{"name": "web", "ports": [80, 443], "public": true, "owner": null}'
+  roundTrip:
+    fn::fromJSON:
+      fn::toJSON: [a, b]
+  secretSettings:
+    fn::fromJSON:
+      fn::secret: '{"password": "hunter2"}'
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, map[string]interface{}{
+			"name":   "web",
+			"ports":  []interface{}{80.0, 443.0},
+			"public": true,
+			"owner":  nil,
+		}, e.variables["settings"])
+		assert.Equal(t, []interface{}{"a", "b"}, e.variables["roundTrip"])
+
+		s := e.variables["secretSettings"].(pulumi.Output)
+		require.True(t, pulumi.IsSecret(s))
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, map[string]interface{}{"password": "hunter2"}, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestFromJSONInvalid(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-from-json
+runtime: yaml
+variables:
+  settings:
+    fn::fromJSON: '{"name": '
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{"<stdin>:5:19: failed to decode JSON: unexpected end of JSON input"}, diagStrings)
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-merge
+runtime: yaml
+variables:
+  base:
+    name: web
+    tags:
+      team: infra
+      env: dev
+  merged:
+    fn::merge:
+      - ${base}
+      - tags:
+          env: prod
+        replicas: 3
+  env: ${merged.tags.env}
+  withSecret:
+    fn::merge:
+      - name: web
+      - fn::secret:
+          password: hunter2
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, map[string]interface{}{
+			"name":     "web",
+			"replicas": 3.0,
+			"tags": map[string]interface{}{
+				"team": "infra",
+				"env":  "prod",
+			},
+		}, e.variables["merged"])
+		assert.Equal(t, "prod", e.variables["env"])
+		// Merging must not modify its arguments.
+		assert.Equal(t, "dev", e.variables["base"].(map[string]interface{})["tags"].(map[string]interface{})["env"])
+
+		s := e.variables["withSecret"].(pulumi.Output)
+		require.True(t, pulumi.IsSecret(s))
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, map[string]interface{}{"name": "web", "password": "hunter2"}, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestMergeRequiresObjects(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-merge
+runtime: yaml
+variables:
+  merged:
+    fn::merge:
+      - name: web
+      - [not, an, object]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{"<stdin>:7:9: the arguments to fn::merge must be objects, not a list"}, diagStrings)
+}
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-template
+runtime: yaml
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  hosts:
+    fn::template:
+      - "{{range .hosts}}{{.}} {{$.domain}}\n{{end}}"
+      - hosts: [web, db]
+        domain: example.com
+  computed:
+    fn::template:
+      - "out={{.out}}"
+      - out: ${resA.bar}
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "web example.com\ndb example.com\n", e.variables["hosts"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "out=oof", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestTemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-template
+runtime: yaml
+variables:
+  unparsable:
+    fn::template:
+      - "{{.name"
+      - name: web
+  missing:
+    fn::template:
+      - "{{.nome}}"
+      - name: web
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:6:9: failed to parse template: template: fn::template:1: unclosed action`,
+		`<stdin>:9:5: failed to render template: template: fn::template:1:2: executing "fn::template" at <.nome>: map has no entry for key "nome"`,
+	}, diagStrings)
+}
+
+func TestReplace(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-replace
+runtime: yaml
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  stack:
+    fn::replace: [feature/new-thing, /, "-"]
+  computed:
+    fn::replace: ["${resA.bar}-ooo", oo, x]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "feature-new-thing", e.variables["stack"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "xf-xo", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestReplaceRequiresStrings(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-replace
+runtime: yaml
+variables:
+  stack:
+    fn::replace: [feature/new-thing, [/], "-"]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:38: string is not assignable from List<string>; Cannot assign 'List<string>' to 'string'",
+	}, diagStrings)
+}
+
+func TestTrim(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-trim
+runtime: yaml
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  trimmed:
+    fn::trim: "  some value\n"
+  prefix:
+    fn::trimPrefix: [refs/heads/main, refs/heads/]
+  suffix:
+    fn::trimSuffix: [archive.tar.gz, .gz]
+  unchanged:
+    fn::trimSuffix: [archive.tar.gz, .zip]
+  computed:
+    fn::trimPrefix: ["${resA.bar}-suffix", oo]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "some value", e.variables["trimmed"])
+		assert.Equal(t, "main", e.variables["prefix"])
+		assert.Equal(t, "archive.tar", e.variables["suffix"])
+		assert.Equal(t, "archive.tar.gz", e.variables["unchanged"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "f-suffix", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestTrimRequiresStrings(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-trim
+runtime: yaml
+variables:
+  trimmed:
+    fn::trim: [value]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:15: string is not assignable from List<string>; Cannot assign 'List<string>' to 'string'",
+	}, diagStrings)
+}
+
+func TestCaseBuiltins(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-case
+runtime: yaml
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  upper:
+    fn::upper: MyBucket-ß
+  lower:
+    fn::lower: MyBucket-ÄÖÜ
+  computed:
+    fn::upper: ${resA.bar}
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "MYBUCKET-ß", e.variables["upper"])
+		assert.Equal(t, "mybucket-äöü", e.variables["lower"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "OOF", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-format
+runtime: yaml
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  padded:
+    fn::format: ["%s-%03d", web, 7]
+  quoted:
+    fn::format: ["%q is %v%%", name, 2.5]
+  computed:
+    fn::format: ["%s/%x", "${resA.bar}", 255]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "web-007", e.variables["padded"])
+		assert.Equal(t, `"name" is 2.5%`, e.variables["quoted"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "oof/ff", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestFormatArgumentCountWarns(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-format
+runtime: yaml
+variables:
+  missing:
+    fn::format: ["%s-%d", web]
+  width:
+    fn::format: ["%*d", 5, 7]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:5: fn::format expects 2 arguments for its format string, got 1; " +
+			"The output will include a placeholder such as %!d(MISSING) or %!(EXTRA ...) where the arguments do not match.",
+	}, diagStrings)
+	assert.False(t, diags.HasErrors())
+}
+
+func TestFormatNumber(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-format-number
+runtime: yaml
+variables:
+  cost:
+    fn::formatNumber: [1234567.891, ",.2"]
+  negative:
+    fn::formatNumber: [-1234, ","]
+  rounded:
+    fn::formatNumber: [2.5, ".0"]
+  plain:
+    fn::formatNumber: [0.125, ""]
+  computed:
+    fn::formatNumber:
+      - fn::secret: 1
+      - ".3"
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "1,234,567.89", e.variables["cost"])
+		assert.Equal(t, "-1,234", e.variables["negative"])
+		assert.Equal(t, "2", e.variables["rounded"])
+		assert.Equal(t, "0.125", e.variables["plain"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "1.000", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestFormatNumberInvalidArguments(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-format-number
+runtime: yaml
+variables:
+  badFormat:
+    fn::formatNumber: [12, ".x"]
+  notNumber:
+    fn::formatNumber: [twelve, ","]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:28: invalid number format \".x\": expected a number of decimal places after '.'",
+		"<stdin>:7:24: number is not assignable from string; Cannot assign type 'string' to type 'number'",
+	}, diagStrings)
+}
+
+func TestJSONPath(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-json-path
+runtime: yaml
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  settings:
+    fn::fromJSON: '{"servers": [{"name": "a", "port": 80}, {"name": "b", "port": 443}]}'
+  first:
+    fn::jsonPath: ["${settings}", "$.servers[0].name"]
+  last:
+    fn::jsonPath: ["${settings}", "$['servers'][-1].port"]
+  names:
+    fn::jsonPath: ["${settings}", "$.servers[*].name"]
+  computed:
+    fn::jsonPath: [{ owner: "${resA.bar}" }, "$.owner"]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "a", e.variables["first"])
+		assert.Equal(t, float64(443), e.variables["last"])
+		assert.Equal(t, []interface{}{"a", "b"}, e.variables["names"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "oof", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestJSONPathDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-json-path
+runtime: yaml
+variables:
+  invalid:
+    fn::jsonPath: [{ a: b }, "$..a"]
+  missing:
+    fn::jsonPath: [{ a: b }, "$.c"]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:5:30: invalid JSONPath "$..a": recursive descent is not supported`,
+	}, diagStrings)
+
+	diags = testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	diagStrings = nil
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		`<stdin>:5:30: invalid JSONPath "$..a": recursive descent is not supported`,
+		`<stdin>:7:5: no value matches the JSONPath "$.c"`,
+	}, diagStrings)
+}
+
+func TestIf(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-if
+runtime: yaml
+configuration:
+  isProd:
+    type: boolean
+    default: true
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  size:
+    fn::if: ["${isProd}", large, small]
+  notEvaluated:
+    fn::if: ["${isProd}", kept, { fn::readFile: ./does-not-exist }]
+  computed:
+    fn::if: [{ fn::secret: false }, none, "${resA.bar}"]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "large", e.variables["size"])
+		assert.Equal(t, "kept", e.variables["notEvaluated"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "oof", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestIfRequiresBoolean(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-if
+runtime: yaml
+variables:
+  size:
+    fn::if: [yes please, large, small]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:14: boolean is not assignable from string; Cannot assign type 'string' to type 'boolean'",
+	}, diagStrings)
+}
+
+func TestLogicalBuiltins(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-logical
+runtime: yaml
+configuration:
+  env:
+    type: string
+    default: prod
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  isProd:
+    fn::equals: ["${env}", prod]
+  sameObjects:
+    fn::equals: [{ a: [1, 2] }, { a: [1, 2] }]
+  isDev:
+    fn::not: ${isProd}
+  all:
+    fn::and: ["${isProd}", true]
+  shortAnd:
+    fn::and: [false, { fn::select: [5, [true]] }]
+  any:
+    fn::or: ["${isDev}", false]
+  shortOr:
+    fn::or: [true, { fn::select: [5, [true]] }]
+  none:
+    fn::or: []
+  computed:
+    fn::and:
+      - true
+      - fn::equals: ["${resA.bar}", oof]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, true, e.variables["isProd"])
+		assert.Equal(t, true, e.variables["sameObjects"])
+		assert.Equal(t, false, e.variables["isDev"])
+		assert.Equal(t, true, e.variables["all"])
+		assert.Equal(t, false, e.variables["shortAnd"])
+		assert.Equal(t, false, e.variables["any"])
+		assert.Equal(t, true, e.variables["shortOr"])
+		assert.Equal(t, false, e.variables["none"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, true, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestLogicalBuiltinsRequireBooleans(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-logical
+runtime: yaml
+variables:
+  notString:
+    fn::not: yes please
+  andNumber:
+    fn::and: [true, 1]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, []string{
+		"<stdin>:5:14: boolean is not assignable from string; Cannot assign type 'string' to type 'boolean'",
+		"<stdin>:7:21: boolean is not assignable from number; Cannot assign type 'number' to type 'boolean'",
+	}, diagStrings)
+}
+
+func TestContains(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-contains
+runtime: yaml
+resources:
+  resA:
+    type: test:resource:type
+    properties:
+      foo: oof
+variables:
+  inList:
+    fn::contains: [[a, b], b]
+  notInList:
+    fn::contains: [[a, b], c]
+  structured:
+    fn::contains: [[{ a: [1, 2] }], { a: [1, 2] }]
+  hasKey:
+    fn::contains: [{ a: 1 }, a]
+  missingKey:
+    fn::contains: [{ a: 1 }, b]
+  computed:
+    fn::contains: [[bar, oof], "${resA.bar}"]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, true, e.variables["inList"])
+		assert.Equal(t, false, e.variables["notInList"])
+		assert.Equal(t, true, e.variables["structured"])
+		assert.Equal(t, true, e.variables["hasKey"])
+		assert.Equal(t, false, e.variables["missingKey"])
+
+		out := e.variables["computed"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, true, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestContainsWithConfig(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	const text = `
+name: test-yaml
+runtime: yaml
+configuration:
+  zones:
+    type: List<String>
+  ports:
+    type: List<Integer>
+  tags:
+    type: Map<String>
+variables:
+  hasZone:
+    fn::contains: ["${zones}", b]
+  hasPort:
+    fn::contains: ["${ports}", 443]
+  missingPort:
+    fn::contains: ["${ports}", 8080]
+  hasTag:
+    fn::contains: ["${tags}", env]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	setConfig(t, resource.PropertyMap{
+		projectConfigKey("zones"): resource.NewStringProperty(`["a", "b"]`),
+		projectConfigKey("ports"): resource.NewStringProperty("[80, 443]"),
+		projectConfigKey("tags"):  resource.NewStringProperty(`{"env": "prod"}`),
+	})
+	testRan := false
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, true, e.variables["hasZone"])
+		assert.Equal(t, true, e.variables["hasPort"])
+		assert.Equal(t, false, e.variables["missingPort"])
+		assert.Equal(t, true, e.variables["hasTag"])
+		testRan = true
+	})
+	requireNoErrors(t, tmpl, diags)
+	assert.True(t, testRan)
+}
+
+func TestContainsDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-contains
+runtime: yaml
+variables:
+  notCollection:
+    fn::contains: [abc, b]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:20: the collection given to fn::contains must be a list or an object, not string",
+	}, diagStrings)
+}
+
+func TestSort(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-sort
+runtime: yaml
+variables:
+  strings:
+    fn::sort: [b, c, a]
+  numbers:
+    fn::sort: [10, 2, 1.5]
+  descending:
+    fn::sort:
+      values: [b, c, a]
+      descending: true
+  zone:
+    fn::secret: b
+  fromOutput:
+    fn::sort: [c, "${zone}", a]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"a", "b", "c"}, e.variables["strings"])
+		assert.Equal(t, []interface{}{1.5, 2.0, 10.0}, e.variables["numbers"])
+		assert.Equal(t, []interface{}{"c", "b", "a"}, e.variables["descending"])
+
+		out := e.variables["fromOutput"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, []interface{}{"a", "b", "c"}, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestSortWithConfig(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	const text = `
+name: test-sort
+runtime: yaml
+configuration:
+  zones:
+    type: List<String>
+  ports:
+    type: List<Integer>
+  weights:
+    type: List<Integer>
+    default: [3, 1, 2]
+variables:
+  sortedZones:
+    fn::sort: ${zones}
+  sortedPorts:
+    fn::sort:
+      values: ${ports}
+      descending: true
+  sortedWeights:
+    fn::sort: ${weights}
+  mixed:
+    fn::sort: [1.5, "${ports[0]}"]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	setConfig(t, resource.PropertyMap{
+		projectConfigKey("zones"): resource.NewStringProperty(`["c", "a", "b"]`),
+		projectConfigKey("ports"): resource.NewStringProperty("[80, 8080, 443]"),
+	})
+	testRan := false
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"a", "b", "c"}, e.variables["sortedZones"])
+		assert.Equal(t, []interface{}{8080, 443, 80}, e.variables["sortedPorts"])
+		assert.Equal(t, []interface{}{1, 2, 3}, e.variables["sortedWeights"])
+		assert.Equal(t, []interface{}{1.5, 80}, e.variables["mixed"])
+		testRan = true
+	})
+	requireNoErrors(t, tmpl, diags)
+	assert.True(t, testRan)
+}
+
+func TestSortDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-sort
+runtime: yaml
+variables:
+  mixed:
+    fn::sort: [b, 1]
+  objects:
+    fn::sort: [{ a: 1 }]
+  notBoolean:
+    fn::sort:
+      values: [a]
+      descending: yes please
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:15: fn::sort can only sort lists of strings or of numbers, not List<Union<string, number>>",
+		"<stdin>:7:15: fn::sort can only sort lists of strings or of numbers, not List<{a: number}>",
+		"<stdin>:11:19: boolean is not assignable from string; Cannot assign type 'string' to type 'boolean'",
+	}, diagStrings)
+
+	// Without type checking, the same lists are rejected when they are sorted.
+	tmpl = yamlTemplate(t, strings.TrimSpace(text))
+	diags = testTemplateSyntaxDiags(t, tmpl, nil)
+	diagStrings = nil
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:15: fn::sort cannot sort a list that mixes strings and numbers",
+		"<stdin>:7:15: fn::sort can only sort strings and numbers, not an object",
+		"<stdin>:11:19: the descending argument of fn::sort must be a boolean, not a string",
+	}, diagStrings)
+
+	const unknownArg = `
+name: test-sort
+runtime: yaml
+variables:
+  unknownArg:
+    fn::sort:
+      values: [a]
+      reverse: true
+`
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(unknownArg)))
+	require.NoError(t, err)
+	diagStrings = nil
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:7:7: fn::sort has no argument named \"reverse\"; Valid arguments are 'values' and 'descending'",
+	}, diagStrings)
+}
+
+func TestUnique(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-unique
+runtime: yaml
+variables:
+  strings:
+    fn::unique: [b, a, b, c, a]
+  structured:
+    fn::unique: [{ a: [1] }, { a: [2] }, { a: [1] }]
+  zone:
+    fn::secret: a
+  fromOutput:
+    fn::unique: [a, "${zone}", b]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"b", "a", "c"}, e.variables["strings"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"a": []interface{}{1.0}},
+			map[string]interface{}{"a": []interface{}{2.0}},
+		}, e.variables["structured"])
+
+		out := e.variables["fromOutput"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, []interface{}{"a", "b"}, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestUniqueWithConfig(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	const text = `
+name: test-unique
+runtime: yaml
+configuration:
+  zones:
+    type: List<String>
+  ports:
+    type: List<Integer>
+variables:
+  uniqueZones:
+    fn::unique: ${zones}
+  uniquePorts:
+    fn::unique: ${ports}
+  mixed:
+    fn::unique: ["${ports[0]}", 80, 443]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	setConfig(t, resource.PropertyMap{
+		projectConfigKey("zones"): resource.NewStringProperty(`["a", "b", "a"]`),
+		projectConfigKey("ports"): resource.NewStringProperty("[80, 443, 80]"),
+	})
+	testRan := false
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"a", "b"}, e.variables["uniqueZones"])
+		assert.Equal(t, []interface{}{80, 443}, e.variables["uniquePorts"])
+		assert.Equal(t, []interface{}{80, 443.0}, e.variables["mixed"])
+		testRan = true
+	})
+	requireNoErrors(t, tmpl, diags)
+	assert.True(t, testRan)
+}
+
+func TestHashBuiltins(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-hash
+runtime: yaml
+variables:
+  sha256:
+    fn::sha256: hello world
+  sha1:
+    fn::sha1: hello world
+  secret:
+    fn::sha256:
+      fn::secret: hello world
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", e.variables["sha256"])
+		assert.Equal(t, "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed", e.variables["sha1"])
+
+		secret := e.variables["secret"].(pulumi.Output)
+		assert.True(t, pulumi.IsSecret(secret))
+		out := secret.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestDiagnosticSources(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-sources
+runtime: yaml
+variables:
+  stack:
+    fn::replace: [feature/new-thing, [/], "-"]
+`
+	tmpl, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	requireNoErrors(t, tmpl, diags)
+
+	_, tdiags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	require.Len(t, tdiags, 1)
+	assert.Equal(t, syntax.SourceTypeCheck, tdiags[0].Source)
+
+	err = pulumi.RunErr(func(ctx *pulumi.Context) error {
+		rdiags := newRunner(tmpl, newMockPackageMap()).Evaluate(ctx)
+		require.Len(t, rdiags, 1)
+		assert.Equal(t, syntax.SourceRuntime, rdiags[0].Source)
+
+		all := append(tdiags, rdiags...)
+		assert.Equal(t, tdiags, all.FromSource(syntax.SourceParse, syntax.SourceTypeCheck))
+		assert.Equal(t, rdiags, all.FromSource(syntax.SourceRuntime))
+		return nil
+	}, pulumi.WithMocks("project", "stack", &testMonitor{}))
+	assert.NoError(t, err)
+}
+
+func TestSecret(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-secret
+runtime: yaml
+variables:
+  mySecret:
+    fn::secret: my-special-secret
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.False(t, e.evalContext.Evaluate(e.pulumiCtx).HasErrors())
+		s := e.variables["mySecret"].(pulumi.Output)
+		require.True(t, pulumi.IsSecret(s))
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "my-special-secret", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestPathBuiltins(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-path
+runtime: yaml
+variables:
+  base:
+    fn::basename: /archive/dir/content.tar.gz
+  dir:
+    fn::dirname: /archive/dir/content.tar.gz
+  ext:
+    fn::fileExtension: /archive/dir/content.tar.gz
+  noExt:
+    fn::fileExtension: README
+  secretBase:
+    fn::basename:
+      fn::secret: /archive/dir/content.tar.gz
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "content.tar.gz", e.variables["base"])
+		assert.Equal(t, "/archive/dir", e.variables["dir"])
+		assert.Equal(t, ".gz", e.variables["ext"])
+		assert.Equal(t, "", e.variables["noExt"])
+
+		s := e.variables["secretBase"].(pulumi.Output)
+		require.True(t, pulumi.IsSecret(s))
+		out := s.ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "content.tar.gz", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestPathBuiltinsRequireStrings(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-path
+runtime: yaml
+variables:
+  base:
+    fn::basename: [a, b]
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{"<stdin>:5:19: expected argument to fn::basename to be a string, got a list"}, diagStrings)
+}
+
+func TestReadFile(t *testing.T) {
+	t.Parallel()
+
+	repoReadmePath, err := filepath.Abs("../../README.md")
+	assert.NoError(t, err)
+
+	repoReadmeText, err := os.ReadFile(repoReadmePath)
+	assert.NoError(t, err)
+
+	text := fmt.Sprintf(`
+name: test-readfile
+runtime: yaml
+variables:
+  textData:
+    fn::readFile: ./README.md
+  absInDirData:
+    fn::readFile: ${pulumi.cwd}/README.md
+  absOutOfDirData:
+    fn::readFile: %v
+`, repoReadmePath)
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		diags := e.evalContext.Evaluate(e.pulumiCtx)
+		requireNoErrors(t, tmpl, diags)
+		result, ok := e.variables["textData"].(string)
+		assert.True(t, ok)
+		assert.Equal(t, packageReadmeFile, result)
+
+		result, ok = e.variables["absInDirData"].(string)
+		assert.True(t, ok)
+		assert.Equal(t, packageReadmeFile, result)
+
+		result, ok = e.variables["absOutOfDirData"].(string)
+		assert.True(t, ok)
+		assert.Equal(t, string(repoReadmeText), result)
+	})
+}
+
+func TestReadFileMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-readfile
+runtime: yaml
+variables:
+  small:
+    fn::readFile:
+      path: ./README.md
+      maxBytes: 1000000
+  tooLarge:
+    fn::readFile:
+      path: ./README.md
+      maxBytes: 10
+`
+
+	readmePath, err := filepath.Abs("README.md")
+	require.NoError(t, err)
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf("<stdin>:10:13: file at path %v is %d bytes, which exceeds maxBytes (10)", readmePath, len(packageReadmeFile)),
+	}, diagStrings)
+}
+
+func TestReadFileArguments(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-readfile
+runtime: yaml
+variables:
+  missing:
+    fn::readFile:
+      maxBytes: 10
+  unknown:
+    fn::readFile:
+      path: ./README.md
+      size: 10
+`
+
+	tmpl, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	assert.Nil(t, tmpl)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:6:7: missing required argument 'path' to fn::readFile",
+		"<stdin>:10:7: fn::readFile has no argument named \"size\"; Valid arguments are 'path' and 'maxBytes'",
+	}, diagStrings)
+}
+
+func TestReadFileWarnsOnBinaryContent(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	require.NoError(t, os.WriteFile(path, []byte{0xff, 0xfe, 0x00, 0x01}, 0o600))
+
+	text := fmt.Sprintf(`
+name: test-readfile
+runtime: yaml
+variables:
+  binary:
+    fn::readFile: %v
+`, path)
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		diags := e.evalContext.Evaluate(e.pulumiCtx)
+		requireNoErrors(t, tmpl, diags)
+		var diagStrings []string
+		for _, v := range diags {
+			diagStrings = append(diagStrings, diagString(v))
+		}
+		assert.Contains(t, diagStrings,
+			fmt.Sprintf("<stdin>:5:19: file at path %v is not valid UTF-8 text; "+
+				"fn::readFile reads text files. To use binary content, encode it with base64 first, "+
+				"or use fn::fileAsset to upload it as an asset.", path))
+	})
+}
+
+//nolint:paralleltest // uses t.Setenv
+func TestGetEnv(t *testing.T) {
+	t.Setenv("PULUMI_YAML_TEST_GETENV", "from-env")
+
+	const text = `
+name: test-getenv
+runtime: yaml
+variables:
+  set:
+    fn::getEnv: PULUMI_YAML_TEST_GETENV
+  setWithDefault:
+    fn::getEnv:
+      name: PULUMI_YAML_TEST_GETENV
+      default: fallback
+  unsetWithDefault:
+    fn::getEnv:
+      name: PULUMI_YAML_TEST_GETENV_UNSET
+      default: fallback
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+	require.Len(t, diags, 3)
+	assert.Equal(t, "<stdin>:5:5: fn::getEnv makes the program depend on the environment it runs in; "+
+		"A preview may not match the update that follows it, if the environment variable differs between them",
+		diagString(diags[0]))
+
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "from-env", e.variables["set"])
+		assert.Equal(t, "from-env", e.variables["setWithDefault"])
+		assert.Equal(t, "fallback", e.variables["unsetWithDefault"])
+	})
+}
+
+func TestGetEnvUnset(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-getenv
+runtime: yaml
+variables:
+  unset:
+    fn::getEnv: PULUMI_YAML_TEST_GETENV_UNSET
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Contains(t, diagStrings,
+		"<stdin>:5:5: environment variable PULUMI_YAML_TEST_GETENV_UNSET is not set, and fn::getEnv has no default")
+}
+
+func TestGeneratedValues(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-generated
+runtime: yaml
+variables:
+  id:
+    fn::uuid: {}
+  now:
+    fn::timestamp:
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	requireNoErrors(t, tmpl, diags)
+
+	run := func(preview bool, check func(r *Runner)) {
+		err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+			r := newRunner(tmpl, newMockPackageMap())
+			if diags := r.Evaluate(ctx); diags.HasErrors() {
+				return diags
+			}
+			check(r)
+			return nil
+		}, pulumi.WithMocks(testProject, "dev", &testMonitor{}), func(ri *pulumi.RunInfo) {
+			ri.DryRun = preview
+		})
+		require.NoError(t, err)
+	}
+
+	// The values are unknown during a preview, so that previews are stable.
+	run(true, func(r *Runner) {
+		assert.IsType(t, pulumi.AnyOutput{}, r.variables["id"])
+		assert.IsType(t, pulumi.AnyOutput{}, r.variables["now"])
+	})
+	run(false, func(r *Runner) {
+		id, ok := r.variables["id"].(string)
+		require.True(t, ok)
+		parsed, err := uuid.Parse(id)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(4), parsed.Version())
+
+		now, ok := r.variables["now"].(string)
+		require.True(t, ok)
+		_, err = time.Parse(time.RFC3339, now)
+		assert.NoError(t, err)
+	})
+}
+
+func TestGeneratedValuesTakeNoArguments(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-generated
+runtime: yaml
+variables:
+  id:
+    fn::uuid: [a]
+`
+
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, []string{"<stdin>:5:15: fn::uuid takes no arguments; Write it as 'fn::uuid: {}'"}, diagStrings)
+}
+
+func TestCidrBuiltins(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-cidr
+runtime: yaml
+variables:
+  subnet:
+    fn::cidrSubnet: [10.0.0.0/16, 8, 2]
+  host:
+    fn::cidrHost: ["${subnet}", 5]
+  netnum:
+    fn::secret: 1
+  fromOutput:
+    fn::cidrSubnet: [10.0.0.0/16, 8, "${netnum}"]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "10.0.2.0/24", e.variables["subnet"])
+		assert.Equal(t, "10.0.2.5", e.variables["host"])
+		out := e.variables["fromOutput"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			assert.Equal(t, "10.0.1.0/24", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+}
+
+func TestCidrBuiltinsDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-cidr
+runtime: yaml
+variables:
+  badPrefix:
+    fn::cidrHost: [10.0.0.0, 1]
+  badNumber:
+    fn::cidrSubnet: [10.0.0.0/16, 8, two]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:5:20: "10.0.0.0" is not a valid CIDR prefix`,
+		"<stdin>:7:38: integer is not assignable from string; Cannot assign type 'string' to type 'integer'",
+	}, diagStrings)
+
+	const outOfRange = `
+name: test-cidr
+runtime: yaml
+variables:
+  subnet:
+    fn::cidrSubnet: [10.0.0.0/16, 8, 256]
+`
+	tmpl = yamlTemplate(t, strings.TrimSpace(outOfRange))
+	diags = testTemplateDiags(t, tmpl, nil)
+	diagStrings = nil
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Contains(t, diagStrings, "<stdin>:5:5: prefix 10.0.0.0/16 extended by 8 bits has no network number 256")
+}
+
+func TestRange(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-range
+runtime: yaml
+variables:
+  up:
+    fn::range: [0, 3]
+  down:
+    fn::range: [3, 0]
+  stepped:
+    fn::range: [8000, 8010, 4]
+  empty:
+    fn::range: [1, 1]
+  port:
+    fn::select:
+      - 1
+      - fn::range: [8080, 8090]
+  count:
+    fn::secret: 2
+  fromOutput:
+    fn::range: [0, "${count}"]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{0.0, 1.0, 2.0}, e.variables["up"])
+		assert.Equal(t, []interface{}{3.0, 2.0, 1.0}, e.variables["down"])
+		assert.Equal(t, []interface{}{8000.0, 8004.0, 8008.0}, e.variables["stepped"])
+		assert.Equal(t, []interface{}{}, e.variables["empty"])
+		assert.Equal(t, 8081.0, e.variables["port"])
+		out := e.variables["fromOutput"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			assert.Equal(t, []interface{}{0.0, 1.0}, x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+}
+
+func TestRangeWithConfig(t *testing.T) { //nolint:paralleltest // uses t.Setenv
+	const text = `
+name: test-yaml
+runtime: yaml
+configuration:
+  count:
+    type: Integer
+  step:
+    type: Integer
+    default: 2
+variables:
+  indices:
+    fn::range: [0, "${count}", "${step}"]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	setConfig(t, resource.PropertyMap{
+		projectConfigKey("count"): resource.NewStringProperty("5"),
+	})
+	testRan := false
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{0.0, 2.0, 4.0}, e.variables["indices"])
+		testRan = true
+	})
+	requireNoErrors(t, tmpl, diags)
+	assert.True(t, testRan)
+}
+
+func TestRangeDiags(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-range
+runtime: yaml
+variables:
+  zero:
+    fn::range: [0, 3, 0]
+  mismatched:
+    fn::range: [0, 3, -1]
+  tooLong:
+    fn::range: [0, 100000]
+  notNumber:
+    fn::range: [0, three]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, d := range diags {
+		diagStrings = append(diagStrings, diagString(d))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:5: the step of fn::range must not be zero",
+		"<stdin>:7:5: the step of fn::range must be positive when the range counts up, not -1",
+		"<stdin>:9:5: fn::range would generate 100000 numbers, but it generates at most 1024",
+		"<stdin>:11:20: number is not assignable from string; Cannot assign type 'string' to type 'number'",
+	}, diagStrings)
+
+}
+
+func TestReadDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"b.yaml", "a.yaml"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o700))
+
+	text := fmt.Sprintf(`
+name: test-readdir
+runtime: yaml
+variables:
+  files:
+    fn::readDir: %v
+`, dir)
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, []interface{}{"a.yaml", "b.yaml"}, e.variables["files"])
+	})
+}
+
+func TestReadDirRejectsFiles(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-readdir
+runtime: yaml
+variables:
+  files:
+    fn::readDir: ./README.md
+`
+
+	readmePath, err := filepath.Abs("README.md")
+	require.NoError(t, err)
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf("<stdin>:5:18: path %v is a file, not a directory; use fn::readFile to read its contents", readmePath),
+	}, diagStrings)
+}
+
+func TestFileArchiveGlob(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "css/site.css", "css/vendor/reset.css", "notes.txt", "build[1]/app.js"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o600))
+	}
+
+	text := fmt.Sprintf(`
+name: test-glob
+runtime: yaml
+variables:
+  site:
+    fn::assetArchive:
+      public:
+        fn::fileArchive: %[1]v/**
+      styles:
+        fn::fileArchive: %[1]v/**/*.css
+      empty:
+        fn::fileArchive: %[1]v/*.png
+      literal:
+        fn::fileArchive: %[1]v/build[1]
+`, dir)
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		diags := e.evalContext.Evaluate(e.pulumiCtx)
+		requireNoErrors(t, tmpl, diags)
+		var diagStrings []string
+		for _, v := range diags {
+			diagStrings = append(diagStrings, diagString(v))
+		}
+		assert.Contains(t, diagStrings, fmt.Sprintf("<stdin>:11:26: no files match %v/*.png", dir))
+
+		archive, ok := e.variables["site"].(pulumi.Archive)
+		require.True(t, ok)
+		keys := func(a interface{}) []string {
+			var keys []string
+			for k := range a.(pulumi.Archive).Assets() {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return keys
+		}
+		assets := archive.Assets()
+		assert.Equal(t, []string{"build[1]/app.js", "css/site.css", "css/vendor/reset.css", "index.html", "notes.txt"},
+			keys(assets["public"]))
+		assert.Equal(t, []string{"css/site.css", "css/vendor/reset.css"}, keys(assets["styles"]))
+		assert.Empty(t, keys(assets["empty"]))
+		assert.Equal(t, filepath.Join(dir, "index.html"),
+			assets["public"].(pulumi.Archive).Assets()["index.html"].(pulumi.Asset).Path())
+		// An existing path is archived as is, even though its name contains glob characters.
+		assert.Equal(t, filepath.Join(dir, "build[1]"), assets["literal"].(pulumi.Archive).Path())
+	})
+}
+
+func TestFileArchiveGlobForbidsPathTraversal(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-glob
+runtime: yaml
+variables:
+  escaped:
+    fn::fileArchive: ${pulumi.cwd}/../../*.go
+  missing:
+    fn::fileArchive: ${pulumi.cwd}/../../does-not-exist/*.go
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:22: Argument must be a constant or contained in the project dir",
+		"<stdin>:7:22: Argument must be a constant or contained in the project dir",
+	}, diagStrings)
+}
+
+// TestReadFileForbidsPathTraversal ensures that we forbid certain malicious path behaviors which
+// allow escaping the project directory in static YAML.
+//
+// The example program uses a non-constant path which escapes the project directory.
+//
+// Non-constant paths which read from the project dir are considered safe, likely as uses of
+// ${pulumi.cwd}, see above. Constant, absolute path are also permitted, sometimes necessary to use
+// a secret or token.
+func TestReadFileForbidsPathTraversal(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-readfile
+runtime: yaml
+outputs:
+  readme:
+    fn::readFile: ${pulumi.cwd}/../../go.mod # imagine this is /etc/shadow, /var/run/secrets/tokens, etc.
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, diagStrings,
+		[]string{
+			"<stdin>:5:5: Argument must be a constant or contained in the project dir",
+		},
+	)
+}
+
+func TestReadDirForbidsPathTraversal(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-readdir
+runtime: yaml
+outputs:
+  files:
+    fn::readDir: ${pulumi.cwd}/../..
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:5:5: Argument must be a constant or contained in the project dir",
+	}, diagStrings)
+}
+
+func TestJoinTemplate(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-readfile
+runtime: yaml
+variables:
+  inputs:
+    - "foo"
+    - "bar"
+  foo-bar:
+    fn::join:
+      - "-"
+      - ${inputs}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		diags := e.evalContext.Evaluate(e.pulumiCtx)
+		requireNoErrors(t, tmpl, diags)
+		result, ok := e.variables["foo-bar"].(string)
+		assert.True(t, ok)
+		assert.Equal(t, "foo-bar", result)
+	})
+}
+
+func TestEscapingInterpolationInTemplate(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-readfile
+runtime: yaml
+variables:
+    world: world
+    interpolated: hello ${world}!
+    escaped: hello $${world}!
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		diags := e.evalContext.Evaluate(e.pulumiCtx)
+		requireNoErrors(t, tmpl, diags)
+		result, ok := e.variables["interpolated"].(string)
+		assert.True(t, ok)
+		assert.Equal(t, "hello world!", result)
+
+		result, ok = e.variables["escaped"].(string)
+		assert.True(t, ok)
+		assert.Equal(t, "hello ${world}!", result)
+	})
+}
+
+func TestInterpolationDefault(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-interpolation-default
+runtime: yaml
+variables:
+  world: world
+  unset: null
+  object:
+    present: yes
+  secretUnset:
+    fn::secret: null
+  set: hello ${world:-nobody}!
+  defaulted: hello ${unset:-nobody}!
+  missingProperty: ${object.absent:-none}
+  fromOutput: ${secretUnset:-hidden}
+  escaped: hello $${unset:-nobody}!
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	hasRun := false
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "hello world!", e.variables["set"])
+		assert.Equal(t, "hello nobody!", e.variables["defaulted"])
+		assert.Equal(t, "none", e.variables["missingProperty"])
+		assert.Equal(t, "hello ${unset:-nobody}!", e.variables["escaped"])
+		out := e.variables["fromOutput"].(pulumi.Output).ApplyT(func(x interface{}) (interface{}, error) {
+			hasRun = true
+			assert.Equal(t, "hidden", x)
+			return nil, nil
+		})
+		e.pulumiCtx.Export("out", out)
+	})
+	assert.True(t, hasRun)
+}
+
+func TestInterpolationQuotedClosingBrace(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-interpolation-brace
+runtime: yaml
+variables:
+  weird}name: root
+  object:
+    weird}key: nested
+  fromRoot: hello ${["weird}name"]}!
+  fromObject: hello ${object["weird}key"]}!
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "hello root!", e.variables["fromRoot"])
+		assert.Equal(t, "hello nested!", e.variables["fromObject"])
+	})
+}
+
+func TestInterpolationDefaultConfig(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-interpolation-default
+runtime: yaml
+configuration:
+  region:
+    type: String
+variables:
+  zone: ${region:-us-west-2}a
+`
+
+	// A config value that is interpolated with a default doesn't need to be set.
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testRan := false
+	diags := testTemplateDiags(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "us-west-2a", e.variables["zone"])
+		testRan = true
+	})
+	requireNoErrors(t, tmpl, diags)
+	assert.True(t, testRan)
+}
+
+func TestInterpolationDefaultConfigRequired(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-interpolation-default
+runtime: yaml
+configuration:
+  region:
+    type: String
+  size:
+    type: String
+variables:
+  zone: ${region:-us-west-2}a
+  strict: ${region}
+  required: ${size}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	// References without a default still require the value.
+	assert.ElementsMatch(t, []string{
+		"<stdin>:6:3: missing required configuration variable 'size'; run `pulumi config` to set",
+		"<stdin>:10:11: missing required configuration variable 'region'; run `pulumi config` to set",
+	}, diagStrings)
+}
+
+func TestInterpolationDefaultUndefined(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-interpolation-default
+runtime: yaml
+variables:
+  value: ${undefined:-fallback}
+`
+
+	// Defaults apply to values that are null or unset config, not to names that aren't declared.
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateDiags(t, tmpl, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:4:10: resource, variable, or config value \"undefined\" not found",
+	}, diagStrings)
+}
+
+func TestJoinForbidsNonStringArgs(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-readfile
+runtime: yaml
+variables:
+  inputs:
+    - 1
+    - { "foo": "bar" }
+    - [1, 2, 3]
+    - true
+  foo-bar:
+    fn::join:
+      - "-"
+      - ${inputs}
+  foo-err:
+    fn::join:
+      - "-"
+      - ${inputs[1]}
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, diagStrings,
+		[]string{
+			"<stdin>:12:9: the second argument to fn::join must be a list of strings, found an object at index 1",
+			"<stdin>:12:9: the second argument to fn::join must be a list of strings, found a list at index 2",
+			"<stdin>:16:9: the second argument to fn::join must be a list, found an object",
+		},
+	)
+}
+
+func TestJoinCoercesScalars(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-join
+runtime: yaml
+variables:
+  numbers:
+    fn::join:
+      - "-"
+      - [1, 2.5, 3]
+  mixed:
+    fn::join:
+      - ","
+      - [a, true, 10]
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	testTemplate(t, tmpl, func(e *programEvaluator) {
+		assert.Equal(t, "1-2.5-3", e.variables["numbers"])
+		assert.Equal(t, "a,true,10", e.variables["mixed"])
+	})
+}
+
+func TestUnicodeLogicalName(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  "bB-Beta_beta.💜⁉":
+    test: oof
+resources:
+  "aA-Alpha_alpha.\U0001F92F⁉️":
+    type: test:resource:type
+    properties:
+      foo: "${[\"bB-Beta_beta.💜⁉\"].test}"
+`
+
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {})
+	requireNoErrors(t, tmpl, diags)
+}
+
+func TestPoisonResult(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-poison
+runtime: yaml
+variables:
+  poisoned:
+    fn::invoke:
+      function: test:invoke:poison
+      arguments:
+        foo: three
+      return: value
+  never-run:
+    fn::invoke:
+      function: test:invoke:poison
+      arguments:
+        foo: ${poisoned}
+      return: value
+resources:
+  alsoPoisoned:
+    type: test:resource:not-run
+    properties:
+      foo: ${poisoned}`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testInvokeDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+
+	assert.ElementsMatch(t, diagStrings,
+		[]string{
+			"<stdin>:5:5: Don't eat the poison",
+		})
+}
+
+func TestEmptyInterpolate(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-empty
+runtime: yaml
+variables:
+  empty: ${}
+`
+	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
 	}
+
+	assert.ElementsMatch(t, diagStrings,
+		[]string{
+			"<stdin>:4:10: Property access expressions cannot be empty",
+		})
 }
 
-func TestSub(t *testing.T) {
+func TestReadResource(t *testing.T) {
 	t.Parallel()
-
-	tmpl := template(t, &Template{
-		Variables: map[string]interface{}{
-			"foo": "oof",
-		},
-		Resources: map[string]*Resource{
-			"resA": {
-				Type: testResourceToken,
-				Properties: map[string]interface{}{
-					"foo": "oof",
-				},
-			},
-		},
+	text := `
+name: consumer
+runtime: yaml
+resources:
+  bucket:
+    type: test:read:Resource
+    get:
+      id: ${id}
+      state:
+        foo: bar
+variables:
+  id: bucket-123456
+  isRight: ${bucket.tags["isRight"]}
+`
+	templ := yamlTemplate(t, text)
+	var wasRun bool
+	diags := testInvokeDiags(t, templ, func(r *Runner) {
+		r.variables["isRight"].(pulumi.AnyOutput).ApplyT(func(s interface{}) interface{} {
+			wasRun = true
+			assert.Equal(t, "yes", s)
+			return s
+		})
 	})
-	testTemplate(t, tmpl, func(e *programEvaluator) {
-		v, ok := e.evaluateInterpolate(ast.MustInterpolate("Hello ${foo}!"))
-		assert.True(t, ok)
-		assert.Equal(t, "Hello oof!", v)
+	assert.True(t, wasRun)
+	assert.Len(t, diags, 0)
+}
 
-		v, ok = e.evaluateInterpolate(ast.MustInterpolate("Hello ${resA.out} - ${resA.id}!!"))
-		assert.True(t, ok)
-		out := v.(pulumi.AnyOutput).ApplyT(func(x interface{}) (interface{}, error) {
-			assert.Equal(t, "Hello tuo - someID!!", x)
-			return nil, nil
+func TestReadResourceNoState(t *testing.T) {
+	t.Parallel()
+	text := `
+name: consumer
+runtime: yaml
+resources:
+  bucket:
+    type: test:read:Resource
+    get:
+      id: no-state
+variables:
+  id: bucket-123456
+  isRight: ${bucket.tags["isRight"]}
+`
+	templ := yamlTemplate(t, text)
+	var wasRun bool
+	diags := testInvokeDiags(t, templ, func(r *Runner) {
+		r.variables["isRight"].(pulumi.AnyOutput).ApplyT(func(s interface{}) interface{} {
+			wasRun = true
+			assert.Equal(t, "yes", s)
+			return s
 		})
-		e.pulumiCtx.Export("out", out)
 	})
+	assert.True(t, wasRun)
+	assert.Len(t, diags, 0)
 }
 
-func TestSecret(t *testing.T) {
+func TestReadResourceEventualId(t *testing.T) {
 	t.Parallel()
-
-	const text = `
-name: test-secret
+	text := `
+name: consumer
 runtime: yaml
+resources:
+  bucket:
+    type: test:read:Resource
+    get:
+      id: no-state
+  v2:
+    type: test:read:Resource
+    get:
+      id: eventual-${bucket.tags["isRight"]}
 variables:
-  mySecret:
-    fn::secret: my-special-secret
+  isRight: ${v2.tags["isRight"]}
 `
-	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	hasRun := false
-	testTemplate(t, tmpl, func(e *programEvaluator) {
-		assert.False(t, e.evalContext.Evaluate(e.pulumiCtx).HasErrors())
-		s := e.variables["mySecret"].(pulumi.Output)
-		require.True(t, pulumi.IsSecret(s))
-		out := s.ApplyT(func(x interface{}) (interface{}, error) {
-			hasRun = true
-			assert.Equal(t, "my-special-secret", x)
-			return nil, nil
+	templ := yamlTemplate(t, text)
+	var wasRun bool
+	diags := testInvokeDiags(t, templ, func(r *Runner) {
+		r.variables["isRight"].(pulumi.AnyOutput).ApplyT(func(s interface{}) interface{} {
+			wasRun = true
+			assert.Equal(t, "definitely", s)
+			return s
 		})
-		e.pulumiCtx.Export("out", out)
 	})
-	assert.True(t, hasRun)
+	assert.True(t, wasRun)
+	assert.Len(t, diags, 0)
 }
 
-func TestReadFile(t *testing.T) {
+func TestReadResourceIDRuntimeTypeErorr(t *testing.T) {
 	t.Parallel()
-
-	repoReadmePath, err := filepath.Abs("../../README.md")
-	assert.NoError(t, err)
-
-	repoReadmeText, err := os.ReadFile(repoReadmePath)
-	assert.NoError(t, err)
-
-	text := fmt.Sprintf(`
-name: test-readfile
+	text := `
+name: consumer
 runtime: yaml
+resources:
+  bucket:
+    type: test:read:Resource
+    get:
+      id: no-state
+  v2:
+    type: test:read:Resource
+    get:
+      id: { a: b }
 variables:
-  textData:
-    fn::readFile: ./README.md
-  absInDirData:
-    fn::readFile: ${pulumi.cwd}/README.md
-  absOutOfDirData:
-    fn::readFile: %v
-`, repoReadmePath)
-
-	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	testTemplate(t, tmpl, func(e *programEvaluator) {
-		diags := e.evalContext.Evaluate(e.pulumiCtx)
-		requireNoErrors(t, tmpl, diags)
-		result, ok := e.variables["textData"].(string)
-		assert.True(t, ok)
-		assert.Equal(t, packageReadmeFile, result)
+  isRight: ${v2.tags["isRight"]}
+`
+	templ := yamlTemplate(t, text)
+	diags := testInvokeDiags(t, templ, nil)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
 
-		result, ok = e.variables["absInDirData"].(string)
-		assert.True(t, ok)
-		assert.Equal(t, packageReadmeFile, result)
+	assert.ElementsMatch(t, diagStrings, []string{
+		"<stdin>:12:11: { a: b } must be a string, instead got type map[string]interface {}; This indicates a bug in the Pulumi YAML type checker. Please open an issue at https://github.com/pulumi/pulumi-yaml/issues/new/choose",
+	})
+}
 
-		result, ok = e.variables["absOutOfDirData"].(string)
-		assert.True(t, ok)
-		assert.Equal(t, string(repoReadmeText), result)
+func TestReadResourceErrorTyping(t *testing.T) {
+	t.Parallel()
+	text := `
+name: consumer
+runtime: yaml
+resources:
+  bucket:
+    type: test:read:Resource
+    properties:
+      foo: bar
+    get:
+      state:
+        fizz: buzz
+`
+	templ := yamlTemplate(t, text)
+	diags := testTemplateDiags(t, templ, nil)
+	assert.Len(t, diags, 2)
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.ElementsMatch(t, diagStrings, []string{
+		"<stdin>:5:3: Resource fields properties and get are mutually exclusive; Properties is used to describe a resource managed by Pulumi.\nGet is used to describe a resource managed outside of the current Pulumi stack.\nSee https://www.pulumi.com/docs/intro/concepts/resources/get for more details on using Get.",
+		"<stdin>:11:9: Property fizz does not exist on 'test:read:Resource'; Cannot assign '{fizz: string}' to 'test:read:Resource':\n  Existing properties are: foo",
 	})
 }
 
-// TestReadFileForbidsPathTraversal ensures that we forbid certain malicious path behaviors which
-// allow escaping the project directory in static YAML.
-//
-// The example program uses a non-constant path which escapes the project directory.
-//
-// Non-constant paths which read from the project dir are considered safe, likely as uses of
-// ${pulumi.cwd}, see above. Constant, absolute path are also permitted, sometimes necessary to use
-// a secret or token.
-func TestReadFileForbidsPathTraversal(t *testing.T) {
+func TestResourceWithSecret(t *testing.T) {
 	t.Parallel()
 
 	text := `
-name: test-readfile
+name: test-secret
 runtime: yaml
-outputs:
-  readme:
-    fn::readFile: ${pulumi.cwd}/../../go.mod # imagine this is /etc/shadow, /var/run/secrets/tokens, etc.
+resources:
+  sec:
+    type: test:resource:with-secret
+    properties:
+      foo: baz
+      bar: frotz
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			assert.Equal(t, "bar", args.RegisterRPC.GetAdditionalSecretOutputs()[0])
+			return args.Name, args.Inputs, nil
+		},
+	}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		runner := newRunner(tmpl, newMockPackageMap())
+		err := runner.Evaluate(ctx)
+		assert.Len(t, err, 0)
+		assert.Equal(t, err.Error(), "no diagnostics")
+		return nil
+	}, pulumi.WithMocks("project", "stack", mocks))
+	assert.NoError(t, err)
+}
+
+func TestResourceReadOnlyProperty(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  res:
+    type: test:resource:with-output
+    properties:
+      foo: bar
+      arn: arn:aws:s3:::bucket
 `
-
 	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
-
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
 	var diagStrings []string
 	for _, v := range diags {
 		diagStrings = append(diagStrings, diagString(v))
 	}
-	assert.ElementsMatch(t, diagStrings,
-		[]string{
-			"<stdin>:5:5: Argument must be a constant or contained in the project dir",
-		},
-	)
+	assert.Equal(t, []string{
+		"<stdin>:8:7: Property arn is read-only and cannot be set as an input; arn is an output of test:resource:with-output",
+	}, diagStrings)
 }
 
-func TestJoinTemplate(t *testing.T) {
+func TestResourceChangeOptionPaths(t *testing.T) {
 	t.Parallel()
 
-	text := `
-name: test-readfile
+	const text = `
+name: test-yaml
 runtime: yaml
-variables:
-  inputs:
-    - "foo"
-    - "bar"
-  foo-bar:
-    fn::join:
-      - "-"
-      - ${inputs}
+resources:
+  res:
+    type: test:resource:with-output
+    properties:
+      foo: bar
+    options:
+      ignoreChanges:
+        - foo
+        - arn
+        - tags["env"]
+        - "*"
+      replaceOnChanges:
+        - foo.bar
+        - items[*].name
 `
-
 	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	testTemplate(t, tmpl, func(e *programEvaluator) {
-		diags := e.evalContext.Evaluate(e.pulumiCtx)
-		requireNoErrors(t, tmpl, diags)
-		result, ok := e.variables["foo-bar"].(string)
-		assert.True(t, ok)
-		assert.Equal(t, "foo-bar", result)
-	})
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:11:11: ignoreChanges references arn, which is an output-only property of test:resource:with-output; " +
+			"ignoreChanges only applies to input properties, so this entry has no effect",
+		"<stdin>:12:11: ignoreChanges references tags, which is not a property of test:resource:with-output",
+	}, diagStrings)
+	assert.False(t, diags.HasErrors())
 }
 
-func TestEscapingInterpolationInTemplate(t *testing.T) {
+func TestResourceProvidersMustBeUsed(t *testing.T) {
 	t.Parallel()
 
-	text := `
-name: test-readfile
+	const text = `
+name: test-yaml
 runtime: yaml
-variables:
-    world: world
-    interpolated: hello ${world}!
-    escaped: hello $${world}!
+resources:
+  testProvider:
+    type: pulumi:providers:test
+  dockerProvider:
+    type: pulumi:providers:docker
+  awsProvider:
+    type: pulumi:providers:aws
+  res:
+    type: test:resource:type
+    properties:
+      foo: bar
+    options:
+      providers:
+        - ${testProvider}
+        - ${dockerProvider}
+        - ${awsProvider}
+  child:
+    type: docker:index:Network
+    options:
+      parent: ${res}
+  comp:
+    type: test:component:type
+    properties:
+      foo: bar
+    options:
+      providers:
+        - ${awsProvider}
 `
-
 	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	testTemplate(t, tmpl, func(e *programEvaluator) {
-		diags := e.evalContext.Evaluate(e.pulumiCtx)
-		requireNoErrors(t, tmpl, diags)
-		result, ok := e.variables["interpolated"].(string)
-		assert.True(t, ok)
-		assert.Equal(t, "hello world!", result)
-
-		result, ok = e.variables["escaped"].(string)
-		assert.True(t, ok)
-		assert.Equal(t, "hello ${world}!", result)
-	})
+	loader := newMockPackageMap().(MockPackageLoader)
+	loader.packages["aws"] = MockPackage{
+		resourceTypeHint: func(typeName string) *schema.ResourceType {
+			return inputProperties(typeName)
+		},
+	}
+	_, diags := TypeCheck(newRunner(tmpl, loader))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:18:11: Provider awsProvider will never be used by resource res; " +
+			"awsProvider is a provider for the aws package, which is not used by res or any of its children",
+	}, diagStrings)
+	assert.False(t, diags.HasErrors())
 }
 
-func TestJoinForbidsNonStringArgs(t *testing.T) {
+func TestResourceReplacedOnEveryDeployment(t *testing.T) {
 	t.Parallel()
 
-	text := `
-name: test-readfile
+	const text = `
+name: test-yaml
 runtime: yaml
-variables:
-  inputs:
-    - 1
-    - { "foo": "bar" }
-    - [1, 2, 3]
-    - true
-  foo-bar:
-    fn::join:
-      - "-"
-      - ${inputs}
-  foo-err:
-    fn::join:
-      - "-"
-      - ${inputs[1]}
+resources:
+  res:
+    type: test:resource:replaced
+    properties:
+      name: bucket-${pulumi.stack}
+      description: bucket for ${pulumi.stack}
+  stable:
+    type: test:resource:replaced
+    properties:
+      name: bucket-${pulumi.project}
+  fromEnv:
+    type: test:resource:replaced
+    properties:
+      name:
+        fn::getEnv: BUCKET_NAME
 `
-
 	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
-
+	loader := MockPackageLoader{
+		packages: map[string]Package{
+			"test": MockPackage{
+				resourceTypeHint: func(typeName string) *schema.ResourceType {
+					return inputProperties(typeName,
+						schema.Property{Name: "name", Type: schema.StringType, ReplaceOnChanges: true},
+						schema.Property{Name: "description", Type: &schema.OptionalType{ElementType: schema.StringType}})
+				},
+			},
+		},
+	}
+	_, diags := TypeCheck(newRunner(tmpl, loader))
 	var diagStrings []string
 	for _, v := range diags {
 		diagStrings = append(diagStrings, diagString(v))
 	}
-	assert.ElementsMatch(t, diagStrings,
-		[]string{
-			"<stdin>:12:9: the second argument to fn::join must be a list of strings, found a number at index 0",
-			"<stdin>:12:9: the second argument to fn::join must be a list of strings, found an object at index 1",
-			"<stdin>:12:9: the second argument to fn::join must be a list of strings, found a list at index 2",
-			"<stdin>:12:9: the second argument to fn::join must be a list of strings, found a boolean at index 3",
-			"<stdin>:16:9: the second argument to fn::join must be a list, found an object",
-		},
-	)
+	assert.Equal(t, []string{
+		"<stdin>:7:13: Resource res may be replaced on every deployment; " +
+			"Changing name replaces res, and it is bound to ${pulumi.stack}, which can change between deployments",
+		"<stdin>:17:9: fn::getEnv makes the program depend on the environment it runs in; " +
+			"A preview may not match the update that follows it, if the environment variable differs between them",
+		"<stdin>:17:9: Resource fromEnv may be replaced on every deployment; " +
+			"Changing name replaces fromEnv, and it is bound to the environment variable BUCKET_NAME, " +
+			"which can change between deployments",
+	}, diagStrings)
+	assert.False(t, diags.HasErrors())
 }
 
-func TestUnicodeLogicalName(t *testing.T) {
+func TestResourceHook(t *testing.T) {
 	t.Parallel()
 
 	const text = `
 name: test-yaml
 runtime: yaml
-variables:
-  "bB-Beta_beta.💜⁉":
-    test: oof
 resources:
-  "aA-Alpha_alpha.\U0001F92F⁉️":
+  res:
     type: test:resource:type
     properties:
-      foo: "${[\"bB-Beta_beta.💜⁉\"].test}"
+      foo: oof
 `
-
 	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	diags := testInvokeDiags(t, tmpl, func(r *Runner) {})
-	requireNoErrors(t, tmpl, diags)
+	var registered []pulumi.MockResourceArgs
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			registered = append(registered, args)
+			return "someID", resource.PropertyMap{}, nil
+		},
+	}
+	hook := func(args *ResourceHookArgs) error {
+		assert.Equal(t, ResourceTypeToken(testResourceToken), args.Type)
+		args.Name = "org-" + args.Name
+		args.Properties["bar"] = "tagged"
+		args.Options = append(args.Options, pulumi.Protect(true))
+		args.Warn("renamed %v", args.Name)
+		return nil
+	}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		return RunTemplate(ctx, tmpl, nil, nil, newMockPackageMap(), WithResourceHook(hook))
+	}, pulumi.WithMocks("foo", "dev", mocks))
+	require.NoError(t, err)
+	require.Len(t, registered, 1)
+	assert.Equal(t, "org-res", registered[0].Name)
+	assert.Equal(t, resource.NewPropertyMapFromMap(map[string]interface{}{
+		"foo": "oof",
+		"bar": "tagged",
+	}), registered[0].Inputs)
+	assert.True(t, registered[0].RegisterRPC.Protect)
 }
 
-func TestPoisonResult(t *testing.T) {
+func TestResourceHookRejectsResource(t *testing.T) {
 	t.Parallel()
 
-	text := `
-name: test-poison
+	const text = `
+name: test-yaml
 runtime: yaml
-variables:
-  poisoned:
-    fn::invoke:
-      function: test:invoke:poison
-      arguments:
-        foo: three
-      return: value
-  never-run:
-    fn::invoke:
-      function: test:invoke:poison
-      arguments:
-        foo: ${poisoned}
-      return: value
 resources:
-  alsoPoisoned:
-    type: test:resource:not-run
+  res:
+    type: test:resource:type
     properties:
-      foo: ${poisoned}`
+      foo: oof
+`
 	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	diags := testInvokeDiags(t, tmpl, func(r *Runner) {})
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			assert.Fail(t, "The rejected resource was registered")
+			return "", resource.PropertyMap{}, nil
+		},
+	}
+	hook := func(args *ResourceHookArgs) error {
+		if _, ok := args.Properties["bar"]; !ok {
+			return fmt.Errorf("missing required tag bar")
+		}
+		return nil
+	}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		return RunTemplate(ctx, tmpl, nil, nil, newMockPackageMap(), WithResourceHook(hook))
+	}, pulumi.WithMocks("foo", "dev", mocks))
+	diags, ok := HasDiagnostics(err)
+	require.True(t, ok, "expected diagnostics, got %v", err)
 	var diagStrings []string
 	for _, v := range diags {
 		diagStrings = append(diagStrings, diagString(v))
 	}
-
-	assert.ElementsMatch(t, diagStrings,
-		[]string{
-			"<stdin>:5:5: Don't eat the poison",
-		})
+	assert.Equal(t, []string{
+		"<stdin>:4:3: resource hook rejected resource res: missing required tag bar",
+	}, diagStrings)
 }
 
-func TestEmptyInterpolate(t *testing.T) {
+func TestResourceHookPropertiesAreTypeChecked(t *testing.T) {
 	t.Parallel()
 
-	text := `
-name: test-empty
+	const text = `
+name: test-yaml
 runtime: yaml
-variables:
-  empty: ${}
+resources:
+  res:
+    type: test:resource:type
+    properties:
+      foo: oof
 `
-	_, diags, err := LoadYAMLBytes("<stdin>", []byte(strings.TrimSpace(text)))
-	require.NoError(t, err)
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			assert.Fail(t, "A resource with invalid properties was registered")
+			return "", resource.PropertyMap{}, nil
+		},
+	}
+	hook := func(args *ResourceHookArgs) error {
+		args.Properties["bar"] = 42.0
+		args.Properties["baz"] = "unknown"
+		return nil
+	}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		return RunTemplate(ctx, tmpl, nil, nil, newMockPackageMap(), WithResourceHook(hook))
+	}, pulumi.WithMocks("foo", "dev", mocks))
+	diags, ok := HasDiagnostics(err)
+	require.True(t, ok, "expected diagnostics, got %v", err)
 	var diagStrings []string
 	for _, v := range diags {
 		diagStrings = append(diagStrings, diagString(v))
 	}
-
-	assert.ElementsMatch(t, diagStrings,
-		[]string{
-			"<stdin>:4:10: Property access expressions cannot be empty",
-		})
+	assert.Equal(t, []string{
+		"<stdin>:4:3: resource hook set an invalid value for resource res: bar must be string, not a number",
+		"<stdin>:4:3: resource hook set property baz of resource res, which is not a property of its type",
+	}, diagStrings)
 }
 
-func TestReadResource(t *testing.T) {
+func TestResourceMissingRequiredProperties(t *testing.T) {
 	t.Parallel()
-	text := `
-name: consumer
-runtime: yaml
-resources:
-  bucket:
-    type: test:read:Resource
-    get:
-      id: ${id}
-      state:
-        foo: bar
-variables:
-  id: bucket-123456
-  isRight: ${bucket.tags["isRight"]}
-`
-	templ := yamlTemplate(t, text)
-	var wasRun bool
-	diags := testInvokeDiags(t, templ, func(r *Runner) {
-		r.variables["isRight"].(pulumi.AnyOutput).ApplyT(func(s interface{}) interface{} {
-			wasRun = true
-			assert.Equal(t, "yes", s)
-			return s
-		})
-	})
-	assert.True(t, wasRun)
-	assert.Len(t, diags, 0)
-}
 
-func TestReadResourceNoState(t *testing.T) {
-	t.Parallel()
-	text := `
-name: consumer
+	const text = `
+name: test-yaml
 runtime: yaml
 resources:
-  bucket:
-    type: test:read:Resource
-    get:
-      id: no-state
-variables:
-  id: bucket-123456
-  isRight: ${bucket.tags["isRight"]}
+  absent:
+    type: test:resource:type
+  partial:
+    type: test:resource:type
+    properties:
+      bar: x
+  complete:
+    type: test:resource:type
+    properties:
+      foo: x
 `
-	templ := yamlTemplate(t, text)
-	var wasRun bool
-	diags := testInvokeDiags(t, templ, func(r *Runner) {
-		r.variables["isRight"].(pulumi.AnyOutput).ApplyT(func(s interface{}) interface{} {
-			wasRun = true
-			assert.Equal(t, "yes", s)
-			return s
-		})
-	})
-	assert.True(t, wasRun)
-	assert.Len(t, diags, 0)
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:4:3: Resource absent was declared without any properties; test:resource:type requires foo",
+		"<stdin>:4:3: Resource absent is missing required property foo; foo is a required input of test:resource:type",
+		"<stdin>:6:3: Resource partial is missing required property foo; foo is a required input of test:resource:type",
+	}, diagStrings)
 }
 
-func TestReadResourceEventualId(t *testing.T) {
+func TestResourceOptionTypes(t *testing.T) {
 	t.Parallel()
-	text := `
-name: consumer
+
+	const text = `
+name: test-yaml
 runtime: yaml
 resources:
-  bucket:
-    type: test:read:Resource
-    get:
-      id: no-state
-  v2:
-    type: test:read:Resource
-    get:
-      id: eventual-${bucket.tags["isRight"]}
-variables:
-  isRight: ${v2.tags["isRight"]}
+  first:
+    type: test:resource:type
+    properties:
+      foo: x
+  second:
+    type: test:resource:type
+    properties:
+      foo: x
+    options:
+      protect: "yes"
+      dependsOn:
+        - ${first}
+        - first
+  third:
+    type: test:resource:type
+    properties:
+      foo: x
+    options:
+      dependsOn: 3
+  fourth:
+    type: test:resource:type
+    properties:
+      foo: x
+    options:
+      retainOnDelete: true
+      dependsOn: ${first}
+  fifth:
+    type: test:resource:type
+    properties:
+      foo: x
+    options:
+      pluginDownloadURL: example.com/plugins
 `
-	templ := yamlTemplate(t, text)
-	var wasRun bool
-	diags := testInvokeDiags(t, templ, func(r *Runner) {
-		r.variables["isRight"].(pulumi.AnyOutput).ApplyT(func(s interface{}) interface{} {
-			wasRun = true
-			assert.Equal(t, "definitely", s)
-			return s
-		})
-	})
-	assert.True(t, wasRun)
-	assert.Len(t, diags, 0)
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheckAll(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		"<stdin>:13:16: boolean is not assignable from string; Cannot assign type 'string' to type 'boolean'",
+		"<stdin>:16:11: dependsOn must be a resource or a list of resources, not string",
+		"<stdin>:22:18: dependsOn must be a resource or a list of resources, not number",
+		`<stdin>:35:26: pluginDownloadURL must be a URL with a scheme, such as https://, not "example.com/plugins"`,
+	}, diagStrings)
 }
 
-func TestReadResourceIDRuntimeTypeErorr(t *testing.T) {
+func TestDependsOnResolvesResources(t *testing.T) {
 	t.Parallel()
-	text := `
-name: consumer
+
+	const text = `
+name: test-yaml
 runtime: yaml
-resources:
-  bucket:
-    type: test:read:Resource
-    get:
-      id: no-state
-  v2:
-    type: test:read:Resource
-    get:
-      id: { a: b }
 variables:
-  isRight: ${v2.tags["isRight"]}
+  alias: ${first}
+  name: first
+resources:
+  first:
+    type: test:resource:type
+    properties:
+      foo: x
+  second:
+    type: test:resource:type
+    properties:
+      foo: x
+    options:
+      dependsOn:
+        - ${alias}
+        - ${name}
+  third:
+    type: test:resource:type
+    properties:
+      foo: x
+    options:
+      dependsOn: ${name}
 `
-	templ := yamlTemplate(t, text)
-	diags := testInvokeDiags(t, templ, nil)
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags := TypeCheckAll(newRunner(tmpl, newMockPackageMap()))
 	var diagStrings []string
 	for _, v := range diags {
 		diagStrings = append(diagStrings, diagString(v))
 	}
-
-	assert.ElementsMatch(t, diagStrings, []string{
-		"<stdin>:12:11: { a: b } must be a string, instead got type map[string]interface {}; This indicates a bug in the Pulumi YAML type checker. Please open an issue at https://github.com/pulumi/pulumi-yaml/issues/new/choose",
-	})
+	assert.Equal(t, []string{
+		`<stdin>:18:11: resource "name" could not be found; dependsOn refers to the variable "name", which is not a resource`,
+		`<stdin>:24:18: resource "name" could not be found; dependsOn refers to the variable "name", which is not a resource`,
+	}, diagStrings)
 }
 
-func TestReadResourceErrorTyping(t *testing.T) {
+func TestEvaluateCancelled(t *testing.T) {
 	t.Parallel()
-	text := `
-name: consumer
+
+	const text = `
+name: test-yaml
 runtime: yaml
 resources:
-  bucket:
-    type: test:read:Resource
-    properties:
-      foo: bar
-    get:
-      state:
-        fizz: buzz
+  res:
+    type: test:resource:not-run
 `
-	templ := yamlTemplate(t, text)
-	diags := testTemplateDiags(t, templ, nil)
-	assert.Len(t, diags, 2)
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			assert.Fail(t, "No resources should be registered once evaluation is cancelled")
+			return "", nil, nil
+		},
+	}
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ctx, err := pulumi.NewContext(cancelCtx, pulumi.RunInfo{Project: "foo", Stack: "dev", Mocks: mocks})
+	require.NoError(t, err)
+
+	diags := newRunner(tmpl, newMockPackageMap()).Evaluate(ctx)
 	var diagStrings []string
 	for _, v := range diags {
 		diagStrings = append(diagStrings, diagString(v))
 	}
-	assert.ElementsMatch(t, diagStrings, []string{
-		"<stdin>:5:3: Resource fields properties and get are mutually exclusive; Properties is used to describe a resource managed by Pulumi.\nGet is used to describe a resource managed outside of the current Pulumi stack.\nSee https://www.pulumi.com/docs/intro/concepts/resources/get for more details on using Get.",
-		"<stdin>:11:9: Property fizz does not exist on 'test:read:Resource'; Cannot assign '{fizz: string}' to 'test:read:Resource':\n  Existing properties are: foo",
-	})
+	assert.Equal(t, []string{"evaluation was cancelled: context canceled"}, diagStrings)
 }
 
-func TestResourceWithSecret(t *testing.T) {
+func TestGetComponentResource(t *testing.T) {
 	t.Parallel()
 
-	text := `
-name: test-secret
+	const text = `
+name: test-yaml
 runtime: yaml
 resources:
-  sec:
-    type: test:resource:with-secret
-    properties:
-      foo: baz
-      bar: frotz
+  res:
+    type: test:component:type
+    get:
+      id: some-id
 `
 	tmpl := yamlTemplate(t, strings.TrimSpace(text))
-	mocks := &testMonitor{
-		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
-			assert.Equal(t, "bar", args.RegisterRPC.GetAdditionalSecretOutputs()[0])
-			return args.Name, args.Inputs, nil
-		},
+	_, diags := TypeCheck(newRunner(tmpl, newMockPackageMap()))
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
 	}
-	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
-		runner := newRunner(tmpl, newMockPackageMap())
-		err := runner.Evaluate(ctx)
-		assert.Len(t, err, 0)
-		assert.Equal(t, err.Error(), "no diagnostics")
-		return nil
-	}, pulumi.WithMocks("project", "stack", mocks))
-	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"<stdin>:7:7: Resource test:component:type is a component and cannot be read with get; " +
+			"Components are not managed by a provider, so there is no existing state to read.",
+	}, diagStrings)
 }
 
 func TestResourceWithAlias(t *testing.T) {
@@ -2076,6 +5793,90 @@ resources:
 	assert.NoError(t, err)
 }
 
+func TestResourceNamePrefix(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-name-prefix
+runtime: yaml
+namePrefix: ${pulumi.stack}-
+resources:
+  sourceName:
+    type: test:resource:UsingLogicalName
+    name: actual-registered-name
+
+  sourceNameOnly:
+    type: test:resource:WithoutLogicalName
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	mocks := &testMonitor{
+		NewResourceF: func(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+			switch args.TypeToken {
+			case "test:resource:UsingLogicalName":
+				assert.Equal(t, "actual-registered-name", args.Name)
+			case "test:resource:WithoutLogicalName":
+				assert.Equal(t, "stack-sourceNameOnly", args.Name)
+			default:
+				t.Fatalf("unexpected type token: %s", args.TypeToken)
+			}
+			return args.Name, args.Inputs, nil
+		},
+	}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		runner := newRunner(tmpl, newMockPackageMap())
+		diags := runner.Evaluate(ctx)
+		requireNoErrors(t, tmpl, diags)
+		return nil
+	}, pulumi.WithMocks("project", "stack", mocks))
+	assert.NoError(t, err)
+}
+
+func TestResourceNamePrefixReferences(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-name-prefix
+runtime: yaml
+namePrefix: ${env}-${pulumi.stack}-
+variables:
+  env: prod
+resources:
+  res:
+    type: test:resource:not-run
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:3:13: namePrefix cannot reference "env"; only the "pulumi" variable is available when it is evaluated`,
+	}, diagStrings)
+}
+
+func TestResourceNamePrefixURNSeparator(t *testing.T) {
+	t.Parallel()
+
+	text := `
+name: test-name-prefix
+runtime: yaml
+namePrefix: "${pulumi.stack}::"
+resources:
+  res:
+    type: test:resource:not-run
+`
+	tmpl := yamlTemplate(t, strings.TrimSpace(text))
+	diags := testTemplateSyntaxDiags(t, tmpl, func(r *Runner) {})
+	var diagStrings []string
+	for _, v := range diags {
+		diagStrings = append(diagStrings, diagString(v))
+	}
+	assert.Equal(t, []string{
+		`<stdin>:3:13: namePrefix must not contain "::", but evaluated to "dev::"`,
+	}, diagStrings)
+}
+
 func TestGetConfNodesFromMap(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -2358,6 +6159,41 @@ func TestHandleUnknownNestedPropertiesDuringPreview(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestResourceInvalidLogicalName(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+resources:
+  my::resource:
+    type: test:resource:type
+  my-resource:
+    type: test:resource:type
+    name: "a::b"
+  ok-resource:
+    type: test:resource:type
+    name: "a:b"
+  empty:
+    type: test:resource:type
+    name: ""
+`
+	template := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags, err := PrepareTemplate(template, nil, newMockPackageMap())
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		if v.Severity == hcl.DiagError {
+			diagStrings = append(diagStrings, diagString(v))
+		}
+	}
+	assert.Equal(t, []string{
+		`<stdin>:4:3: resource name "my::resource" must not contain "::"; "::" separates the components of a resource URN`,
+		`<stdin>:8:11: resource name "a::b" must not contain "::"; "::" separates the components of a resource URN`,
+		`<stdin>:14:11: resource "empty" must have a non-empty name`,
+	}, diagStrings)
+}
+
 // This test checks that unknown outputs are marked in preview and not during update.
 // Regression test for https://github.com/pulumi/pulumi-yaml/issues/492.
 func TestUnknownsDuringPreviewNotUpdate(t *testing.T) {
@@ -2412,3 +6248,99 @@ func TestUnknownsDuringPreviewNotUpdate(t *testing.T) {
 	assert.NoError(t, runProgram(true))
 	assert.Error(t, runProgram(false))
 }
+
+func TestUnknownBooleanResourceOptionsDuringPreviewNotUpdate(t *testing.T) {
+	t.Parallel()
+
+	runProgram := func(isPreview bool) (pulumi.ResourceOption, bool, syntax.Diagnostics) {
+		var opt pulumi.ResourceOption
+		var ok bool
+		var diags syntax.Diagnostics
+		err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+			e := &programEvaluator{
+				pulumiCtx: ctx,
+				evalContext: &evalContext{
+					Runner: &Runner{
+						t: &ast.TemplateDecl{},
+						variables: map[string]interface{}{
+							"shouldRetain": unknownOutput(),
+						},
+					},
+				},
+			}
+
+			node, parseDiags := ast.ParseExpr(syntax.String("${shouldRetain}"))
+			require.False(t, parseDiags.HasErrors())
+
+			opt, ok = e.evaluateBooleanResourceOption(node, "retainOnDelete", pulumi.RetainOnDelete)
+			diags = e.sdiags.diags
+			return nil
+		}, pulumi.WithMocks(testProject, "unknowns", &testMonitor{}), func(ri *pulumi.RunInfo) {
+			ri.DryRun = isPreview
+		})
+		require.NoError(t, err)
+		return opt, ok, diags
+	}
+
+	opt, ok, diags := runProgram(true)
+	assert.True(t, ok)
+	assert.Nil(t, opt, "an unknown option is left unset during a preview")
+	assert.Empty(t, diags)
+
+	opt, ok, diags = runProgram(false)
+	assert.False(t, ok)
+	assert.Nil(t, opt)
+	require.Len(t, diags, 1)
+	assert.Equal(t, "retainOnDelete must not be an output", diags[0].Summary)
+}
+
+func TestUnusedWarnings(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+configuration:
+  region:
+    type: String
+    default: us-west-2
+  stale:
+    type: String
+    default: x
+  _reserved:
+    type: String
+    default: y
+  aliased:
+    type: String
+    name: stackAlias
+    default: z
+  aws:region:
+    type: String
+    default: us-east-1
+variables:
+  prefix: ${region}-app
+  leftover: 42
+  exported: ${test-yaml:region}
+resources:
+  res:
+    type: test:resource:type
+    properties:
+      foo: ${prefix}
+outputs:
+  out: ${exported}
+`
+	template := yamlTemplate(t, strings.TrimSpace(text))
+	_, diags, err := PrepareTemplate(template, nil, newMockPackageMap())
+	require.NoError(t, err)
+	var diagStrings []string
+	for _, v := range diags {
+		if v.Severity == hcl.DiagWarning {
+			diagStrings = append(diagStrings, diagString(v))
+		}
+	}
+	assert.Equal(t, []string{
+		"<stdin>:7:3: Config value stale is never used; remove it, or rename it to _stale and set 'name: stale' to keep reading it without this warning",
+		"<stdin>:13:3: Config value aliased is never used; remove it, or rename it to _aliased to keep it without this warning",
+		"<stdin>:22:3: Variable leftover is never used; remove it, or rename it to _leftover to keep it without this warning",
+	}, diagStrings)
+}
@@ -363,3 +363,75 @@ func testVariableDiags(t *testing.T, template *ast.TemplateDecl, callback func(*
 	assert.Equal(t, 1, testInvokeCalls)
 	return nil
 }
+
+// Test that streaming evaluation releases variables once nothing else references them, and keeps those that
+// outputs still need.
+func TestVariableStreaming(t *testing.T) {
+	t.Parallel()
+
+	const text = `
+name: test-yaml
+runtime: yaml
+variables:
+  greeting: hello
+  message: ${greeting} world
+  unused: baz
+  shout:
+    fn::toBase64: ${message}
+outputs:
+  greeting: ${greeting}
+  shout: ${shout}
+`
+	template := yamlTemplate(t, strings.TrimSpace(text))
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		runner := newRunner(template, newMockPackageMap())
+		runner.streamValues = true
+		diags := runner.Evaluate(ctx)
+		requireNoErrors(t, template, diags)
+
+		assert.Contains(t, runner.variables, "greeting")
+		assert.Contains(t, runner.variables, "shout")
+		assert.NotContains(t, runner.variables, "message")
+		assert.NotContains(t, runner.variables, "unused")
+		return nil
+	}, pulumi.WithMocks("projectFoo", "stackDev", &testMonitor{}))
+	assert.NoError(t, err)
+}
+
+func BenchmarkEvaluateLargeTemplate(b *testing.B) {
+	var text strings.Builder
+	text.WriteString("name: bench\nruntime: yaml\nvariables:\n")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&text, "  blob%d:\n    fn::toJSON:\n      count: %d\n      items: [a, b, c, d, e, f, g, h]\n", i, i)
+		fmt.Fprintf(&text, "  value%d:\n    fn::toBase64: ${blob%d}\n", i, i)
+	}
+	text.WriteString("outputs:\n  last: ${value499}\n")
+	template, diags, err := LoadYAMLBytes("<stdin>", []byte(text.String()))
+	if err != nil || diags.HasErrors() {
+		b.Fatalf("failed to load template: %v %v", err, diags)
+	}
+
+	for _, streaming := range []bool{false, true} {
+		streaming := streaming
+		b.Run(fmt.Sprintf("streaming=%v", streaming), func(b *testing.B) {
+			b.ReportAllocs()
+			retained := 0
+			for i := 0; i < b.N; i++ {
+				err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+					runner := newRunner(template, newMockPackageMap())
+					runner.streamValues = streaming
+					if diags := runner.Evaluate(ctx); diags.HasErrors() {
+						return diags
+					}
+					retained = len(runner.variables)
+					return nil
+				}, pulumi.WithMocks("projectFoo", "stackDev", &testMonitor{}))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(retained), "retained-vars")
+		})
+	}
+}
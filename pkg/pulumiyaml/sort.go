@@ -155,7 +155,9 @@ func topologicallySortedResources(t *ast.TemplateDecl, externalConfig []configNo
 		return nil, diags
 	}
 
-	// Depth-first visit each node
+	// Depth-first visit each node. path holds the names of the nodes being visited, so a cycle can be reported in
+	// full.
+	var path []string
 	var visit func(name *ast.StringExpr) bool
 	visit = func(name *ast.StringExpr) bool {
 		// Special case: pulumi variable has no dependencies.
@@ -176,15 +178,22 @@ func topologicallySortedResources(t *ast.TemplateDecl, externalConfig []configNo
 		kind := e.valueKind()
 
 		if visiting[name.Value] {
+			start := len(path) - 1
+			for path[start] != name.Value {
+				start--
+			}
+			cycle := append(append([]string{}, path[start:]...), name.Value)
 			diags.Extend(ast.ExprError(
 				name,
-				fmt.Sprintf("circular dependency of %s '%s' transitively on itself", kind, name.Value),
+				fmt.Sprintf("circular dependency of %s '%s' transitively on itself: %s",
+					kind, name.Value, strings.Join(cycle, " -> ")),
 				"",
 			))
 			return false
 		}
 		if !visited[name.Value] {
 			visiting[name.Value] = true
+			path = append(path, name.Value)
 			for _, mname := range dependencies[name.Value] {
 				if mname.Value == PulumiVarName {
 					continue
@@ -193,6 +202,7 @@ func topologicallySortedResources(t *ast.TemplateDecl, externalConfig []configNo
 					return false
 				}
 			}
+			path = path[:len(path)-1]
 			visited[name.Value] = true
 			visiting[name.Value] = false
 
@@ -143,6 +143,33 @@ func TestSortErrorCycle(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSortErrorCyclePath(t *testing.T) {
+	t.Parallel()
+
+	const text = `name: test-yaml
+runtime: yaml
+variables:
+  bucketName: ${res-a.name}
+resources:
+  res-a:
+    type: test:resource:type
+    options:
+      dependsOn:
+        - ${res-b}
+  res-b:
+    type: test:resource:type
+    properties:
+      foo: ${bucketName}
+`
+	tmpl := yamlTemplate(t, text)
+	_, diags := topologicallySortedResources(tmpl, nil)
+	require.Len(t, diags, 1)
+	assert.Equal(t,
+		"<stdin>:4:15: circular dependency of resource 'res-a' transitively on itself: "+
+			"res-a -> res-b -> bucketName -> res-a",
+		diagString(diags[0]))
+}
+
 func sortedNames(rs []graphNode) []string {
 	names := make([]string, len(rs))
 	for i, kvp := range rs {
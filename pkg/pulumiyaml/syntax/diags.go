@@ -10,12 +10,29 @@ import (
 	"github.com/hashicorp/hcl/v2"
 )
 
+// A DiagnosticSource identifies the stage of processing a template that produced a diagnostic.
+type DiagnosticSource string
+
+const (
+	// SourceUnknown is the source of diagnostics that have not been attributed to a stage.
+	SourceUnknown DiagnosticSource = ""
+	// SourceParse is the source of diagnostics produced while decoding and parsing a template.
+	SourceParse DiagnosticSource = "parse"
+	// SourceTypeCheck is the source of diagnostics produced by the type checker.
+	SourceTypeCheck DiagnosticSource = "typecheck"
+	// SourceRuntime is the source of diagnostics produced while evaluating a template.
+	SourceRuntime DiagnosticSource = "runtime"
+)
+
 // A Diagnostic represents a warning or an error to be presented to the user.
 type Diagnostic struct {
 	hcl.Diagnostic
 
 	// Whether the diagnostic has been shown to the user
 	Shown bool
+
+	// The stage that produced the diagnostic, if known.
+	Source DiagnosticSource
 }
 
 // WithContext adds context without mutating the receiver.
@@ -57,6 +74,16 @@ func NodeError(node Node, summary, detail string) *Diagnostic {
 	return Error(rng, summary, detail)
 }
 
+// NodeWarning creates a new warning-level diagnostic from the given node, summary, and detail. If the node is
+// non-nil, the diagnostic will be associated with the range of its associated syntax, if any.
+func NodeWarning(node Node, summary, detail string) *Diagnostic {
+	var rng *hcl.Range
+	if node != nil {
+		rng = node.Syntax().Range()
+	}
+	return Warning(rng, summary, detail)
+}
+
 // Diagnostics is a list of diagnostics.
 type Diagnostics []*Diagnostic
 
@@ -105,6 +132,31 @@ func (d *Diagnostics) Extend(diags ...*Diagnostic) {
 	}
 }
 
+// Attribute sets the source of each diagnostic in the list that has not already been attributed to a stage, and
+// returns the list.
+func (d Diagnostics) Attribute(source DiagnosticSource) Diagnostics {
+	for _, diag := range d {
+		if diag.Source == SourceUnknown {
+			diag.Source = source
+		}
+	}
+	return d
+}
+
+// FromSource returns the diagnostics in the list that were produced by any of the given sources.
+func (d Diagnostics) FromSource(sources ...DiagnosticSource) Diagnostics {
+	var diags Diagnostics
+	for _, diag := range d {
+		for _, source := range sources {
+			if diag.Source == source {
+				diags = append(diags, diag)
+				break
+			}
+		}
+	}
+	return diags
+}
+
 func (d *Diagnostics) HCL() hcl.Diagnostics {
 	if d == nil {
 		return nil
@@ -3,7 +3,9 @@
 package encoding
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
@@ -291,10 +293,43 @@ func DecodeYAML(filename string, d *yaml.Decoder, tags TagDecoder) (*syntax.Obje
 	if err := d.Decode(&v); err != nil {
 		return nil, syntax.Diagnostics{syntax.Error(nil, err.Error(), "")}
 	}
+	return v.object()
+}
+
+// DecodeYAMLDocuments decodes each `---` separated document read from the given decoder into a syntax node. See
+// UnmarshalYAML for mode details on the decoding process.
+func DecodeYAMLDocuments(filename string, d *yaml.Decoder, tags TagDecoder) ([]*syntax.ObjectNode, syntax.Diagnostics) {
+	var docs []*syntax.ObjectNode
+	var diags syntax.Diagnostics
+	for {
+		v := yamlValue{filename: filename, tags: tags}
+		if err := d.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) && len(docs) > 0 {
+				return docs, diags
+			}
+			diags.Extend(syntax.Error(nil, err.Error(), ""))
+			return nil, diags
+		}
+		// A trailing `---`, or a document holding only comments, decodes to an empty document, which adds nothing to
+		// the template.
+		if _, isNull := v.node.(*syntax.NullNode); v.node == nil || isNull {
+			diags.Extend(v.diags...)
+			continue
+		}
+		obj, odiags := v.object()
+		diags.Extend(odiags...)
+		if odiags.HasErrors() {
+			return nil, diags
+		}
+		docs = append(docs, obj)
+	}
+}
+
+func (v *yamlValue) object() (*syntax.ObjectNode, syntax.Diagnostics) {
 	obj, ok := v.node.(*syntax.ObjectNode)
 	if !ok {
 		return nil, syntax.Diagnostics{syntax.Error(nil,
-			fmt.Sprintf("Top level of '%s' must be an object", filename), "")}
+			fmt.Sprintf("Top level of '%s' must be an object", v.filename), "")}
 	}
 	return obj, v.diags
 }